@@ -2,28 +2,46 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"denden-core/internal/client"
+	"denden-core/internal/identity"
 )
 
+// input reads commands and passphrase prompts from stdin
+var input = bufio.NewScanner(os.Stdin)
+
 func main() {
 	fmt.Println("🔥🔥🔥 Den Den CLI - Phase 1.5 🔥🔥🔥")
 	fmt.Println("================================================")
 	fmt.Println("Decentralized Encrypted Messenger")
-	fmt.Println("================================================\n")
+	fmt.Println("================================================")
+	fmt.Println()
+
+	// DENDEN_REQUIRE_PASSPHRASE=1 refuses to create a plaintext identity file
+	if os.Getenv("DENDEN_REQUIRE_PASSPHRASE") == "1" {
+		identity.SetPassphrasePolicy(identity.PassphraseRequired)
+	}
 
 	// Initialize client
 	fmt.Println("🔧 Initializing client...")
-	c, err := client.NewClient("")
+	c, err := newClient()
 	if err != nil {
 		log.Fatalf("❌ Failed to initialize client: %v", err)
 	}
 	defer c.Close()
 
+	// Open local event store (~/.denden/denden.db)
+	if err := c.OpenStore(""); err != nil {
+		fmt.Printf("⚠️  Event store unavailable: %v\n", err)
+	}
+
 	// Display identity
 	identity := c.GetIdentity()
 	fmt.Printf("\n🆔 Your Identity:\n")
@@ -48,11 +66,10 @@ func main() {
 	printHelp()
 
 	// Main command loop
-	scanner := bufio.NewScanner(os.Stdin)
 	fmt.Print("\n> ")
 
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+	for input.Scan() {
+		line := strings.TrimSpace(input.Text())
 
 		if line == "" {
 			fmt.Print("> ")
@@ -67,13 +84,68 @@ func main() {
 		fmt.Print("> ")
 	}
 
-	if err := scanner.Err(); err != nil {
+	if err := input.Err(); err != nil {
 		log.Printf("❌ Error reading input: %v", err)
 	}
 
 	fmt.Println("\n👋 Goodbye!")
 }
 
+// newClient creates the client, asking for the passphrase if the identity is encrypted
+// (or if the passphrase policy requires a new identity to be encrypted)
+func newClient() (*client.Client, error) {
+	c, err := client.NewClient("")
+	if !errors.Is(err, identity.ErrPassphraseRequired) {
+		return c, err
+	}
+
+	path, err := identity.GetDefaultIdentityPath()
+	if err != nil {
+		return nil, err
+	}
+
+	if !identity.IsEncryptedFile(identity.ResolveIdentityPath(path)) {
+		fmt.Println("🔐 Choose a passphrase to encrypt your new identity")
+		passphrase, err := readNewPassphrase()
+		if err != nil {
+			return nil, err
+		}
+		return client.NewClientWithPassphrase("", passphrase)
+	}
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		passphrase := prompt("🔐 Passphrase: ")
+		c, err = client.NewClientWithPassphrase("", passphrase)
+		if !errors.Is(err, identity.ErrWrongPassphrase) {
+			return c, err
+		}
+		fmt.Println("❌ Wrong passphrase")
+	}
+	return nil, identity.ErrWrongPassphrase
+}
+
+// prompt prints a label and reads one line from stdin
+// Note: input is echoed, the CLI has no raw terminal mode
+func prompt(label string) string {
+	fmt.Print(label)
+	if !input.Scan() {
+		return ""
+	}
+	return strings.TrimSpace(input.Text())
+}
+
+// readNewPassphrase asks for a new passphrase twice
+func readNewPassphrase() (string, error) {
+	passphrase := prompt("   New passphrase: ")
+	if passphrase == "" {
+		return "", fmt.Errorf("passphrase can't be empty")
+	}
+	if prompt("   Repeat passphrase: ") != passphrase {
+		return "", fmt.Errorf("passphrases don't match")
+	}
+	return passphrase, nil
+}
+
 // handleCommand processes a user command
 // Returns false if the program should exit
 func handleCommand(c *client.Client, line string) bool {
@@ -96,7 +168,7 @@ func handleCommand(c *client.Client, line string) bool {
 		message := strings.Join(parts[2:], " ")
 
 		fmt.Printf("📤 Sending message to %s...\n", recipientPubKey[:12]+"...")
-		err := c.SendEncryptedMessage(recipientPubKey, message)
+		_, err := c.SendPrivateMessage(c.GetContext(), recipientPubKey, message)
 		if err != nil {
 			fmt.Printf("❌ Failed to send message: %v\n", err)
 		} else {
@@ -107,9 +179,181 @@ func handleCommand(c *client.Client, line string) bool {
 		identity := c.GetIdentity()
 		fmt.Println("\n🆔 Your Identity:")
 		fmt.Printf("   npub: %s\n", identity.Npub)
-		fmt.Printf("   nsec: %s (keep this secret!)\n", identity.Nsec)
 		fmt.Printf("   Public key (hex): %s\n", identity.PublicKey)
-		fmt.Printf("   Private key (hex): %s... (keep this secret!)\n", identity.PrivateKey[:16])
+		fmt.Printf("   Encrypted: %t\n", identity.IsEncrypted())
+		if identity.HasMnemonic() {
+			fmt.Printf("   Account: %d\n", identity.Account)
+			fmt.Println("   Use /backup to write down your words")
+		} else {
+			fmt.Println("   Use /export to back up your secret key")
+		}
+
+	case "/export":
+		var passphrase string
+		if c.GetIdentity().IsEncrypted() {
+			passphrase = prompt("   Passphrase: ")
+		} else {
+			fmt.Println("🔐 Choose a passphrase to protect the exported key")
+			var err error
+			passphrase, err = readNewPassphrase()
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				return true
+			}
+		}
+
+		ncryptsec, err := c.ExportSecretKey(passphrase)
+		if err != nil {
+			fmt.Printf("❌ Failed to export key: %v\n", err)
+			return true
+		}
+		fmt.Printf("🔑 %s\n", ncryptsec)
+		fmt.Println("   Keep this safe, it can be imported into any NIP-49 client")
+
+	case "/backup":
+		var passphrase string
+		if c.GetIdentity().IsEncrypted() {
+			passphrase = prompt("   Passphrase: ")
+		}
+
+		mnemonic, err := c.BackupMnemonic(passphrase)
+		if errors.Is(err, identity.ErrNoMnemonic) {
+			fmt.Println("❌ This identity wasn't created from words, use /export instead")
+			return true
+		}
+		if err != nil {
+			fmt.Printf("❌ Failed to back up: %v\n", err)
+			return true
+		}
+
+		fmt.Println("📝 Write these words down, in order, and keep them offline:")
+		for i, word := range strings.Fields(mnemonic) {
+			fmt.Printf("   %2d. %s\n", i+1, word)
+		}
+		fmt.Printf("   Account: %d\n", c.GetIdentity().Account)
+		fmt.Println("   Anyone with these words controls your identity")
+
+	case "/restore":
+		var account uint64
+		if len(parts) > 1 {
+			var err error
+			account, err = strconv.ParseUint(parts[1], 10, 31)
+			if err != nil {
+				fmt.Println("❌ Usage: /restore [account]")
+				return true
+			}
+		}
+
+		fmt.Println("⚠️  This replaces your current identity, back it up first (/backup or /export)")
+		mnemonic := prompt("   Words (12 or 24, separated by spaces): ")
+		if !identity.ValidateMnemonic(mnemonic) {
+			fmt.Println("❌ Invalid words, check the spelling and order")
+			return true
+		}
+
+		// Keep the identity file encrypted if it was
+		var passphrase string
+		if c.GetIdentity().IsEncrypted() || identity.GetPassphrasePolicy() == identity.PassphraseRequired {
+			fmt.Println("🔐 Choose a passphrase to encrypt the restored identity")
+			var err error
+			passphrase, err = readNewPassphrase()
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				return true
+			}
+		}
+
+		if err := c.RestoreFromMnemonic(mnemonic, uint32(account), passphrase); err != nil {
+			fmt.Printf("❌ Failed to restore: %v\n", err)
+		} else {
+			fmt.Printf("✅ Restored %s\n", c.GetIdentity().Npub)
+		}
+
+	case "/lock":
+		if c.GetIdentity().IsEncrypted() {
+			fmt.Println("❌ Identity is already encrypted, use /passwd to change the passphrase")
+			return true
+		}
+
+		passphrase, err := readNewPassphrase()
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return true
+		}
+
+		if err := c.LockIdentity(passphrase); err != nil {
+			fmt.Printf("❌ %v\n", err)
+		} else {
+			fmt.Println("✅ Identity encrypted, you'll be asked for the passphrase on next start")
+		}
+
+	case "/passwd":
+		if !c.GetIdentity().IsEncrypted() {
+			fmt.Println("❌ Identity is not encrypted, use /lock first")
+			return true
+		}
+
+		oldPassphrase := prompt("   Current passphrase: ")
+		newPassphrase, err := readNewPassphrase()
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return true
+		}
+
+		if err := c.ChangePassphrase(oldPassphrase, newPassphrase); err != nil {
+			fmt.Printf("❌ %v\n", err)
+		} else {
+			fmt.Println("✅ Passphrase changed")
+		}
+
+	case "/accounts":
+		handleAccounts(c, parts[1:])
+
+	case "/pow":
+		handlePow(c, parts[1:])
+
+	case "/bunker":
+		if len(parts) < 2 {
+			fmt.Println("❌ Usage: /bunker <bunker://...> or /bunker off")
+			return true
+		}
+
+		if parts[1] == "off" {
+			if err := c.DisconnectBunker(); err != nil {
+				fmt.Printf("❌ %v\n", err)
+			} else {
+				fmt.Println("✅ Signing with local key again")
+			}
+			return true
+		}
+
+		fmt.Println("🔌 Connecting to remote signer (approve the request in your signer app)...")
+		if err := c.ConnectBunker(parts[1], printAuthURL); err != nil {
+			fmt.Printf("❌ %v\n", err)
+		} else {
+			fmt.Println("✅ Remote signer connected, restart to receive messages for this identity")
+		}
+
+	case "/nostrconnect":
+		relays := []string{"wss://relay.nsec.app"}
+		if len(parts) > 1 {
+			relays = parts[1:]
+		}
+
+		nc, err := c.StartNostrConnect(relays, "Den Den CLI")
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return true
+		}
+
+		fmt.Println("📋 Paste this URI into your signer app:")
+		fmt.Printf("   %s\n", nc.URI())
+		fmt.Println("⏳ Waiting for the signer...")
+		if err := c.CompleteNostrConnect(nc, printAuthURL); err != nil {
+			fmt.Printf("❌ %v\n", err)
+		} else {
+			fmt.Println("✅ Remote signer connected, restart to receive messages for this identity")
+		}
 
 	case "/help":
 		printHelp()
@@ -130,11 +374,197 @@ func handleCommand(c *client.Client, line string) bool {
 	return true
 }
 
+// handleAccounts lists, adds, imports, removes and switches accounts
+func handleAccounts(c *client.Client, args []string) {
+	if len(args) == 0 {
+		active, _ := c.ActiveAccount()
+		fmt.Println("\n👥 Accounts:")
+		for _, account := range c.ListAccounts() {
+			marker := " "
+			if account.PublicKey == active.PublicKey {
+				marker = "*"
+			}
+			fmt.Printf("   %s %s  %s\n", marker, account.Npub, account.Label)
+		}
+		return
+	}
+
+	switch args[0] {
+	case "add":
+		passphrase, err := readAccountPassphrase()
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+
+		account, err := c.AddAccount(strings.Join(args[1:], " "), passphrase)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		fmt.Printf("✅ Added %s, use /accounts switch to use it\n", account.Npub)
+
+	case "import":
+		if len(args) < 2 {
+			fmt.Println("❌ Usage: /accounts import <nsec> [label]")
+			return
+		}
+
+		passphrase, err := readAccountPassphrase()
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+
+		account, err := c.ImportAccount(strings.Join(args[2:], " "), args[1], passphrase)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return
+		}
+		fmt.Printf("✅ Imported %s, use /accounts switch to use it\n", account.Npub)
+
+	case "switch":
+		if len(args) < 2 {
+			fmt.Println("❌ Usage: /accounts switch <npub>")
+			return
+		}
+
+		account, ok := findAccount(c, args[1])
+		if !ok {
+			fmt.Printf("❌ No account matches %s\n", args[1])
+			return
+		}
+
+		var passphrase string
+		if c.IsAccountEncrypted(account.PublicKey) {
+			passphrase = prompt("   Passphrase: ")
+		}
+
+		if err := c.SwitchAccount(account.PublicKey, passphrase); err != nil {
+			fmt.Printf("❌ %v\n", err)
+		}
+
+	case "remove":
+		if len(args) < 2 {
+			fmt.Println("❌ Usage: /accounts remove <npub>")
+			return
+		}
+
+		account, ok := findAccount(c, args[1])
+		if !ok {
+			fmt.Printf("❌ No account matches %s\n", args[1])
+			return
+		}
+
+		fmt.Printf("⚠️  This deletes the key of %s from this device\n", account.Npub)
+		if prompt("   Type 'yes' to continue: ") != "yes" {
+			return
+		}
+
+		if err := c.RemoveAccount(account.PublicKey); err != nil {
+			fmt.Printf("❌ %v\n", err)
+		} else {
+			fmt.Println("✅ Account removed")
+		}
+
+	default:
+		fmt.Println("❌ Usage: /accounts [add [label] | import <nsec> [label] | switch <npub> | remove <npub>]")
+	}
+}
+
+// findAccount finds an account by npub, hex public key or label
+// (a prefix of the npub is enough)
+func findAccount(c *client.Client, query string) (identity.Account, bool) {
+	for _, account := range c.ListAccounts() {
+		if account.Label == query || account.PublicKey == query || strings.HasPrefix(account.Npub, query) {
+			return account, true
+		}
+	}
+	return identity.Account{}, false
+}
+
+// readAccountPassphrase asks for a passphrase for a new account
+// (empty for a plaintext identity, unless the passphrase policy requires one)
+func readAccountPassphrase() (string, error) {
+	if identity.GetPassphrasePolicy() != identity.PassphraseRequired {
+		if prompt("   Encrypt the new identity with a passphrase? (y/N): ") != "y" {
+			return "", nil
+		}
+	}
+	return readNewPassphrase()
+}
+
+// printAuthURL shows the approval page a remote signer asked us to open
+func printAuthURL(authURL string) {
+	fmt.Printf("\n🌐 Approve the request at: %s\n", authURL)
+}
+
+// powKinds are the kinds the CLI lists by default in /pow
+var powKinds = []int{0, 1, 3, 4, 5, 6, 7, 1059}
+
+// handlePow shows or overrides the publish PoW policy
+// Usage: /pow | /pow <kind> | /pow <kind> <bits> | /pow <kind> reset
+func handlePow(c *client.Client, args []string) {
+	policy := c.GetPublishPolicy()
+
+	if len(args) == 0 {
+		fmt.Println("\n⛏️  PoW for sent events (NIP-13):")
+		for _, kind := range powKinds {
+			difficulty, estimate, err := c.EstimatePublish(c.GetContext(), kind)
+			if err != nil {
+				fmt.Printf("   kind %-5d %d bits\n", kind, difficulty)
+				continue
+			}
+			fmt.Printf("   kind %-5d %d bits  ~%s\n", kind, difficulty, estimate.Round(time.Millisecond))
+		}
+		for url, required := range c.GetPowAdvisor().RelayRequirements() {
+			if required < 0 {
+				fmt.Printf("   %s: requirement unknown\n", url)
+			} else {
+				fmt.Printf("   %s: min %d bits\n", url, required)
+			}
+		}
+		return
+	}
+
+	kind, err := strconv.Atoi(args[0])
+	if err != nil || kind < 0 {
+		fmt.Println("❌ Usage: /pow [kind [bits|reset]]")
+		return
+	}
+
+	switch {
+	case len(args) == 1:
+		// Show only
+	case args[1] == "reset":
+		policy.Reset(kind)
+	default:
+		bits, err := strconv.Atoi(args[1])
+		if err != nil || bits < 0 || bits > 256 {
+			fmt.Println("❌ Difficulty must be between 0 and 256 bits")
+			return
+		}
+		policy.Set(kind, bits)
+	}
+
+	fmt.Printf("⛏️  kind %d: %d bits\n", kind, policy.Difficulty(kind))
+}
+
 // printHelp prints available commands
 func printHelp() {
 	fmt.Println("\n📖 Available Commands:")
-	fmt.Println("   /send <npub|pubkey> <message>  Send encrypted message")
+	fmt.Println("   /send <npub|pubkey> <message>  Send private message (NIP-17)")
 	fmt.Println("   /info                           Show your identity")
+	fmt.Println("   /backup                         Show your backup words (NIP-06)")
+	fmt.Println("   /restore [account]              Restore an identity from backup words")
+	fmt.Println("   /export                         Export your secret key (ncryptsec)")
+	fmt.Println("   /lock                           Encrypt your identity file with a passphrase")
+	fmt.Println("   /passwd                         Change your identity passphrase")
+	fmt.Println("   /accounts [add|import|switch|remove]")
+	fmt.Println("                                   List and manage the identities on this device")
+	fmt.Println("   /pow [kind [bits|reset]]        Show or set the PoW mined into sent events")
+	fmt.Println("   /bunker <bunker://...|off>      Sign with a remote signer (NIP-46)")
+	fmt.Println("   /nostrconnect [relay...]        Pair with a remote signer via nostrconnect://")
 	fmt.Println("   /help                           Show this help")
 	fmt.Println("   /quit or /exit                  Exit the program")
 }
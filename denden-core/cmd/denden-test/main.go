@@ -18,7 +18,8 @@ func main() {
 	fmt.Println("🔥🔥🔥 Den Den Core - Phase 1 (Firefly) 🔥🔥🔥")
 	fmt.Println("================================================")
 	fmt.Println("This is a Nostr standard decentralized messaging system")
-	fmt.Println("================================================\n")
+	fmt.Println("================================================")
+	fmt.Println()
 
 	// ==========================================
 	// Step 1: Generate Identity
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"fmt"
+	"io"
+
+	"denden-core/internal/identity"
+	"denden-core/internal/signer"
+)
+
+// ListAccounts returns the identities registered on this device
+func (c *Client) ListAccounts() []identity.Account {
+	return c.accounts.List()
+}
+
+// ActiveAccount returns the account the client is running as
+func (c *Client) ActiveAccount() (identity.Account, bool) {
+	return c.accounts.Active()
+}
+
+// IsAccountEncrypted reports whether switching to an account needs a passphrase
+func (c *Client) IsAccountEncrypted(pubKey string) bool {
+	account, ok := c.accounts.Get(pubKey)
+	if !ok {
+		return false
+	}
+	return identity.IsEncryptedFile(c.accounts.Path(account))
+}
+
+// AddAccount generates a new identity and registers it (without switching to it)
+// Parameters:
+//   - label: name for the account (may be empty)
+//   - passphrase: passphrase to encrypt the identity file with (empty for plaintext)
+//
+// Returns:
+//   - identity.Account: the new account
+//   - error: identity.ErrPassphraseRequired, or error if any
+func (c *Client) AddAccount(label, passphrase string) (identity.Account, error) {
+	ident, err := c.accounts.Generate(label, passphrase)
+	if err != nil {
+		return identity.Account{}, fmt.Errorf("failed to add account: %w", err)
+	}
+
+	account, _ := c.accounts.Get(ident.PublicKey)
+	return account, nil
+}
+
+// ImportAccount registers an identity from an nsec (without switching to it)
+// Parameters:
+//   - label: name for the account (may be empty)
+//   - nsec: private key in Bech32 format (nsec1...)
+//   - passphrase: passphrase to encrypt the identity file with (empty for plaintext)
+//
+// Returns:
+//   - identity.Account: the imported account
+//   - error: error if the key is invalid or already registered
+func (c *Client) ImportAccount(label, nsec, passphrase string) (identity.Account, error) {
+	ident, err := c.accounts.ImportNsec(label, nsec, passphrase)
+	if err != nil {
+		return identity.Account{}, fmt.Errorf("failed to import account: %w", err)
+	}
+
+	account, _ := c.accounts.Get(ident.PublicKey)
+	return account, nil
+}
+
+// RemoveAccount deletes a registered identity from this device
+// The active account can't be removed
+func (c *Client) RemoveAccount(pubKey string) error {
+	return c.accounts.Remove(pubKey)
+}
+
+// SwitchAccount switches the client to another registered identity
+// Its remote signer session is restored if it has one, and the message
+// subscription is re-established for the new public key
+//
+// Parameters:
+//   - pubKey: public key of the account (hex or npub)
+//   - passphrase: passphrase of the account's identity file (ignored if plaintext)
+//
+// Returns:
+//   - error: identity.ErrAccountNotFound, identity.ErrPassphraseRequired,
+//     identity.ErrWrongPassphrase, or error if any
+func (c *Client) SwitchAccount(pubKey, passphrase string) error {
+	account, ok := c.accounts.Get(pubKey)
+	if !ok {
+		return identity.ErrAccountNotFound
+	}
+
+	path := c.accounts.Path(account)
+	ident, err := identity.LoadIdentity(path)
+	if err != nil {
+		return err
+	}
+	if ident.IsEncrypted() {
+		if err := ident.Unlock(passphrase); err != nil {
+			return err
+		}
+	}
+
+	localSigner, err := signer.NewLocalSigner(ident.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("failed to create signer: %w", err)
+	}
+
+	if err := c.accounts.SetActive(account.PublicKey); err != nil {
+		return err
+	}
+
+	// The previous account's remote signer stays saved in its own directory
+	if closer, ok := c.signer.(io.Closer); ok {
+		closer.Close()
+	}
+
+	c.identity = ident
+	c.identityPath = path
+	c.signer = localSigner
+	c.localIdentity = nil
+	c.localSigner = nil
+	c.restoreBunker()
+
+	fmt.Printf("🔀 Switched to %s\n", c.identity.Npub)
+
+	// Re-subscribe for the new public key
+	return c.resubscribe()
+}
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"denden-core/internal/identity"
+	"denden-core/internal/signer"
+
+	"github.com/nbd-wtf/go-nostr/nip19"
+)
+
+// bunkerTimeout bounds a bunker connection, including the time the user
+// needs to approve it in the signer app
+const bunkerTimeout = 2 * time.Minute
+
+// ConnectBunker switches signing to a NIP-46 remote signer from a bunker:// URI
+// The session is saved next to the identity file and restored on the next start
+// Parameters:
+//   - bunkerURI: bunker://<remote-signer-pubkey>?relay=...&secret=...
+//   - onAuth: called with a URL if the signer wants the user to approve in a browser (may be nil)
+//
+// Returns:
+//   - error: error if the signer can't be reached or refused
+func (c *Client) ConnectBunker(bunkerURI string, onAuth func(authURL string)) error {
+	ctx, cancel := context.WithTimeout(c.ctx, bunkerTimeout)
+	defer cancel()
+
+	bunker, err := signer.ConnectBunker(ctx, bunkerURI, onAuth)
+	if err != nil {
+		return fmt.Errorf("failed to connect to bunker: %w", err)
+	}
+
+	return c.useBunker(bunker)
+}
+
+// StartNostrConnect starts a client-initiated pairing with a remote signer
+// Show the returned URI to the user, then call CompleteNostrConnect
+func (c *Client) StartNostrConnect(relays []string, appName string) (*signer.NostrConnect, error) {
+	return signer.StartNostrConnect(relays, appName)
+}
+
+// CompleteNostrConnect waits for the signer app to accept the pairing, then switches signing to it
+// Parameters:
+//   - nc: pairing from StartNostrConnect
+//   - onAuth: called with a URL if the signer wants the user to approve in a browser (may be nil)
+//
+// Returns:
+//   - error: error if the pairing timed out
+func (c *Client) CompleteNostrConnect(nc *signer.NostrConnect, onAuth func(authURL string)) error {
+	ctx, cancel := context.WithTimeout(c.ctx, bunkerTimeout)
+	defer cancel()
+
+	bunker, err := nc.Wait(ctx, onAuth)
+	if err != nil {
+		return fmt.Errorf("failed to pair with signer: %w", err)
+	}
+
+	return c.useBunker(bunker)
+}
+
+// DisconnectBunker forgets the remote signer and signs with the local key again
+func (c *Client) DisconnectBunker() error {
+	if c.localSigner == nil {
+		return fmt.Errorf("not using a remote signer")
+	}
+
+	if closer, ok := c.signer.(io.Closer); ok {
+		closer.Close()
+	}
+
+	c.signer = c.localSigner
+	c.identity = c.localIdentity
+	c.localSigner = nil
+	c.localIdentity = nil
+
+	if err := os.Remove(c.bunkerSessionPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove bunker session: %w", err)
+	}
+	return nil
+}
+
+// IsUsingBunker reports whether signing goes through a remote signer
+func (c *Client) IsUsingBunker() bool {
+	return c.localSigner != nil
+}
+
+// UseSigner switches signing and encryption to another signer
+// The client's identity becomes the signer's public key (without a private key)
+func (c *Client) UseSigner(s signer.Signer) error {
+	ctx, cancel := context.WithTimeout(c.ctx, 30*time.Second)
+	defer cancel()
+
+	pubKey, err := s.GetPublicKey(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get signer public key: %w", err)
+	}
+
+	npub, err := nip19.EncodePublicKey(pubKey)
+	if err != nil {
+		return fmt.Errorf("failed to encode public key: %w", err)
+	}
+
+	// Keep the local key so DisconnectBunker can switch back
+	if c.localSigner == nil {
+		c.localSigner = c.signer
+		c.localIdentity = c.identity
+	} else if closer, ok := c.signer.(io.Closer); ok {
+		closer.Close()
+	}
+
+	c.signer = s
+	c.identity = &identity.Identity{
+		PublicKey: pubKey,
+		Npub:      npub,
+	}
+	return nil
+}
+
+// useBunker switches to a connected bunker and saves its session
+func (c *Client) useBunker(bunker *signer.BunkerSigner) error {
+	if err := c.UseSigner(bunker); err != nil {
+		bunker.Close()
+		return err
+	}
+
+	if err := signer.SaveBunkerSession(bunker.Session(), c.bunkerSessionPath()); err != nil {
+		fmt.Printf("⚠️  Bunker session not saved: %v\n", err)
+	}
+
+	fmt.Printf("🔐 Signing with remote signer for %s\n", c.identity.Npub)
+	return nil
+}
+
+// restoreBunker reconnects to the remote signer saved by a previous session, if any
+func (c *Client) restoreBunker() {
+	session, err := signer.LoadBunkerSession(c.bunkerSessionPath())
+	if err != nil {
+		return // No saved session
+	}
+
+	bunker, err := signer.RestoreBunker(*session, nil)
+	if err != nil {
+		fmt.Printf("⚠️  Remote signer unavailable, using local key: %v\n", err)
+		return
+	}
+
+	if err := c.UseSigner(bunker); err != nil {
+		bunker.Close()
+		fmt.Printf("⚠️  Remote signer unavailable, using local key: %v\n", err)
+		return
+	}
+
+	fmt.Printf("🔐 Signing with remote signer for %s\n", c.identity.Npub)
+}
+
+// bunkerSessionPath returns the path of the saved bunker session (next to the identity file)
+func (c *Client) bunkerSessionPath() string {
+	return filepath.Join(filepath.Dir(c.identityPath), "bunker.json")
+}
@@ -3,25 +3,46 @@ package client
 import (
 	"context"
 	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"time"
 
-	"denden-core/internal/crypto"
 	"denden-core/internal/identity"
+	"denden-core/internal/ingest"
 	"denden-core/internal/pow"
 	"denden-core/internal/relay"
+	"denden-core/internal/signer"
+	"denden-core/internal/store"
 
 	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip49"
 )
 
-// Client represents the Den Den client with identity and relay connection
+// Client represents the Den Den client with identity and relay connections
 type Client struct {
-	identity *identity.Identity
-	relay    *relay.Relay
-	ctx      context.Context
-	cancel   context.CancelFunc
+	identity      *identity.Identity
+	identityPath  string             // File the identity was loaded from
+	signer        signer.Signer      // Signs and encrypts for every publish and DM path
+	localIdentity *identity.Identity // Local identity while a remote signer is in use (nil otherwise)
+	localSigner   signer.Signer      // Local key signer while a remote signer is in use (nil otherwise)
+	accounts      *identity.Registry // Identities registered next to the identity file
+	pool          *relay.Pool
+	ingest        *ingest.Pipeline   // Verifies ID, signature and PoW of received events
+	publishPolicy *pow.Policy        // PoW difficulty per kind for outgoing events
+	powAdvisor    *pow.Advisor       // Default outgoing PoW from relay requirements, mining estimates
+	store         *store.Store       // Local event store (nil if not opened)
+	onState       relay.StateHandler // Receives relay connection state changes
+	ctx           context.Context
+	cancel        context.CancelFunc
+	listenCancel  context.CancelFunc // Stops the StartListening subscription (nil if not listening)
 }
 
 // NewClient creates a new client instance
+// Returns identity.ErrPassphraseRequired if the identity file is encrypted
+// (use NewClientWithPassphrase)
+//
 // Parameters:
 //   - identityPath: path to the identity file (use empty string for default)
 //
@@ -29,6 +50,24 @@ type Client struct {
 //   - *Client: new client instance
 //   - error: error if any
 func NewClient(identityPath string) (*Client, error) {
+	return newClient(identityPath, "")
+}
+
+// NewClientWithPassphrase creates a new client with an encrypted identity (NIP-49)
+// A new identity is saved encrypted; an existing plaintext one is migrated
+// Parameters:
+//   - identityPath: path to the identity file (use empty string for default)
+//   - passphrase: passphrase protecting the private key
+//
+// Returns:
+//   - *Client: new client instance
+//   - error: identity.ErrWrongPassphrase, or error if any
+func NewClientWithPassphrase(identityPath, passphrase string) (*Client, error) {
+	return newClient(identityPath, passphrase)
+}
+
+// newClient loads or generates the identity and creates the client
+func newClient(identityPath, passphrase string) (*Client, error) {
 	// Get identity path
 	if identityPath == "" {
 		var err error
@@ -38,8 +77,23 @@ func NewClient(identityPath string) (*Client, error) {
 		}
 	}
 
+	// Use the active account if several identities are registered
+	accounts, err := identity.OpenRegistry(filepath.Dir(identityPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open account registry: %w", err)
+	}
+	if active, ok := accounts.Active(); ok {
+		identityPath = accounts.Path(active)
+	}
+
 	// Load or generate identity
-	ident, isNew, err := identity.EnsureIdentity(identityPath)
+	var ident *identity.Identity
+	var isNew bool
+	if passphrase != "" {
+		ident, isNew, err = identity.EnsureIdentityWithPassphrase(identityPath, passphrase)
+	} else {
+		ident, isNew, err = identity.EnsureIdentity(identityPath)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to ensure identity: %w", err)
 	}
@@ -50,93 +104,338 @@ func NewClient(identityPath string) (*Client, error) {
 		fmt.Println("✅ Identity loaded from file")
 	}
 
+	if _, err := accounts.Register(identityPath, ""); err != nil {
+		return nil, fmt.Errorf("failed to register identity: %w", err)
+	}
+
+	localSigner, err := signer.NewLocalSigner(ident.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create signer: %w", err)
+	}
+
 	// Create context
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &Client{
-		identity: ident,
-		ctx:      ctx,
-		cancel:   cancel,
-	}, nil
+	// Outgoing PoW follows the relays' requirements unless the user overrides it
+	advisor := pow.NewAdvisor()
+
+	c := &Client{
+		identity:      ident,
+		identityPath:  identityPath,
+		signer:        localSigner,
+		accounts:      accounts,
+		ingest:        ingest.NewPipeline(),
+		publishPolicy: pow.NewPolicy(advisor.Difficulty),
+		powAdvisor:    advisor,
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+
+	// Switch to the remote signer (NIP-46) if one was connected before
+	c.restoreBunker()
+
+	return c, nil
+}
+
+// LockIdentity encrypts the plaintext identity file with a passphrase (NIP-49)
+// The keys in memory stay usable; the next start needs the passphrase
+func (c *Client) LockIdentity(passphrase string) error {
+	if c.identity.IsEncrypted() {
+		return fmt.Errorf("identity is already encrypted, use ChangePassphrase")
+	}
+
+	if err := identity.SaveEncryptedIdentity(c.identity, c.identityPath, passphrase); err != nil {
+		return fmt.Errorf("failed to lock identity: %w", err)
+	}
+	return nil
 }
 
-// Connect connects to a Nostr relay
+// ChangePassphrase re-encrypts the identity file with a new passphrase
+func (c *Client) ChangePassphrase(oldPassphrase, newPassphrase string) error {
+	if err := identity.ChangePassphrase(c.identityPath, oldPassphrase, newPassphrase); err != nil {
+		return fmt.Errorf("failed to change passphrase: %w", err)
+	}
+
+	ident, err := identity.LoadIdentity(c.identityPath)
+	if err == nil {
+		c.identity.Ncryptsec = ident.Ncryptsec
+		c.identity.EncryptedMnemonic = ident.EncryptedMnemonic
+	}
+	return nil
+}
+
+// Connect connects to a Nostr relay and adds it to the relay pool
 // Parameters:
 //   - relayURL: WebSocket URL of the relay (e.g., "wss://relay.damus.io")
 //
 // Returns:
 //   - error: connection error if any
 func (c *Client) Connect(relayURL string) error {
-	r, err := relay.Connect(relayURL)
-	if err != nil {
+	return c.ConnectAll([]string{relayURL})
+}
+
+// ConnectAll connects to every given relay and adds them to the relay pool
+// Relays that can't be reached yet stay in the pool and keep retrying in the
+// background. The relays' PoW requirements (NIP-11) are fetched in the background
+// Parameters:
+//   - relayURLs: WebSocket URLs of the relays
+//
+// Returns:
+//   - error: error if none of the relays could be reached
+func (c *Client) ConnectAll(relayURLs []string) error {
+	pool := c.pool
+	if pool == nil {
+		pool = relay.NewPool()
+		pool.SetStateHandler(c.onState)
+	}
+
+	if err := pool.AddAll(relayURLs); err != nil {
 		return fmt.Errorf("failed to connect to relay: %w", err)
 	}
 
-	c.relay = r
+	c.pool = pool
+	c.refreshRelayRequirements(pool.URLs())
 	return nil
 }
 
-// SendEncryptedMessage sends an encrypted direct message
+// OpenStore opens the local event store
+// Once opened, every event published or received through the client is saved in it
 // Parameters:
-//   - recipientPubKey: recipient's public key (hex format or npub)
-//   - content: plaintext message content
+//   - dbPath: path to the SQLite database file (use empty string for default)
 //
 // Returns:
-//   - error: send error if any
-func (c *Client) SendEncryptedMessage(recipientPubKey, content string) error {
-	if c.relay == nil {
-		return fmt.Errorf("not connected to any relay")
-	}
-
-	// Decode npub if necessary
-	if len(recipientPubKey) > 4 && recipientPubKey[:4] == "npub" {
-		decoded, err := identity.DecodePublicKey(recipientPubKey)
+//   - error: error if the store can't be opened
+func (c *Client) OpenStore(dbPath string) error {
+	if dbPath == "" {
+		var err error
+		dbPath, err = store.GetDefaultStorePath()
 		if err != nil {
-			return fmt.Errorf("failed to decode recipient public key: %w", err)
+			return fmt.Errorf("failed to get default store path: %w", err)
 		}
-		recipientPubKey = decoded
 	}
 
-	// Encrypt message
-	encrypted, err := crypto.Encrypt(content, c.identity.PrivateKey, recipientPubKey)
+	s, err := store.Open(dbPath)
 	if err != nil {
-		return fmt.Errorf("failed to encrypt message: %w", err)
+		return fmt.Errorf("failed to open event store: %w", err)
 	}
 
-	// Create Kind 4 event (Encrypted Direct Message)
-	event := &nostr.Event{
-		PubKey:    c.identity.PublicKey,
-		CreatedAt: nostr.Timestamp(time.Now().Unix()),
-		Kind:      4, // Kind 4 = Encrypted Direct Message
-		Tags: []nostr.Tag{
-			{"p", recipientPubKey}, // Recipient's public key
-		},
-		Content: encrypted,
+	c.store = s
+	return nil
+}
+
+// SetRelayStateHandler sets the handler for relay connection state changes
+// (connecting/connected/lost). Must be called before Connect/ConnectAll
+func (c *Client) SetRelayStateHandler(onState relay.StateHandler) {
+	c.onState = onState
+}
+
+// Publish publishes a signed event to every relay in the pool
+// and saves it in the local event store
+// Parameters:
+//   - ctx: context (for timeout control)
+//   - event: signed event
+//
+// Returns:
+//   - error: error if no relay accepted the event
+func (c *Client) Publish(ctx context.Context, event *nostr.Event) error {
+	if c.pool == nil {
+		return fmt.Errorf("not connected to any relay")
 	}
 
-	// Mine with PoW
-	difficulty := pow.GetDifficultyRecommendation("private")
-	_, _, _, err = pow.MineEvent(event, difficulty)
-	if err != nil {
-		return fmt.Errorf("failed to mine event: %w", err)
+	if err := c.pool.Publish(ctx, event); err != nil {
+		return err
 	}
 
-	// Sign event
-	err = event.Sign(c.identity.PrivateKey)
+	c.saveEvent(event)
+	return nil
+}
+
+// QuerySync queries every relay in the pool and saves the results
+// in the local event store
+// Events that fail verification (ID, signature, PoW) are dropped, and so are
+// events their author deleted (NIP-09) that the relays still serve
+// Parameters:
+//   - ctx: context (for timeout control)
+//   - filter: filter conditions
+//
+// Returns:
+//   - []*nostr.Event: merged events, newest first
+//   - error: query error
+func (c *Client) QuerySync(ctx context.Context, filter nostr.Filter) ([]*nostr.Event, error) {
+	if c.pool == nil {
+		return nil, fmt.Errorf("not connected to any relay")
+	}
+
+	events, err := c.pool.QuerySync(ctx, filter)
 	if err != nil {
-		return fmt.Errorf("failed to sign event: %w", err)
+		return nil, err
 	}
 
-	// Publish to relay
-	ctx, cancel := context.WithTimeout(c.ctx, 10*time.Second)
-	defer cancel()
+	events = c.ingest.Filter(events)
+	for _, event := range events {
+		c.saveEvent(event)
+	}
+	return c.dropDeleted(events), nil
+}
 
-	err = c.relay.Publish(ctx, event)
+// Subscribe subscribes on every relay in the pool
+// Received events are verified (ID, signature, PoW) and saved in the local
+// event store before being delivered; events that fail verification or that
+// their author deleted are dropped. Deletions (Kind 5) of events already
+// delivered are delivered too, even if the filters don't ask for Kind 5, so
+// the caller can remove the deleted events (see store.Deletes)
+// Parameters:
+//   - ctx: context (for canceling subscription)
+//   - filters: filter conditions
+//
+// Returns:
+//   - chan *nostr.Event: merged Event receive channel
+//   - error: subscription error
+func (c *Client) Subscribe(ctx context.Context, filters []nostr.Filter) (chan *nostr.Event, error) {
+	if c.pool == nil {
+		return nil, fmt.Errorf("not connected to any relay")
+	}
+
+	events, err := c.pool.Subscribe(ctx, slices.Concat(filters, deletionFilters(filters, nostr.Now())))
 	if err != nil {
-		return fmt.Errorf("failed to publish event: %w", err)
+		return nil, err
 	}
 
-	return nil
+	verified := make(chan *nostr.Event, 10)
+	go func() {
+		defer close(verified)
+		delivered := newDeliveredEvents()
+		for event := range events {
+			if err := c.ingest.Check(event); err != nil {
+				continue
+			}
+
+			c.saveEvent(event)
+			switch {
+			case event.Kind == nostr.KindDeletion && !nostr.Filters(filters).Match(event):
+				if !delivered.remove(event) {
+					continue
+				}
+			case c.isDeleted(event):
+				continue
+			default:
+				delivered.add(event)
+			}
+
+			select {
+			case verified <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return verified, nil
+}
+
+// deletionFilters returns filters for the deletions (Kind 5) of the events a
+// subscription asks for: by the same authors or, for filters without authors,
+// mentioning the same kinds ("k" tags). Only deletions since the filter's
+// since (or now) are asked for: older ones come with queries
+func deletionFilters(filters []nostr.Filter, now nostr.Timestamp) []nostr.Filter {
+	var deletions []nostr.Filter
+	seen := make(map[string]bool)
+	for _, filter := range filters {
+		if len(filter.Kinds) == 0 && len(filter.Authors) == 0 || slices.Contains(filter.Kinds, nostr.KindDeletion) {
+			continue
+		}
+
+		since := now
+		if filter.Since != nil {
+			since = *filter.Since
+		}
+		deletion := nostr.Filter{Kinds: []int{nostr.KindDeletion}, Since: &since}
+		if len(filter.Authors) > 0 {
+			deletion.Authors = filter.Authors
+		} else {
+			kinds := make([]string, len(filter.Kinds))
+			for i, kind := range filter.Kinds {
+				kinds[i] = strconv.Itoa(kind)
+			}
+			deletion.Tags = nostr.TagMap{"k": kinds}
+		}
+
+		if key := deletion.String(); !seen[key] {
+			seen[key] = true
+			deletions = append(deletions, deletion)
+		}
+	}
+	return deletions
+}
+
+// deliveredEvents remembers what a subscription delivered (ID, author, kind,
+// time and d tag only), to tell which deletions concern it
+type deliveredEvents struct {
+	byAuthor map[string][]*nostr.Event
+}
+
+// newDeliveredEvents creates an empty set
+func newDeliveredEvents() *deliveredEvents {
+	return &deliveredEvents{byAuthor: make(map[string][]*nostr.Event)}
+}
+
+// add remembers a delivered event
+func (d *deliveredEvents) add(event *nostr.Event) {
+	kept := &nostr.Event{ID: event.ID, PubKey: event.PubKey, Kind: event.Kind, CreatedAt: event.CreatedAt}
+	if tag := event.Tags.Find("d"); tag != nil {
+		kept.Tags = nostr.Tags{tag}
+	}
+	d.byAuthor[event.PubKey] = append(d.byAuthor[event.PubKey], kept)
+}
+
+// remove forgets the delivered events a deletion removes
+// Returns: true if there were any
+func (d *deliveredEvents) remove(deletion *nostr.Event) bool {
+	events := d.byAuthor[deletion.PubKey]
+	kept := events[:0]
+	for _, event := range events {
+		if !store.Deletes(deletion, event) {
+			kept = append(kept, event)
+		}
+	}
+	if len(kept) == 0 {
+		delete(d.byAuthor, deletion.PubKey)
+	} else {
+		d.byAuthor[deletion.PubKey] = kept
+	}
+	return len(kept) < len(events)
+}
+
+// QueryLocal queries the local event store only
+// Returns no events (and no error) if the store isn't open
+func (c *Client) QueryLocal(filter nostr.Filter) ([]*nostr.Event, error) {
+	if c.store == nil {
+		return nil, nil
+	}
+	return c.store.QueryEvents(filter)
+}
+
+// saveEvent stores an event locally, logging (not failing) on errors
+func (c *Client) saveEvent(event *nostr.Event) {
+	if c.store == nil {
+		return
+	}
+	if _, err := c.store.SaveEvent(event); err != nil {
+		fmt.Printf("⚠️  Failed to store event %s: %v\n", event.ID, err)
+	}
+}
+
+// isDeleted reports whether the local store has a tombstone for an event
+func (c *Client) isDeleted(event *nostr.Event) bool {
+	return c.store != nil && c.store.IsDeleted(event)
+}
+
+// dropDeleted removes the events the local store has tombstones for
+func (c *Client) dropDeleted(events []*nostr.Event) []*nostr.Event {
+	if c.store == nil {
+		return events
+	}
+	return c.store.FilterDeleted(events)
 }
 
 // GetIdentity returns the client's identity
@@ -144,9 +443,20 @@ func (c *Client) GetIdentity() *identity.Identity {
 	return c.identity
 }
 
-// GetRelay returns the relay connection
-func (c *Client) GetRelay() *relay.Relay {
-	return c.relay
+// GetIngest returns the pipeline that verifies received events
+// (use it to change the minimum PoW per kind)
+func (c *Client) GetIngest() *ingest.Pipeline {
+	return c.ingest
+}
+
+// GetStore returns the local event store (nil if not opened)
+func (c *Client) GetStore() *store.Store {
+	return c.store
+}
+
+// GetPool returns the relay pool (nil if not connected)
+func (c *Client) GetPool() *relay.Pool {
+	return c.pool
 }
 
 // GetContext returns the client's context
@@ -158,19 +468,66 @@ func (c *Client) GetContext() context.Context {
 func (c *Client) Close() error {
 	c.cancel() // Cancel context
 
-	if c.relay != nil {
-		return c.relay.Close()
+	if closer, ok := c.signer.(io.Closer); ok {
+		closer.Close()
+	}
+
+	if c.store != nil {
+		c.store.Close()
+	}
+
+	if c.pool != nil {
+		return c.pool.Close()
 	}
 
 	return nil
 }
 
-// GetPrivateKey returns the private key (hex)
-func (c *Client) GetPrivateKey() string {
-	if c.identity == nil {
-		return ""
+// GetSigner returns the signer used for publishing and DMs
+func (c *Client) GetSigner() signer.Signer {
+	return c.signer
+}
+
+// SignEvent signs an event with the client's signer
+// The event must be complete (tags, content, PoW nonce) before signing
+func (c *Client) SignEvent(event *nostr.Event) error {
+	ctx, cancel := context.WithTimeout(c.ctx, 30*time.Second)
+	defer cancel()
+
+	return c.signer.SignEvent(ctx, event)
+}
+
+// ExportSecretKey exports the private key encrypted with a passphrase (NIP-49 ncryptsec)
+// This is the only way the key leaves the client. If the identity file is
+// encrypted, the passphrase must be the one that unlocks it
+//
+// Parameters:
+//   - passphrase: passphrase to encrypt the exported key with
+//
+// Returns:
+//   - string: ncryptsec1... key
+//   - error: identity.ErrWrongPassphrase, or error if the key isn't held locally
+func (c *Client) ExportSecretKey(passphrase string) (string, error) {
+	if passphrase == "" {
+		return "", identity.ErrPassphraseRequired
+	}
+	if c.identity.IsLocked() {
+		return "", fmt.Errorf("secret key is not held by this client")
+	}
+
+	// Re-authenticate against the identity file's passphrase
+	if c.identity.IsEncrypted() {
+		check := &identity.Identity{PublicKey: c.identity.PublicKey, Ncryptsec: c.identity.Ncryptsec}
+		if err := check.Unlock(passphrase); err != nil {
+			return "", err
+		}
+	}
+
+	ncryptsec, err := nip49.Encrypt(c.identity.PrivateKey, passphrase, identity.ScryptLogN, nip49.ClientDoesNotTrackThisData)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt secret key: %w", err)
 	}
-	return c.identity.PrivateKey
+	return ncryptsec, nil
 }
 
 // GetPublicKey returns the public key (hex)
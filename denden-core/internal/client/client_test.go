@@ -0,0 +1,76 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestDeletionFilters(t *testing.T) {
+	alice := strings.Repeat("a", 64)
+	since := nostr.Timestamp(50)
+	now := nostr.Timestamp(100)
+
+	filters := []nostr.Filter{
+		{Kinds: []int{1, 6}, Authors: []string{alice}, Since: &since},
+		{Kinds: []int{1, 6}, Limit: 20},
+		{Kinds: []int{1, 6}, Tags: nostr.TagMap{"t": {"nostr"}}}, // Same deletions as the previous one
+		{IDs: []string{alice}},                                   // No kinds nor authors
+		{Kinds: []int{5}, Authors: []string{alice}},              // Asks for deletions itself
+	}
+
+	got := deletionFilters(filters, now)
+	want := []nostr.Filter{
+		{Kinds: []int{5}, Authors: []string{alice}, Since: &since},
+		{Kinds: []int{5}, Tags: nostr.TagMap{"k": {"1", "6"}}, Since: &now},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("deletionFilters() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if !nostr.FilterEqual(got[i], want[i]) {
+			t.Errorf("deletionFilters()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDeliveredEventsRemove(t *testing.T) {
+	alice := strings.Repeat("a", 64)
+	bob := strings.Repeat("b", 64)
+	event := func(n int, author string, kind int, tags ...nostr.Tag) *nostr.Event {
+		return &nostr.Event{ID: fmt.Sprintf("%064x", n), PubKey: author, Kind: kind, CreatedAt: 100, Tags: tags}
+	}
+	deletion := func(author string, tags ...nostr.Tag) *nostr.Event {
+		return &nostr.Event{ID: strings.Repeat("f", 64), PubKey: author, Kind: 5, CreatedAt: 200, Tags: tags}
+	}
+
+	delivered := newDeliveredEvents()
+	note := event(1, alice, 1, nostr.Tag{"p", bob})
+	article := event(2, alice, 30023, nostr.Tag{"d", "post"}, nostr.Tag{"t", "nostr"})
+	delivered.add(note)
+	delivered.add(article)
+	delivered.add(event(3, bob, 1))
+
+	steps := []struct {
+		name     string
+		deletion *nostr.Event
+		want     bool
+	}{
+		{"not delivered", deletion(alice, nostr.Tag{"e", fmt.Sprintf("%064x", 9)}), false},
+		{"someone else's", deletion(bob, nostr.Tag{"e", note.ID}), false},
+		{"by ID", deletion(alice, nostr.Tag{"e", note.ID}), true},
+		{"already removed", deletion(alice, nostr.Tag{"e", note.ID}), false},
+		{"by address", deletion(alice, nostr.Tag{"a", "30023:" + alice + ":post"}), true},
+		{"other author's note", deletion(bob, nostr.Tag{"e", fmt.Sprintf("%064x", 3)}), true},
+	}
+	for _, step := range steps {
+		if got := delivered.remove(step.deletion); got != step.want {
+			t.Errorf("%s: remove() = %v, want %v", step.name, got, step.want)
+		}
+	}
+	if len(delivered.byAuthor) != 0 {
+		t.Errorf("byAuthor = %v, want empty", delivered.byAuthor)
+	}
+}
@@ -0,0 +1,284 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"denden-core/internal/crypto"
+	"denden-core/internal/identity"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// DirectMessage is a decrypted direct message (Kind 4 or NIP-17)
+type DirectMessage struct {
+	ID        string          // Event ID
+	Sender    string          // Author's public key (hex)
+	Partner   string          // The other party in the conversation (hex)
+	Content   string          // Decrypted plaintext
+	CreatedAt nostr.Timestamp // Event timestamp
+	IsMine    bool            // true if we sent it
+	Scheme    crypto.Scheme   // Encryption scheme the sender used (nip04, nip44 or nip17)
+}
+
+// SendEncryptedMessage sends an encrypted direct message
+// Messages are always encrypted with NIP-44, so CLI and mobile can read each other
+// Parameters:
+//   - recipientPubKey: recipient's public key (hex format or npub)
+//   - content: plaintext message content
+//
+// Returns:
+//   - *nostr.Event: the published event
+//   - error: send error if any
+func (c *Client) SendEncryptedMessage(recipientPubKey, content string) (*nostr.Event, error) {
+	if c.pool == nil {
+		return nil, fmt.Errorf("not connected to any relay")
+	}
+
+	// Decode npub if necessary
+	if len(recipientPubKey) > 4 && recipientPubKey[:4] == "npub" {
+		decoded, err := identity.DecodePublicKey(recipientPubKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode recipient public key: %w", err)
+		}
+		recipientPubKey = decoded
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, 10*time.Second)
+	defer cancel()
+
+	// Encrypt message
+	encrypted, err := c.signer.Encrypt(ctx, content, recipientPubKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt message: %w", err)
+	}
+
+	// Create Kind 4 event (Encrypted Direct Message)
+	event := &nostr.Event{
+		Kind: 4, // Kind 4 = Encrypted Direct Message
+		Tags: []nostr.Tag{
+			{"p", recipientPubKey}, // Recipient's public key
+		},
+		Content: encrypted,
+	}
+
+	// Mine, sign and publish to all relays
+	if err := c.PublishEvent(c.ctx, event, nil); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+// DecryptDirectMessage decrypts a direct message we sent or received
+// Accepts Kind 4 events (NIP-04 or NIP-44 content, detected from the
+// ciphertext format) and Kind 1059 NIP-17 gift wraps
+// Parameters:
+//   - event: Kind 4 or Kind 1059 event
+//
+// Returns:
+//   - *DirectMessage: decrypted message
+//   - error: error if the event isn't ours or can't be decrypted
+func (c *Client) DecryptDirectMessage(event *nostr.Event) (*DirectMessage, error) {
+	switch event.Kind {
+	case 4:
+		// Handled below
+	case crypto.KindGiftWrap:
+		return c.UnwrapPrivateMessage(event)
+	default:
+		return nil, fmt.Errorf("not a direct message (kind %d)", event.Kind)
+	}
+
+	myPubKey := c.identity.PublicKey
+	isMine := event.PubKey == myPubKey
+
+	// Find the conversation partner
+	var partner string
+	if isMine {
+		if tag := event.Tags.Find("p"); tag != nil {
+			partner = tag[1]
+		}
+	} else {
+		partner = event.PubKey
+		if event.Tags.FindWithValue("p", myPubKey) == nil {
+			return nil, fmt.Errorf("direct message is not addressed to us")
+		}
+	}
+
+	if partner == "" {
+		return nil, fmt.Errorf("direct message has no recipient")
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, 10*time.Second)
+	defer cancel()
+
+	scheme := crypto.DetectScheme(event.Content)
+	plaintext, err := c.signer.Decrypt(ctx, event.Content, partner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s message: %w", scheme, err)
+	}
+
+	return &DirectMessage{
+		ID:        event.ID,
+		Sender:    event.PubKey,
+		Partner:   partner,
+		Content:   plaintext,
+		CreatedAt: event.CreatedAt,
+		IsMine:    isMine,
+		Scheme:    scheme,
+	}, nil
+}
+
+// SendPrivateMessage sends a NIP-17 private direct message
+// The Kind 14 message is sealed and gift-wrapped (NIP-59), so relays can't see
+// who is talking to whom or when. A second copy is gift-wrapped to ourselves,
+// which lets our other devices sync the sent history
+// Parameters:
+//   - ctx: context (canceling it stops mining the gift wraps)
+//   - recipientPubKey: recipient's public key (hex format or npub)
+//   - content: plaintext message content
+//
+// Returns:
+//   - *DirectMessage: the sent message (ID is the rumor ID, shared by both copies)
+//   - error: send error if any
+func (c *Client) SendPrivateMessage(ctx context.Context, recipientPubKey, content string) (*DirectMessage, error) {
+	if c.pool == nil {
+		return nil, fmt.Errorf("not connected to any relay")
+	}
+
+	// Decode npub if necessary
+	if len(recipientPubKey) > 4 && recipientPubKey[:4] == "npub" {
+		decoded, err := identity.DecodePublicKey(recipientPubKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode recipient public key: %w", err)
+		}
+		recipientPubKey = decoded
+	}
+
+	myPubKey := c.identity.PublicKey
+
+	// Create the Kind 14 rumor (never signed)
+	rumor := nostr.Event{
+		PubKey:    myPubKey,
+		CreatedAt: nostr.Timestamp(time.Now().Unix()),
+		Kind:      crypto.KindPrivateMessage,
+		Tags: nostr.Tags{
+			{"p", recipientPubKey},
+		},
+		Content: content,
+	}
+	rumor.ID = rumor.GetID()
+
+	// Gift wrap for the recipient
+	wrap, err := c.giftWrap(ctx, rumor, recipientPubKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap message: %w", err)
+	}
+	if err := c.publishWrap(ctx, wrap); err != nil {
+		return nil, fmt.Errorf("failed to publish message: %w", err)
+	}
+
+	// Gift wrap a copy for ourselves (sent history on other devices)
+	if recipientPubKey != myPubKey {
+		selfWrap, err := c.giftWrap(ctx, rumor, myPubKey)
+		if err == nil {
+			err = c.publishWrap(ctx, selfWrap)
+		}
+		if err != nil {
+			fmt.Printf("⚠️  Failed to store self copy of message: %v\n", err)
+		}
+	}
+
+	return &DirectMessage{
+		ID:        rumor.ID,
+		Sender:    myPubKey,
+		Partner:   recipientPubKey,
+		Content:   content,
+		CreatedAt: rumor.CreatedAt,
+		IsMine:    true,
+		Scheme:    crypto.SchemeNIP17,
+	}, nil
+}
+
+// UnwrapPrivateMessage opens a NIP-17 gift wrap addressed to us
+// Works for messages from others and for our own self-addressed copies
+// Parameters:
+//   - wrap: Kind 1059 gift wrap
+//
+// Returns:
+//   - *DirectMessage: decrypted message
+//   - error: error if the wrap can't be opened or doesn't hold a Kind 14 message
+func (c *Client) UnwrapPrivateMessage(wrap *nostr.Event) (*DirectMessage, error) {
+	ctx, cancel := context.WithTimeout(c.ctx, 10*time.Second)
+	defer cancel()
+
+	rumor, err := crypto.GiftUnwrap(wrap, func(otherPubKey, ciphertext string) (string, error) {
+		return c.signer.Decrypt(ctx, ciphertext, otherPubKey)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap message: %w", err)
+	}
+
+	if rumor.Kind != crypto.KindPrivateMessage {
+		return nil, fmt.Errorf("unsupported private event (kind %d)", rumor.Kind)
+	}
+
+	myPubKey := c.identity.PublicKey
+	isMine := rumor.PubKey == myPubKey
+
+	// Find the conversation partner: the sender, or the first recipient that isn't us
+	partner := rumor.PubKey
+	if isMine {
+		partner = ""
+		for _, tag := range rumor.Tags {
+			if len(tag) >= 2 && tag[0] == "p" && tag[1] != myPubKey {
+				partner = tag[1]
+				break
+			}
+		}
+		if partner == "" {
+			partner = myPubKey // Note to self
+		}
+	}
+
+	return &DirectMessage{
+		ID:        rumor.ID,
+		Sender:    rumor.PubKey,
+		Partner:   partner,
+		Content:   rumor.Content,
+		CreatedAt: rumor.CreatedAt,
+		IsMine:    isMine,
+		Scheme:    crypto.SchemeNIP17,
+	}, nil
+}
+
+// giftWrap seals and gift-wraps a rumor for one recipient using our signer
+// The wrap is mined with the publish policy's Kind 1059 difficulty, until ctx
+// is done; the signer gets 30 seconds for each operation
+func (c *Client) giftWrap(ctx context.Context, rumor nostr.Event, recipientPubKey string) (*nostr.Event, error) {
+	signCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return crypto.GiftWrap(
+		rumor,
+		recipientPubKey,
+		func(plaintext string) (string, error) {
+			return c.signer.Encrypt(signCtx, plaintext, recipientPubKey)
+		},
+		func(seal *nostr.Event) error {
+			return c.signer.SignEvent(signCtx, seal)
+		},
+		func(wrap *nostr.Event) error {
+			return c.mine(ctx, wrap, nil, true)
+		},
+	)
+}
+
+// publishWrap publishes a signed gift wrap (mining is done by giftWrap)
+func (c *Client) publishWrap(ctx context.Context, wrap *nostr.Event) error {
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+
+	return c.Publish(ctx, wrap)
+}
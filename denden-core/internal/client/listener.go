@@ -1,56 +1,72 @@
 package client
 
 import (
+	"context"
 	"fmt"
 
-	"denden-core/internal/crypto"
-
 	"github.com/nbd-wtf/go-nostr"
 )
 
 // StartListening starts a background goroutine to listen for incoming messages
 // This will continuously receive messages from the relay and print them to console
 func (c *Client) StartListening() error {
-	if c.relay == nil {
+	if c.pool == nil {
 		return fmt.Errorf("not connected to any relay")
 	}
 
 	// Create subscription filter
-	// Subscribe to Kind 4 (Encrypted DM) events where we are the recipient
+	// Subscribe to Kind 4 (Encrypted DM) and Kind 1059 (NIP-17 gift wrap)
+	// events where we are the recipient
 	filters := []nostr.Filter{
 		{
-			Kinds: []int{4}, // Kind 4 = Encrypted Direct Message
+			Kinds: []int{4, 1059}, // Kind 4 = Encrypted Direct Message, Kind 1059 = Gift Wrap
 			Tags: nostr.TagMap{
 				"p": []string{c.identity.PublicKey}, // Messages sent to us
 			},
 		},
 	}
 
-	// Subscribe to events
-	eventChan, err := c.relay.Subscribe(c.ctx, filters)
+	// Subscribe to events (canceled on Close, or when switching accounts)
+	ctx, cancel := context.WithCancel(c.ctx)
+	eventChan, err := c.Subscribe(ctx, filters)
 	if err != nil {
+		cancel()
 		return fmt.Errorf("failed to subscribe: %w", err)
 	}
+	c.listenCancel = cancel
 
 	// Start background goroutine to handle incoming events
-	go c.handleIncomingEvents(eventChan)
+	go c.handleIncomingEvents(ctx, eventChan)
 
 	fmt.Println("👂 Listening for messages...")
 	return nil
 }
 
+// resubscribe restarts the StartListening subscription after the identity changed
+// Does nothing if the client isn't listening
+func (c *Client) resubscribe() error {
+	if c.listenCancel == nil {
+		return nil
+	}
+
+	c.listenCancel()
+	c.listenCancel = nil
+	return c.StartListening()
+}
+
 // handleIncomingEvents processes incoming events from the subscription
 // This runs in a separate goroutine
-func (c *Client) handleIncomingEvents(eventChan chan *nostr.Event) {
+func (c *Client) handleIncomingEvents(ctx context.Context, eventChan chan *nostr.Event) {
 	for {
 		select {
-		case <-c.ctx.Done():
+		case <-ctx.Done():
 			// Context cancelled, stop listening
 			return
 
 		case event, ok := <-eventChan:
 			if !ok {
-				// Channel closed
+				// Channel closed: the subscription ended for good
+				// (dropped connections are re-established by the relay layer)
 				return
 			}
 
@@ -62,16 +78,28 @@ func (c *Client) handleIncomingEvents(eventChan chan *nostr.Event) {
 
 // processEvent processes a single incoming event
 func (c *Client) processEvent(event *nostr.Event) {
-	// Decrypt the message
-	decrypted, err := crypto.Decrypt(event.Content, c.identity.PrivateKey, event.PubKey)
+	// Deletions of messages already printed: nothing to take back
+	if event.Kind == nostr.KindDeletion {
+		return
+	}
+
+	// Decrypt the message (NIP-04, NIP-44 or NIP-17 gift wrap)
+	dm, err := c.DecryptDirectMessage(event)
 	if err != nil {
 		fmt.Printf("⚠️  Failed to decrypt message from %s: %v\n", event.PubKey[:16]+"...", err)
 		return
 	}
 
+	// Our own self-addressed copies arrive here too
+	if dm.IsMine {
+		return
+	}
+
 	// Format and print the message
-	fmt.Printf("\n📨 New message from %s\n", event.PubKey[:16]+"...")
-	fmt.Printf("   Content: %s\n", decrypted)
-	fmt.Printf("   Time: %s\n", event.CreatedAt.Time().Format("2006-01-02 15:04:05"))
+	// (for gift wraps, event.PubKey is a throwaway key, so use dm.Sender)
+	fmt.Printf("\n📨 New message from %s\n", dm.Sender[:16]+"...")
+	fmt.Printf("   Content: %s\n", dm.Content)
+	fmt.Printf("   Encryption: %s\n", dm.Scheme)
+	fmt.Printf("   Time: %s\n", dm.CreatedAt.Time().Format("2006-01-02 15:04:05"))
 	fmt.Print("\n> ") // Re-print prompt
 }
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"fmt"
+
+	"denden-core/internal/identity"
+	"denden-core/internal/signer"
+)
+
+// BackupMnemonic returns the mnemonic the identity was derived from (NIP-06)
+// If the identity file is encrypted, the passphrase must be the one that unlocks it
+//
+// Parameters:
+//   - passphrase: identity passphrase (ignored for plaintext identities)
+//
+// Returns:
+//   - string: space separated words
+//   - error: identity.ErrNoMnemonic, identity.ErrWrongPassphrase, or error if any
+func (c *Client) BackupMnemonic(passphrase string) (string, error) {
+	if c.IsUsingBunker() {
+		return "", fmt.Errorf("secret key is held by the remote signer")
+	}
+	if !c.identity.HasMnemonic() {
+		return "", identity.ErrNoMnemonic
+	}
+
+	// Re-authenticate against the identity file's passphrase
+	if c.identity.IsEncrypted() {
+		check := &identity.Identity{
+			PublicKey:         c.identity.PublicKey,
+			Ncryptsec:         c.identity.Ncryptsec,
+			EncryptedMnemonic: c.identity.EncryptedMnemonic,
+		}
+		if err := check.Unlock(passphrase); err != nil {
+			return "", err
+		}
+		return check.Mnemonic, nil
+	}
+
+	return c.identity.Mnemonic, nil
+}
+
+// RestoreFromMnemonic replaces the identity with the one derived from a mnemonic (NIP-06)
+// The identity file is overwritten, so back up the current identity first.
+// The message subscription is re-established for the restored public key
+//
+// Parameters:
+//   - mnemonic: BIP-39 words (12 or 24)
+//   - account: account index (0 for the first account)
+//   - passphrase: passphrase to encrypt the identity file with (empty for plaintext)
+//
+// Returns:
+//   - error: identity.ErrInvalidMnemonic, identity.ErrPassphraseRequired, or error if any
+func (c *Client) RestoreFromMnemonic(mnemonic string, account uint32, passphrase string) error {
+	if c.IsUsingBunker() {
+		return fmt.Errorf("disconnect the remote signer before restoring")
+	}
+
+	// Derive first, so an invalid mnemonic never touches the identity file
+	if _, err := identity.IdentityFromMnemonic(mnemonic, account); err != nil {
+		return err
+	}
+
+	ident, err := identity.RestoreIdentity(c.identityPath, mnemonic, account, passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to restore identity: %w", err)
+	}
+
+	localSigner, err := signer.NewLocalSigner(ident.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("failed to create signer: %w", err)
+	}
+
+	c.identity = ident
+	c.signer = localSigner
+
+	// The file now holds another identity, update its registry entry
+	if _, err := c.accounts.Register(c.identityPath, ""); err != nil {
+		return fmt.Errorf("failed to register identity: %w", err)
+	}
+
+	return c.resubscribe()
+}
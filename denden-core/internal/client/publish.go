@@ -0,0 +1,153 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"denden-core/internal/pow"
+	"denden-core/internal/relay"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// publishTimeout bounds sending a mined and signed event to the relays
+const publishTimeout = 10 * time.Second
+
+// relayInfoTimeout bounds fetching a relay's NIP-11 document
+const relayInfoTimeout = 7 * time.Second
+
+// NIP-11 fetch retries: the delay doubles after every failed attempt
+const (
+	relayInfoAttempts = 3
+	relayInfoBackoff  = 2 * time.Second
+)
+
+// PublishEvent publishes an event through the publish pipeline:
+// build (PubKey, CreatedAt) → mine (NIP-13, per-kind policy) → sign → publish → store
+// Every outgoing event goes through it, so relays that require PoW accept it
+//
+// Parameters:
+//   - ctx: context (canceling it stops mining)
+//   - event: unsigned event (Kind, Tags, Content set)
+//   - onProgress: receives mining progress (can be nil)
+//
+// Returns:
+//   - error: mining, signing or publish error
+func (c *Client) PublishEvent(ctx context.Context, event *nostr.Event, onProgress func(pow.Progress)) error {
+	if c.pool == nil {
+		return fmt.Errorf("not connected to any relay")
+	}
+
+	// Build: the signer's pubkey must be committed to by the PoW
+	event.PubKey = c.identity.PublicKey
+	if event.CreatedAt == 0 {
+		event.CreatedAt = nostr.Now()
+	}
+
+	if err := c.mine(ctx, event, onProgress, false); err != nil {
+		return fmt.Errorf("failed to mine event: %w", err)
+	}
+
+	signCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	if err := c.signer.SignEvent(signCtx, event); err != nil {
+		return fmt.Errorf("failed to sign event: %w", err)
+	}
+
+	publishCtx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+	if err := c.Publish(publishCtx, event); err != nil {
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
+
+	return nil
+}
+
+// mine adds the PoW the publish policy requires for the event's kind
+// fixedTimestamp keeps CreatedAt (NIP-59 gift wraps have randomized timestamps)
+func (c *Client) mine(ctx context.Context, event *nostr.Event, onProgress func(pow.Progress), fixedTimestamp bool) error {
+	difficulty := c.publishPolicy.Difficulty(event.Kind)
+	if difficulty <= 0 {
+		return nil
+	}
+
+	_, err := pow.Mine(ctx, event, difficulty, pow.Options{
+		OnProgress:     onProgress,
+		FixedTimestamp: fixedTimestamp,
+	})
+	return err
+}
+
+// GetPublishPolicy returns the PoW difficulty per kind used for outgoing events
+// (use it to override the difficulty of a kind)
+func (c *Client) GetPublishPolicy() *pow.Policy {
+	return c.publishPolicy
+}
+
+// GetPowAdvisor returns the advisor that picks outgoing PoW from the relays'
+// requirements and estimates mining time on this device
+func (c *Client) GetPowAdvisor() *pow.Advisor {
+	return c.powAdvisor
+}
+
+// EstimatePublish returns the difficulty an event of a kind will be mined with
+// and how long that is expected to take on this device
+// The device is benchmarked the first time (about pow.DefaultBenchmarkDuration)
+//
+// Parameters:
+//   - ctx: context (canceling it stops the benchmark)
+//   - kind: event kind
+//
+// Returns:
+//   - int: difficulty from the publish policy
+//   - time.Duration: expected mining time (on average)
+//   - error: error if the benchmark was interrupted
+func (c *Client) EstimatePublish(ctx context.Context, kind int) (int, time.Duration, error) {
+	difficulty := c.publishPolicy.Difficulty(kind)
+
+	estimate, err := c.powAdvisor.Estimate(ctx, difficulty)
+	if err != nil {
+		return difficulty, 0, fmt.Errorf("failed to benchmark device: %w", err)
+	}
+	return difficulty, estimate, nil
+}
+
+// refreshRelayRequirements fetches the NIP-11 PoW requirement of every relay
+// The relays are marked unknown first, so the advisor stays conservative
+// until every fetch has finished. A failed fetch is retried with backoff;
+// a relay that still doesn't answer (or has no NIP-11 document) is recorded
+// as requiring no PoW, so it doesn't keep the advisor conservative all session
+func (c *Client) refreshRelayRequirements(relayURLs []string) {
+	for _, url := range relayURLs {
+		c.powAdvisor.SetRelayUnknown(url)
+	}
+
+	for _, url := range relayURLs {
+		go func(url string) {
+			backoff := relayInfoBackoff
+			for attempt := 1; ; attempt++ {
+				ctx, cancel := context.WithTimeout(c.ctx, relayInfoTimeout)
+				difficulty, err := relay.FetchMinPowDifficulty(ctx, url)
+				cancel()
+				if err == nil {
+					c.powAdvisor.SetRelayRequirement(url, difficulty)
+					return
+				}
+
+				if attempt == relayInfoAttempts {
+					fmt.Printf("⚠️  No PoW requirement from %s, assuming none: %v\n", url, err)
+					c.powAdvisor.SetRelayRequirement(url, 0)
+					return
+				}
+
+				select {
+				case <-time.After(backoff):
+					backoff *= 2
+				case <-c.ctx.Done():
+					return
+				}
+			}
+		}(url)
+	}
+}
@@ -0,0 +1,79 @@
+package crypto
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr/nip04"
+)
+
+// Scheme identifies the encryption scheme of a direct message
+type Scheme string
+
+const (
+	SchemeNIP04 Scheme = "nip04" // Legacy AES-256-CBC ("<base64>?iv=<base64>")
+	SchemeNIP44 Scheme = "nip44" // ChaCha20 + HMAC-SHA256, versioned payload
+	SchemeNIP17 Scheme = "nip17" // NIP-44 rumor inside a NIP-59 gift wrap (Kind 1059)
+)
+
+// DetectScheme tells NIP-04 and NIP-44 ciphertexts apart
+// NIP-04 content always has the "?iv=" suffix, NIP-44 payloads are plain base64
+func DetectScheme(ciphertext string) Scheme {
+	if strings.Contains(ciphertext, "?iv=") {
+		return SchemeNIP04
+	}
+	return SchemeNIP44
+}
+
+// EncryptDM encrypts a direct message with the scheme Den Den sends (NIP-44)
+//
+// Parameters:
+//   - plaintext: The plaintext message to encrypt
+//   - senderPrivKey: The sender's private key (hex string)
+//   - recipientPubKey: The recipient's public key (hex string)
+//
+// Returns:
+//   - ciphertext: The encrypted message
+//   - error: Encryption error
+func EncryptDM(plaintext, senderPrivKey, recipientPubKey string) (string, error) {
+	return Encrypt(plaintext, senderPrivKey, recipientPubKey)
+}
+
+// DecryptDM decrypts a direct message in either scheme
+// The scheme is detected from the ciphertext format
+// Because ECDH is symmetric, the same call decrypts messages we sent:
+// pass our own private key and the partner's public key
+//
+// Parameters:
+//   - ciphertext: The encrypted message (NIP-04 or NIP-44)
+//   - privKey: Our private key (hex string)
+//   - partnerPubKey: The other party's public key (hex string)
+//
+// Returns:
+//   - plaintext: The decrypted message
+//   - Scheme: The scheme the message was encrypted with
+//   - error: Decryption error
+func DecryptDM(ciphertext, privKey, partnerPubKey string) (string, Scheme, error) {
+	scheme := DetectScheme(ciphertext)
+
+	switch scheme {
+	case SchemeNIP04:
+		sharedSecret, err := nip04.ComputeSharedSecret(partnerPubKey, privKey)
+		if err != nil {
+			return "", scheme, fmt.Errorf("Failed to compute shared secret: %w", err)
+		}
+
+		plaintext, err := nip04.Decrypt(ciphertext, sharedSecret)
+		if err != nil {
+			return "", scheme, fmt.Errorf("Failed to decrypt: %w", err)
+		}
+		return plaintext, scheme, nil
+
+	default:
+		plaintext, err := Decrypt(ciphertext, privKey, partnerPubKey)
+		if err != nil {
+			return "", scheme, err
+		}
+		return plaintext, scheme, nil
+	}
+}
@@ -0,0 +1,133 @@
+package crypto
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip59"
+)
+
+// Event kinds used by NIP-17 private direct messages
+const (
+	KindPrivateMessage = 14   // NIP-17 chat message (unsigned "rumor")
+	KindSeal           = 13   // NIP-59 seal: rumor encrypted and signed by the real sender
+	KindGiftWrap       = 1059 // NIP-59 gift wrap: seal encrypted and signed by a one-time key
+)
+
+// GiftWrap seals a rumor and gift-wraps it for one recipient (NIP-59)
+// Relays only ever see the ephemeral key, the recipient and a fake time
+// Layers:
+//  1. rumor (unsigned, e.g. Kind 14) is NIP-44 encrypted to the recipient
+//  2. seal (Kind 13) carries it, signed by the sender, with a randomized timestamp
+//  3. gift wrap (Kind 1059) carries the seal, signed by a fresh ephemeral key,
+//     with a randomized timestamp and only a "p" tag for the recipient
+//
+// Encryption and signing are passed in, so the sender's key can live in a
+// local or remote signer. The gift wrap is the only layer relays see, so it is
+// the one that carries the PoW: mine runs on it after the ephemeral pubkey is
+// set and before the ephemeral key signs it
+//
+// Parameters:
+//   - rumor: the unsigned inner event (PubKey must be the sender)
+//   - recipientPubKey: The recipient's public key (hex string)
+//   - encrypt: NIP-44 encrypts plaintext from the sender to the recipient
+//   - sign: signs the seal as the sender
+//   - mine: adds PoW to the gift wrap, keeping its CreatedAt (nil for none)
+//
+// Returns:
+//   - *nostr.Event: signed Kind 1059 gift wrap
+//   - error: Encryption, mining or signing error
+func GiftWrap(
+	rumor nostr.Event,
+	recipientPubKey string,
+	encrypt func(plaintext string) (string, error),
+	sign func(seal *nostr.Event) error,
+	mine func(wrap *nostr.Event) error,
+) (*nostr.Event, error) {
+	// The rumor must never be signed, otherwise it could be leaked as a valid event
+	rumor.Sig = ""
+	rumor.ID = rumor.GetID()
+
+	rumorCiphertext, err := encrypt(rumor.String())
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encrypt rumor: %w", err)
+	}
+
+	seal := nostr.Event{
+		Kind:      KindSeal,
+		Content:   rumorCiphertext,
+		CreatedAt: randomizedTimestamp(),
+		Tags:      nostr.Tags{},
+	}
+	if err := sign(&seal); err != nil {
+		return nil, fmt.Errorf("Failed to sign seal: %w", err)
+	}
+
+	// Fresh key for the wrap, never reused
+	ephemeralKey := nostr.GeneratePrivateKey()
+	ephemeralPubKey, err := nostr.GetPublicKey(ephemeralKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to derive ephemeral key: %w", err)
+	}
+
+	sealCiphertext, err := Encrypt(seal.String(), ephemeralKey, recipientPubKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encrypt seal: %w", err)
+	}
+
+	wrap := &nostr.Event{
+		PubKey:    ephemeralPubKey,
+		Kind:      KindGiftWrap,
+		Content:   sealCiphertext,
+		CreatedAt: randomizedTimestamp(),
+		Tags: nostr.Tags{
+			{"p", recipientPubKey},
+		},
+	}
+
+	if mine != nil {
+		if err := mine(wrap); err != nil {
+			return nil, fmt.Errorf("Failed to mine gift wrap: %w", err)
+		}
+	}
+
+	if err := wrap.Sign(ephemeralKey); err != nil {
+		return nil, fmt.Errorf("Failed to sign gift wrap: %w", err)
+	}
+
+	return wrap, nil
+}
+
+// randomizedTimestamp returns a time up to two days in the past (NIP-59),
+// so the seal and gift wrap don't reveal when the message was sent
+func randomizedTimestamp() nostr.Timestamp {
+	return nostr.Now() - nostr.Timestamp(rand.Int63n(2*24*60*60))
+}
+
+// GiftUnwrap opens a Kind 1059 gift wrap addressed to us and returns the rumor
+// The rumor's PubKey is taken from the seal signature, so a sender can't
+// impersonate someone else by writing a different pubkey into the rumor
+//
+// Parameters:
+//   - wrap: Kind 1059 gift wrap
+//   - decrypt: NIP-44 decrypts ciphertext from otherPubKey with our key
+//
+// Returns:
+//   - *nostr.Event: the unsigned rumor with PubKey set to the real sender
+//   - error: Decryption or validation error
+func GiftUnwrap(
+	wrap *nostr.Event,
+	decrypt func(otherPubKey, ciphertext string) (string, error),
+) (*nostr.Event, error) {
+	if wrap.Kind != KindGiftWrap {
+		return nil, fmt.Errorf("Not a gift wrap (kind %d)", wrap.Kind)
+	}
+
+	rumor, err := nip59.GiftUnwrap(*wrap, decrypt)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unwrap: %w", err)
+	}
+
+	return &rumor, nil
+}
@@ -0,0 +1,250 @@
+package geo
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// base32 is the geohash alphabet (no a, i, l, o)
+const base32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// MaxPrecision is the longest geohash handled (about 3.7cm x 1.9cm cells)
+const MaxPrecision = 12
+
+// earthRadiusKm is the mean Earth radius used for distances
+const earthRadiusKm = 6371.0
+
+// ErrInvalidGeohash is returned for empty, too long, or non-base32 geohashes
+var ErrInvalidGeohash = errors.New("invalid geohash")
+
+// Cell is the area a geohash covers
+type Cell struct {
+	Geohash string
+	MinLat  float64
+	MaxLat  float64
+	MinLon  float64
+	MaxLon  float64
+}
+
+// Center returns the midpoint of the cell
+func (c Cell) Center() (lat, lon float64) {
+	return (c.MinLat + c.MaxLat) / 2, (c.MinLon + c.MaxLon) / 2
+}
+
+// SizeKm returns the approximate height and width of the cell
+// (the width shrinks away from the equator)
+func (c Cell) SizeKm() (height, width float64) {
+	lat, _ := c.Center()
+	height = (c.MaxLat - c.MinLat) * kmPerDegree()
+	width = (c.MaxLon - c.MinLon) * kmPerDegree() * math.Cos(lat*math.Pi/180)
+	return height, width
+}
+
+// RadiusKm returns the distance from the cell's center to its corners
+func (c Cell) RadiusKm() float64 {
+	lat, lon := c.Center()
+	return Distance(lat, lon, c.MaxLat, c.MaxLon)
+}
+
+// Encode returns the geohash of a point
+// Parameters:
+//   - lat: latitude (-90 to 90)
+//   - lon: longitude (-180 to 180)
+//   - precision: number of characters (1 to MaxPrecision)
+func Encode(lat, lon float64, precision int) string {
+	precision = max(1, min(precision, MaxPrecision))
+
+	minLat, maxLat := -90.0, 90.0
+	minLon, maxLon := -180.0, 180.0
+
+	var hash strings.Builder
+	bit, ch := 0, 0
+	even := true // Bits alternate longitude, latitude, starting with longitude
+
+	for hash.Len() < precision {
+		if even {
+			mid := (minLon + maxLon) / 2
+			if lon >= mid {
+				ch = ch<<1 | 1
+				minLon = mid
+			} else {
+				ch <<= 1
+				maxLon = mid
+			}
+		} else {
+			mid := (minLat + maxLat) / 2
+			if lat >= mid {
+				ch = ch<<1 | 1
+				minLat = mid
+			} else {
+				ch <<= 1
+				maxLat = mid
+			}
+		}
+		even = !even
+
+		bit++
+		if bit == 5 {
+			hash.WriteByte(base32[ch])
+			bit, ch = 0, 0
+		}
+	}
+
+	return hash.String()
+}
+
+// Decode returns the cell a geohash covers
+// Geohashes are case-insensitive
+func Decode(geohash string) (Cell, error) {
+	geohash = strings.ToLower(geohash)
+	if geohash == "" || len(geohash) > MaxPrecision {
+		return Cell{}, ErrInvalidGeohash
+	}
+
+	minLat, maxLat := -90.0, 90.0
+	minLon, maxLon := -180.0, 180.0
+	even := true
+
+	for i := 0; i < len(geohash); i++ {
+		value := strings.IndexByte(base32, geohash[i])
+		if value < 0 {
+			return Cell{}, ErrInvalidGeohash
+		}
+
+		for mask := 16; mask > 0; mask >>= 1 {
+			if even {
+				mid := (minLon + maxLon) / 2
+				if value&mask != 0 {
+					minLon = mid
+				} else {
+					maxLon = mid
+				}
+			} else {
+				mid := (minLat + maxLat) / 2
+				if value&mask != 0 {
+					minLat = mid
+				} else {
+					maxLat = mid
+				}
+			}
+			even = !even
+		}
+	}
+
+	return Cell{
+		Geohash: geohash,
+		MinLat:  minLat,
+		MaxLat:  maxLat,
+		MinLon:  minLon,
+		MaxLon:  maxLon,
+	}, nil
+}
+
+// Neighbors returns the geohashes of the (up to) 8 cells around a geohash,
+// at the same precision. Longitude wraps around; there are no cells past the poles
+func Neighbors(geohash string) ([]string, error) {
+	cell, err := Decode(geohash)
+	if err != nil {
+		return nil, err
+	}
+
+	lat, lon := cell.Center()
+	dLat := cell.MaxLat - cell.MinLat
+	dLon := cell.MaxLon - cell.MinLon
+
+	var neighbors []string
+	seen := map[string]bool{cell.Geohash: true}
+
+	for _, dy := range []float64{1, 0, -1} {
+		for _, dx := range []float64{-1, 0, 1} {
+			nLat := lat + dy*dLat
+			if nLat > 90 || nLat < -90 {
+				continue
+			}
+			nLon := math.Mod(lon+dx*dLon+540, 360) - 180
+
+			hash := Encode(nLat, nLon, len(cell.Geohash))
+			if !seen[hash] {
+				seen[hash] = true
+				neighbors = append(neighbors, hash)
+			}
+		}
+	}
+
+	return neighbors, nil
+}
+
+// Cover returns the geohashes of the cells that could hold a point within
+// radiusKm of a location: the location's cell and rings of neighbors around it
+// Parameters:
+//   - lat, lon: the location
+//   - radiusKm: how far from the location to look
+//   - precision: geohash length of the cells (1 to MaxPrecision)
+//   - maxCells: the most cells to return
+//
+// Returns:
+//   - []string: the cells, the location's first (nil if more than maxCells are needed)
+func Cover(lat, lon, radiusKm float64, precision, maxCells int) []string {
+	first := Encode(lat, lon, precision)
+
+	// The cells touching a circle are connected, so the rings grow from the
+	// cells already in until a whole ring falls outside
+	cells := []string{first}
+	seen := map[string]bool{first: true}
+	for i := 0; i < len(cells); i++ {
+		neighbors, _ := Neighbors(cells[i])
+		for _, hash := range neighbors {
+			if seen[hash] {
+				continue
+			}
+			seen[hash] = true
+
+			cell, _ := Decode(hash)
+			cellLat, cellLon := cell.Center()
+			if Distance(lat, lon, cellLat, cellLon) > radiusKm+cell.RadiusKm() {
+				continue
+			}
+			if len(cells) == maxCells {
+				return nil
+			}
+			cells = append(cells, hash)
+		}
+	}
+
+	return cells
+}
+
+// PrecisionForRadius returns the longest precision (up to maxPrecision) whose
+// cells are at least radiusKm tall and wide at a latitude, so a cell and its
+// neighbors cover every point within radiusKm of anywhere in the center cell
+func PrecisionForRadius(lat float64, radiusKm float64, maxPrecision int) int {
+	maxPrecision = max(1, min(maxPrecision, MaxPrecision))
+
+	for precision := maxPrecision; precision > 1; precision-- {
+		cell, err := Decode(Encode(lat, 0, precision))
+		if err != nil {
+			continue
+		}
+		if height, width := cell.SizeKm(); min(height, width) >= radiusKm {
+			return precision
+		}
+	}
+	return 1
+}
+
+// Distance returns the great-circle distance between two points in km (haversine)
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := math.Pi / 180
+	dLat := (lat2 - lat1) * toRad
+	dLon := (lon2 - lon1) * toRad
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*toRad)*math.Cos(lat2*toRad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// kmPerDegree is the length of one degree of latitude
+func kmPerDegree() float64 {
+	return earthRadiusKm * math.Pi / 180
+}
@@ -0,0 +1,372 @@
+package identity
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/nbd-wtf/go-nostr/nip19"
+)
+
+// registryFile is the account registry's file name, next to identity.json
+const registryFile = "accounts.json"
+
+// ErrAccountNotFound is returned when an account isn't in the registry
+var ErrAccountNotFound = errors.New("account not found")
+
+// Account is an identity registered on this device
+type Account struct {
+	PublicKey string `json:"public_key"`      // Public key in hex format
+	Npub      string `json:"npub"`            // Public key in Bech32 format (npub1...)
+	Label     string `json:"label,omitempty"` // Name shown in account pickers (e.g. "personal")
+	Path      string `json:"path"`            // Identity file, relative to the registry directory
+}
+
+// Registry keeps track of the identities stored in a directory (~/.denden/accounts.json)
+// The first identity lives at identity.json, the others under accounts/<pubkey>/identity.json,
+// so each one keeps its own bunker session next to it
+type Registry struct {
+	dir      string
+	mu       sync.Mutex
+	active   string    // Public key of the active account
+	accounts []Account // Registered accounts, in the order they were added
+}
+
+// registryData is the JSON layout of accounts.json
+type registryData struct {
+	Active   string    `json:"active"`
+	Accounts []Account `json:"accounts"`
+}
+
+// OpenRegistry loads the account registry in a directory
+// A missing registry starts empty, and an existing identity.json is registered
+// as the first account, so single-identity installs keep working
+//
+// Parameters:
+//   - dir: directory holding the identity files (e.g. ~/.denden)
+//
+// Returns:
+//   - *Registry: loaded registry
+//   - error: error if the registry file is invalid
+func OpenRegistry(dir string) (*Registry, error) {
+	r := &Registry{dir: dir}
+
+	data, err := os.ReadFile(filepath.Join(dir, registryFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read account registry: %w", err)
+	}
+	if err == nil {
+		var stored registryData
+		if err := json.Unmarshal(data, &stored); err != nil {
+			return nil, fmt.Errorf("failed to parse account registry: %w", err)
+		}
+		r.active = stored.Active
+		r.accounts = stored.Accounts
+	}
+
+	// Adopt an identity created before the registry existed
+	if len(r.accounts) == 0 {
+		defaultPath := filepath.Join(dir, "identity.json")
+		if _, err := os.Stat(defaultPath); err == nil {
+			if _, err := r.Register(defaultPath, ""); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return r, nil
+}
+
+// ResolveIdentityPath returns the identity file of the active account registered
+// in the same directory as identityPath, or identityPath itself if there is none
+func ResolveIdentityPath(identityPath string) string {
+	r, err := OpenRegistry(filepath.Dir(identityPath))
+	if err != nil {
+		return identityPath
+	}
+
+	active, ok := r.Active()
+	if !ok {
+		return identityPath
+	}
+	return r.Path(active)
+}
+
+// List returns the registered accounts
+func (r *Registry) List() []Account {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	accounts := make([]Account, len(r.accounts))
+	copy(accounts, r.accounts)
+	return accounts
+}
+
+// Active returns the active account (false if the registry is empty)
+func (r *Registry) Active() (Account, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if i := r.find(r.active); i >= 0 {
+		return r.accounts[i], true
+	}
+	return Account{}, false
+}
+
+// Get returns an account by public key (hex or npub)
+func (r *Registry) Get(pubKey string) (Account, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if i := r.find(pubKey); i >= 0 {
+		return r.accounts[i], true
+	}
+	return Account{}, false
+}
+
+// Path returns the absolute path of an account's identity file
+func (r *Registry) Path(account Account) string {
+	if filepath.IsAbs(account.Path) {
+		return account.Path
+	}
+	return filepath.Join(r.dir, account.Path)
+}
+
+// Register adds an existing identity file to the registry and makes it active
+// Registering a file twice only makes it active again; if the file now holds
+// another identity, the old entry is replaced
+//
+// Parameters:
+//   - identityPath: path to the identity file (may be encrypted)
+//   - label: name for the account (may be empty)
+//
+// Returns:
+//   - Account: registered account
+//   - error: error if the file can't be read or the registry can't be saved
+func (r *Registry) Register(identityPath, label string) (Account, error) {
+	ident, err := LoadIdentity(identityPath)
+	if err != nil {
+		return Account{}, err
+	}
+
+	path := identityPath
+	if rel, err := filepath.Rel(r.dir, identityPath); err == nil && !strings.HasPrefix(rel, "..") {
+		path = rel
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// The file was overwritten with another identity (e.g. restored from a mnemonic)
+	for i, existing := range r.accounts {
+		if existing.Path == path && existing.PublicKey != ident.PublicKey {
+			if label == "" {
+				label = existing.Label
+			}
+			r.accounts = append(r.accounts[:i], r.accounts[i+1:]...)
+			break
+		}
+	}
+
+	if i := r.find(ident.PublicKey); i >= 0 {
+		r.active = ident.PublicKey
+		return r.accounts[i], r.save()
+	}
+
+	account := Account{
+		PublicKey: ident.PublicKey,
+		Npub:      ident.Npub,
+		Label:     label,
+		Path:      path,
+	}
+	r.accounts = append(r.accounts, account)
+	r.active = account.PublicKey
+
+	return account, r.save()
+}
+
+// Generate creates a new identity (from a new mnemonic) and registers it
+// The active account doesn't change, use SetActive to switch to it
+//
+// Parameters:
+//   - label: name for the account (may be empty)
+//   - passphrase: passphrase to encrypt the identity file with (empty for plaintext)
+//
+// Returns:
+//   - *Identity: the new, unlocked identity
+//   - error: ErrPassphraseRequired, or error if any
+func (r *Registry) Generate(label, passphrase string) (*Identity, error) {
+	ident, err := newIdentity()
+	if err != nil {
+		return nil, err
+	}
+	return ident, r.add(ident, label, passphrase)
+}
+
+// ImportNsec registers an identity from an existing private key (nsec1...)
+// The active account doesn't change, use SetActive to switch to it
+//
+// Parameters:
+//   - label: name for the account (may be empty)
+//   - nsec: private key in Bech32 format
+//   - passphrase: passphrase to encrypt the identity file with (empty for plaintext)
+//
+// Returns:
+//   - *Identity: the imported, unlocked identity
+//   - error: error if the key is invalid or already registered
+func (r *Registry) ImportNsec(label, nsec, passphrase string) (*Identity, error) {
+	privKey, err := DecodePrivateKey(strings.TrimSpace(nsec))
+	if err != nil {
+		return nil, err
+	}
+
+	pubKey, err := GetPublicKeyFromPrivate(privKey)
+	if err != nil {
+		return nil, err
+	}
+
+	npub, err := nip19.EncodePublicKey(pubKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+
+	ident := &Identity{
+		PrivateKey: privKey,
+		PublicKey:  pubKey,
+		Nsec:       strings.TrimSpace(nsec),
+		Npub:       npub,
+	}
+
+	return ident, r.add(ident, label, passphrase)
+}
+
+// Remove deletes an account's identity file and unregisters it
+// The active account can't be removed, switch to another one first.
+// Without a backup (mnemonic or ncryptsec) the identity is lost for good
+func (r *Registry) Remove(pubKey string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := r.find(pubKey)
+	if i < 0 {
+		return ErrAccountNotFound
+	}
+
+	account := r.accounts[i]
+	if account.PublicKey == r.active {
+		return fmt.Errorf("can't remove the active account, switch to another one first")
+	}
+
+	path := r.Path(account)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove identity file: %w", err)
+	}
+
+	// Accounts added through the registry have a directory of their own
+	// (with their bunker session), remove it with them
+	if filepath.Dir(path) == filepath.Join(r.dir, "accounts", account.PublicKey) {
+		os.RemoveAll(filepath.Dir(path))
+	} else {
+		os.Remove(filepath.Join(filepath.Dir(path), "bunker.json"))
+	}
+
+	r.accounts = append(r.accounts[:i], r.accounts[i+1:]...)
+	return r.save()
+}
+
+// SetActive makes an account the active one, used by the next client that starts
+func (r *Registry) SetActive(pubKey string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := r.find(pubKey)
+	if i < 0 {
+		return ErrAccountNotFound
+	}
+
+	r.active = r.accounts[i].PublicKey
+	return r.save()
+}
+
+// add saves a new identity under accounts/<pubkey>/ and registers it
+func (r *Registry) add(ident *Identity, label, passphrase string) error {
+	if passphrase == "" && passphrasePolicy == PassphraseRequired {
+		return fmt.Errorf("refusing to create a plaintext identity: %w", ErrPassphraseRequired)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.find(ident.PublicKey) >= 0 {
+		return fmt.Errorf("account %s is already registered", ident.Npub)
+	}
+
+	path := filepath.Join("accounts", ident.PublicKey, "identity.json")
+	fullPath := filepath.Join(r.dir, path)
+
+	var err error
+	if passphrase != "" {
+		err = SaveEncryptedIdentity(ident, fullPath, passphrase)
+	} else {
+		err = SaveIdentity(ident, fullPath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save identity: %w", err)
+	}
+
+	r.accounts = append(r.accounts, Account{
+		PublicKey: ident.PublicKey,
+		Npub:      ident.Npub,
+		Label:     label,
+		Path:      path,
+	})
+
+	// The first account becomes active
+	if r.active == "" {
+		r.active = ident.PublicKey
+	}
+
+	return r.save()
+}
+
+// find returns the index of an account by public key (hex or npub), or -1
+// Must be called with the lock held
+func (r *Registry) find(pubKey string) int {
+	if pubKey == "" {
+		return -1
+	}
+	for i, account := range r.accounts {
+		if account.PublicKey == pubKey || account.Npub == pubKey {
+			return i
+		}
+	}
+	return -1
+}
+
+// save writes the registry to accounts.json
+// Must be called with the lock held
+func (r *Registry) save() error {
+	if err := os.MkdirAll(r.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(registryData{Active: r.active, Accounts: r.accounts}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal account registry: %w", err)
+	}
+
+	path := filepath.Join(r.dir, registryFile)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write account registry: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write account registry: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package identity
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/nbd-wtf/go-nostr/nip44"
+	"github.com/tyler-smith/go-bip32"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// DefaultMnemonicWords is the length of the mnemonic generated for new identities
+const DefaultMnemonicWords = 12
+
+var (
+	// ErrInvalidMnemonic is returned when the words aren't a valid BIP-39 mnemonic
+	// (unknown word, wrong length or bad checksum)
+	ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+	// ErrNoMnemonic is returned when a backup is requested for an identity that
+	// wasn't derived from a mnemonic (e.g. imported from an nsec)
+	ErrNoMnemonic = errors.New("identity has no mnemonic")
+)
+
+// GenerateMnemonic generates a new BIP-39 mnemonic (NIP-06)
+// Parameters:
+//   - wordCount: 12 (128 bits of entropy) or 24 (256 bits)
+//
+// Returns:
+//   - string: space separated English words
+//   - error: error if the word count is not supported
+func GenerateMnemonic(wordCount int) (string, error) {
+	var bits int
+	switch wordCount {
+	case 12:
+		bits = 128
+	case 24:
+		bits = 256
+	default:
+		return "", fmt.Errorf("unsupported mnemonic length %d, use 12 or 24 words", wordCount)
+	}
+
+	entropy, err := bip39.NewEntropy(bits)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate entropy: %w", err)
+	}
+
+	mnemonic, err := bip39.NewMnemonic(entropy)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate mnemonic: %w", err)
+	}
+	return mnemonic, nil
+}
+
+// NormalizeMnemonic lowercases the words and collapses whitespace,
+// so words written down by hand can be typed back in any way
+func NormalizeMnemonic(mnemonic string) string {
+	return strings.Join(strings.Fields(strings.ToLower(mnemonic)), " ")
+}
+
+// ValidateMnemonic reports whether the words are a valid BIP-39 mnemonic
+func ValidateMnemonic(mnemonic string) bool {
+	return bip39.IsMnemonicValid(NormalizeMnemonic(mnemonic))
+}
+
+// PrivateKeyFromMnemonic derives the private key for an account (NIP-06)
+// Derivation path: m/44'/1237'/<account>'/0/0
+//
+// Parameters:
+//   - mnemonic: BIP-39 words
+//   - account: account index (0 for the first account)
+//
+// Returns:
+//   - string: private key in hex format
+//   - error: ErrInvalidMnemonic, or error if derivation fails
+func PrivateKeyFromMnemonic(mnemonic string, account uint32) (string, error) {
+	mnemonic = NormalizeMnemonic(mnemonic)
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return "", ErrInvalidMnemonic
+	}
+	if account >= bip32.FirstHardenedChild {
+		return "", fmt.Errorf("invalid account index %d", account)
+	}
+
+	// No BIP-39 passphrase, so the words alone are enough to restore
+	seed := bip39.NewSeed(mnemonic, "")
+
+	key, err := bip32.NewMasterKey(seed)
+	if err != nil {
+		return "", fmt.Errorf("failed to derive master key: %w", err)
+	}
+
+	path := []uint32{
+		bip32.FirstHardenedChild + 44,
+		bip32.FirstHardenedChild + 1237,
+		bip32.FirstHardenedChild + account,
+		0,
+		0,
+	}
+	for _, index := range path {
+		key, err = key.NewChildKey(index)
+		if err != nil {
+			return "", fmt.Errorf("failed to derive key: %w", err)
+		}
+	}
+
+	return hex.EncodeToString(key.Key), nil
+}
+
+// IdentityFromMnemonic derives the identity for an account of a mnemonic (NIP-06)
+// Parameters:
+//   - mnemonic: BIP-39 words
+//   - account: account index (0 for the first account)
+//
+// Returns:
+//   - *Identity: unlocked identity that remembers its mnemonic and account
+//   - error: ErrInvalidMnemonic, or error if derivation fails
+func IdentityFromMnemonic(mnemonic string, account uint32) (*Identity, error) {
+	privKey, err := PrivateKeyFromMnemonic(mnemonic, account)
+	if err != nil {
+		return nil, err
+	}
+
+	pubKey, err := GetPublicKeyFromPrivate(privKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nsec, err := nip19.EncodePrivateKey(privKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+
+	npub, err := nip19.EncodePublicKey(pubKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+
+	return &Identity{
+		PrivateKey: privKey,
+		PublicKey:  pubKey,
+		Nsec:       nsec,
+		Npub:       npub,
+		Mnemonic:   NormalizeMnemonic(mnemonic),
+		Account:    account,
+	}, nil
+}
+
+// RestoreIdentity derives an identity from a mnemonic and saves it,
+// replacing any identity file at the given path
+// Parameters:
+//   - filePath: path to the identity file
+//   - mnemonic: BIP-39 words
+//   - account: account index (0 for the first account)
+//   - passphrase: passphrase to encrypt the file with (empty for plaintext)
+//
+// Returns:
+//   - *Identity: restored identity
+//   - error: ErrInvalidMnemonic, ErrPassphraseRequired, or write error
+func RestoreIdentity(filePath, mnemonic string, account uint32, passphrase string) (*Identity, error) {
+	if passphrase == "" && passphrasePolicy == PassphraseRequired {
+		return nil, fmt.Errorf("refusing to save a plaintext identity: %w", ErrPassphraseRequired)
+	}
+
+	identity, err := IdentityFromMnemonic(mnemonic, account)
+	if err != nil {
+		return nil, err
+	}
+
+	if passphrase != "" {
+		err = SaveEncryptedIdentity(identity, filePath, passphrase)
+	} else {
+		err = SaveIdentity(identity, filePath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save identity: %w", err)
+	}
+
+	return identity, nil
+}
+
+// HasMnemonic reports whether the identity was derived from a mnemonic
+// For encrypted identities this is known before unlocking
+func (i *Identity) HasMnemonic() bool {
+	return i.Mnemonic != "" || i.EncryptedMnemonic != ""
+}
+
+// encryptMnemonic encrypts the mnemonic to the identity's own key (NIP-44),
+// so an encrypted identity file never holds the words in plaintext
+func encryptMnemonic(mnemonic, privKey, pubKey string) (string, error) {
+	conversationKey, err := nip44.GenerateConversationKey(pubKey, privKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate conversation key: %w", err)
+	}
+	return nip44.Encrypt(mnemonic, conversationKey)
+}
+
+// decryptMnemonic decrypts a mnemonic encrypted with encryptMnemonic
+func decryptMnemonic(ciphertext, privKey, pubKey string) (string, error) {
+	conversationKey, err := nip44.GenerateConversationKey(pubKey, privKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate conversation key: %w", err)
+	}
+	return nip44.Decrypt(ciphertext, conversationKey)
+}
+
+// newIdentityFromMnemonic generates a new mnemonic and derives account 0 from it
+func newIdentityFromMnemonic() (*Identity, error) {
+	mnemonic, err := GenerateMnemonic(DefaultMnemonicWords)
+	if err != nil {
+		return nil, err
+	}
+	return IdentityFromMnemonic(mnemonic, 0)
+}
@@ -0,0 +1,152 @@
+package identity
+
+import (
+	"errors"
+	"testing"
+)
+
+// Test vectors from NIP-06 (m/44'/1237'/0'/0/0)
+var nip06Vectors = []struct {
+	name     string
+	mnemonic string
+	privKey  string
+	pubKey   string
+	nsec     string
+	npub     string
+}{
+	{
+		name:     "12 words",
+		mnemonic: "leader monkey parrot ring guide accident before fence cannon height naive bean",
+		privKey:  "7f7ff03d123792d6ac594bfa67bf6d0c0ab55b6b1fdb6249303fe861f1ccba9a",
+		pubKey:   "17162c921dc4d2518f9a101db33695df1afb56ab82f5ff3e5da6eec3ca5cd917",
+		nsec:     "nsec10allq0gjx7fddtzef0ax00mdps9t2kmtrldkyjfs8l5xruwvh2dq0lhhkp",
+		npub:     "npub1zutzeysacnf9rru6zqwmxd54mud0k44tst6l70ja5mhv8jjumytsd2x7nu",
+	},
+	{
+		name:     "24 words",
+		mnemonic: "what bleak badge arrange retreat wolf trade produce cricket blur garlic valid proud rude strong choose busy staff weather area salt hollow arm fade",
+		privKey:  "c15d739894c81a2fcfd3a2df85a0d2c0dbc47a280d092799f144d73d7ae78add",
+		pubKey:   "d41b22899549e1f3d335a31002cfd382174006e166d3e658e3a5eecdb6463573",
+		nsec:     "nsec1c9wh8xy5eqdzln7n5t0ctgxjcrdug73gp5yj0x03gntn67h83twssdfhel",
+		npub:     "npub16sdj9zv4f8sl85e45vgq9n7nsgt5qphpvmf7vk8r5hhvmdjxx4es8rq74h",
+	},
+}
+
+func TestPrivateKeyFromMnemonicNIP06Vectors(t *testing.T) {
+	for _, tt := range nip06Vectors {
+		t.Run(tt.name, func(t *testing.T) {
+			privKey, err := PrivateKeyFromMnemonic(tt.mnemonic, 0)
+			if err != nil {
+				t.Fatalf("PrivateKeyFromMnemonic: %v", err)
+			}
+			if privKey != tt.privKey {
+				t.Errorf("private key = %s, want %s", privKey, tt.privKey)
+			}
+
+			identity, err := IdentityFromMnemonic(tt.mnemonic, 0)
+			if err != nil {
+				t.Fatalf("IdentityFromMnemonic: %v", err)
+			}
+			if identity.PublicKey != tt.pubKey {
+				t.Errorf("public key = %s, want %s", identity.PublicKey, tt.pubKey)
+			}
+			if identity.Nsec != tt.nsec {
+				t.Errorf("nsec = %s, want %s", identity.Nsec, tt.nsec)
+			}
+			if identity.Npub != tt.npub {
+				t.Errorf("npub = %s, want %s", identity.Npub, tt.npub)
+			}
+			if identity.Mnemonic != tt.mnemonic || identity.Account != 0 {
+				t.Errorf("identity doesn't remember its mnemonic and account")
+			}
+		})
+	}
+}
+
+func TestPrivateKeyFromMnemonicAccounts(t *testing.T) {
+	mnemonic := nip06Vectors[0].mnemonic
+
+	tests := []struct {
+		name    string
+		account uint32
+		want    string
+	}{
+		{"account 0", 0, nip06Vectors[0].privKey},
+		// m/44'/1237'/<account>'/0/0, checked against an independent BIP-32 derivation
+		{"account 1", 1, "3790c23940f62b23754115ef70f16e63cca8e9015a532b8a891171ccdadcf910"},
+		{"account 5", 5, "71ac6050f6974298caaccd411e39c43ce684d2b3dfb842cbccdf545c1cbad64e"},
+	}
+
+	keys := make(map[string]uint32)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			privKey, err := PrivateKeyFromMnemonic(mnemonic, tt.account)
+			if err != nil {
+				t.Fatalf("PrivateKeyFromMnemonic: %v", err)
+			}
+			if privKey != tt.want {
+				t.Errorf("private key = %s, want %s", privKey, tt.want)
+			}
+			if other, seen := keys[privKey]; seen {
+				t.Errorf("accounts %d and %d derive the same key", other, tt.account)
+			}
+			keys[privKey] = tt.account
+
+			// Derivation is deterministic, and the identity keeps the account
+			again, _ := PrivateKeyFromMnemonic(mnemonic, tt.account)
+			if again != privKey {
+				t.Errorf("derivation isn't deterministic")
+			}
+			identity, err := IdentityFromMnemonic(mnemonic, tt.account)
+			if err != nil {
+				t.Fatalf("IdentityFromMnemonic: %v", err)
+			}
+			if identity.PrivateKey != privKey || identity.Account != tt.account {
+				t.Errorf("identity = (%s, %d), want (%s, %d)", identity.PrivateKey, identity.Account, privKey, tt.account)
+			}
+		})
+	}
+}
+
+func TestPrivateKeyFromMnemonicInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		mnemonic string
+		account  uint32
+		want     error // nil: any error
+	}{
+		{"bad checksum", "leader monkey parrot ring guide accident before fence cannon height naive abandon", 0, ErrInvalidMnemonic},
+		{"words swapped", "monkey leader parrot ring guide accident before fence cannon height naive bean", 0, ErrInvalidMnemonic},
+		{"unknown word", "leader monkey parrot ring guide accident before fence cannon height naive beanz", 0, ErrInvalidMnemonic},
+		{"too short", "leader monkey parrot ring guide accident before fence cannon height naive", 0, ErrInvalidMnemonic},
+		{"empty", "", 0, ErrInvalidMnemonic},
+		{"hardened account index", nip06Vectors[0].mnemonic, 1 << 31, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			privKey, err := PrivateKeyFromMnemonic(tt.mnemonic, tt.account)
+			if err == nil {
+				t.Fatalf("PrivateKeyFromMnemonic accepted it and derived %s", privKey)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+			if ValidateMnemonic(tt.mnemonic) && tt.want != nil {
+				t.Errorf("ValidateMnemonic = true")
+			}
+		})
+	}
+}
+
+func TestPrivateKeyFromMnemonicNormalizes(t *testing.T) {
+	messy := "  Leader MONKEY parrot\tring guide accident\nbefore fence cannon height naive bean "
+
+	privKey, err := PrivateKeyFromMnemonic(messy, 0)
+	if err != nil {
+		t.Fatalf("PrivateKeyFromMnemonic: %v", err)
+	}
+	if privKey != nip06Vectors[0].privKey {
+		t.Errorf("private key = %s, want %s", privKey, nip06Vectors[0].privKey)
+	}
+}
@@ -0,0 +1,232 @@
+package identity
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/nbd-wtf/go-nostr/nip49"
+)
+
+// ScryptLogN is the scrypt cost for newly encrypted keys (2^16 rounds, ~64 MiB)
+// NIP-49 recommends at least 16; higher values make unlocking slow on phones
+const ScryptLogN = 16
+
+var (
+	// ErrPassphraseRequired is returned when an encrypted identity is loaded without a passphrase,
+	// or when the passphrase policy forbids creating a plaintext identity
+	ErrPassphraseRequired = errors.New("passphrase required")
+
+	// ErrWrongPassphrase is returned when an ncryptsec key can't be decrypted with the given passphrase
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+
+	// ErrNotEncrypted is returned when a passphrase change is requested for a plaintext identity
+	ErrNotEncrypted = errors.New("identity is not encrypted")
+)
+
+// PassphrasePolicy controls whether identities may be stored in plaintext
+type PassphrasePolicy int
+
+const (
+	PassphraseOptional PassphrasePolicy = iota // Plaintext identity files may be created (default)
+	PassphraseRequired                         // New identities must be encrypted with a passphrase
+)
+
+var passphrasePolicy = PassphraseOptional
+
+// SetPassphrasePolicy sets the policy used by EnsureIdentity
+func SetPassphrasePolicy(policy PassphrasePolicy) {
+	passphrasePolicy = policy
+}
+
+// GetPassphrasePolicy returns the policy used by EnsureIdentity
+func GetPassphrasePolicy() PassphrasePolicy {
+	return passphrasePolicy
+}
+
+// IsEncrypted reports whether the identity is stored as an ncryptsec key
+func (i *Identity) IsEncrypted() bool {
+	return i.Ncryptsec != ""
+}
+
+// IsLocked reports whether the private key is unavailable (encrypted and not yet unlocked)
+func (i *Identity) IsLocked() bool {
+	return i.PrivateKey == ""
+}
+
+// SaveEncryptedIdentity saves the identity with its private key encrypted (NIP-49)
+// The file only contains the ncryptsec key, the encrypted mnemonic and the public key,
+// never the hex key, nsec or plaintext mnemonic
+//
+// Parameters:
+//   - identity: the identity to save (must be unlocked)
+//   - filePath: path to the JSON file
+//   - passphrase: passphrase to encrypt the private key with
+//
+// Returns:
+//   - error: error if encryption or writing fails
+func SaveEncryptedIdentity(identity *Identity, filePath, passphrase string) error {
+	if identity.IsLocked() {
+		return fmt.Errorf("identity is locked, can't re-encrypt it")
+	}
+	if passphrase == "" {
+		return ErrPassphraseRequired
+	}
+
+	ncryptsec, err := nip49.Encrypt(identity.PrivateKey, passphrase, ScryptLogN, nip49.ClientDoesNotTrackThisData)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt private key: %w", err)
+	}
+
+	// Only the encrypted key (and mnemonic) goes to disk
+	stored := &Identity{
+		PublicKey: identity.PublicKey,
+		Npub:      identity.Npub,
+		Ncryptsec: ncryptsec,
+		Account:   identity.Account,
+	}
+	if identity.Mnemonic != "" {
+		stored.EncryptedMnemonic, err = encryptMnemonic(identity.Mnemonic, identity.PrivateKey, identity.PublicKey)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt mnemonic: %w", err)
+		}
+	}
+	if err := SaveIdentity(stored, filePath); err != nil {
+		return err
+	}
+
+	identity.Ncryptsec = ncryptsec
+	identity.EncryptedMnemonic = stored.EncryptedMnemonic
+	return nil
+}
+
+// LoadIdentityWithPassphrase loads an identity file and unlocks its private key
+// Plaintext files are returned as they are (use EncryptIdentityFile to migrate them)
+//
+// Parameters:
+//   - filePath: path to the JSON file
+//   - passphrase: passphrase the key was encrypted with
+//
+// Returns:
+//   - *Identity: unlocked identity
+//   - error: ErrWrongPassphrase, or error if the file doesn't exist or is invalid
+func LoadIdentityWithPassphrase(filePath, passphrase string) (*Identity, error) {
+	identity, err := LoadIdentity(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if !identity.IsEncrypted() {
+		return identity, nil
+	}
+
+	if err := identity.Unlock(passphrase); err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+// Unlock decrypts the ncryptsec key and fills in the private key, nsec and mnemonic
+func (i *Identity) Unlock(passphrase string) error {
+	if !i.IsEncrypted() {
+		return ErrNotEncrypted
+	}
+	if passphrase == "" {
+		return ErrPassphraseRequired
+	}
+
+	privKey, err := nip49.Decrypt(i.Ncryptsec, passphrase)
+	if err != nil {
+		if strings.Contains(err.Error(), "authentication failed") {
+			return ErrWrongPassphrase
+		}
+		return fmt.Errorf("failed to decrypt private key: %w", err)
+	}
+
+	// Guard against a file whose public key was swapped for someone else's
+	pubKey, err := GetPublicKeyFromPrivate(privKey)
+	if err != nil {
+		return err
+	}
+	if i.PublicKey != "" && pubKey != i.PublicKey {
+		return fmt.Errorf("decrypted key does not match public key %s", i.PublicKey[:16])
+	}
+
+	nsec, err := nip19.EncodePrivateKey(privKey)
+	if err != nil {
+		return fmt.Errorf("failed to encode private key: %w", err)
+	}
+
+	if i.EncryptedMnemonic != "" {
+		mnemonic, err := decryptMnemonic(i.EncryptedMnemonic, privKey, pubKey)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt mnemonic: %w", err)
+		}
+		i.Mnemonic = mnemonic
+	}
+
+	i.PrivateKey = privKey
+	i.PublicKey = pubKey
+	i.Nsec = nsec
+	return nil
+}
+
+// EncryptIdentityFile migrates a plaintext identity file to the encrypted format
+// The file is replaced in place, so the plaintext key no longer exists on disk
+//
+// Parameters:
+//   - filePath: path to the plaintext identity file
+//   - passphrase: new passphrase
+//
+// Returns:
+//   - *Identity: the unlocked identity
+//   - error: error if the file is already encrypted or can't be rewritten
+func EncryptIdentityFile(filePath, passphrase string) (*Identity, error) {
+	identity, err := LoadIdentity(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if identity.IsEncrypted() {
+		return nil, fmt.Errorf("identity is already encrypted")
+	}
+
+	if err := SaveEncryptedIdentity(identity, filePath, passphrase); err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+// ChangePassphrase re-encrypts an identity file with a new passphrase
+//
+// Parameters:
+//   - filePath: path to the encrypted identity file
+//   - oldPassphrase: current passphrase
+//   - newPassphrase: new passphrase
+//
+// Returns:
+//   - error: ErrNotEncrypted, ErrWrongPassphrase, or write error
+func ChangePassphrase(filePath, oldPassphrase, newPassphrase string) error {
+	identity, err := LoadIdentity(filePath)
+	if err != nil {
+		return err
+	}
+	if !identity.IsEncrypted() {
+		return ErrNotEncrypted
+	}
+
+	if err := identity.Unlock(oldPassphrase); err != nil {
+		return err
+	}
+	return SaveEncryptedIdentity(identity, filePath, newPassphrase)
+}
+
+// IsEncryptedFile reports whether the identity file at the given path is encrypted
+// Returns false if the file doesn't exist or can't be parsed
+func IsEncryptedFile(filePath string) bool {
+	identity, err := LoadIdentity(filePath)
+	if err != nil {
+		return false
+	}
+	return identity.IsEncrypted()
+}
@@ -0,0 +1,357 @@
+package identity
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// NIP-49 test vector: this ncryptsec decrypts to nip49Key with nip49Passphrase
+const (
+	nip49Ncryptsec  = "ncryptsec1qgg9947rlpvqu76pj5ecreduf9jxhselq2nae2kghhvd5g7dgjtcxfqtd67p9m0w57lspw8gsq6yphnm8623nsl8xn9j4jdzz84zm3frztj3z7s35vpzmqf6ksu8r89qk5z2zxfmu5gv8th8wclt0h4p"
+	nip49Passphrase = "nostr"
+	nip49Key        = "3501454135014541350145413501453fefb02227e449e57cf4d3a3ce05378683"
+)
+
+// testIdentity creates an unlocked identity, with or without a mnemonic
+func testIdentity(t *testing.T, withMnemonic bool) *Identity {
+	t.Helper()
+
+	if withMnemonic {
+		identity, err := newIdentityFromMnemonic()
+		if err != nil {
+			t.Fatalf("failed to create identity: %v", err)
+		}
+		return identity
+	}
+
+	privKey, pubKey, nsec, npub, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("failed to generate key pair: %v", err)
+	}
+	return &Identity{PrivateKey: privKey, PublicKey: pubKey, Nsec: nsec, Npub: npub}
+}
+
+// readFile returns the contents of a file as a string
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+// assertNoSecrets fails if the file contains the identity's plaintext secrets
+func assertNoSecrets(t *testing.T, path string, identity *Identity) {
+	t.Helper()
+
+	contents := readFile(t, path)
+	secrets := map[string]string{
+		"hex private key": identity.PrivateKey,
+		"nsec":            identity.Nsec,
+		"mnemonic":        identity.Mnemonic,
+	}
+	for name, secret := range secrets {
+		if secret != "" && strings.Contains(contents, secret) {
+			t.Errorf("%s found in %s", name, path)
+		}
+	}
+	if !strings.Contains(contents, "ncryptsec1") {
+		t.Errorf("no ncryptsec key in %s", path)
+	}
+}
+
+func TestSaveAndLoadEncryptedIdentity(t *testing.T) {
+	tests := []struct {
+		name         string
+		withMnemonic bool
+		passphrase   string
+	}{
+		{"key only", false, "correct horse battery staple"},
+		{"with mnemonic", true, "correct horse battery staple"},
+		{"unicode passphrase", false, "ÅΩẛ̣ パスワード"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "identity.json")
+			identity := testIdentity(t, tt.withMnemonic)
+
+			if err := SaveEncryptedIdentity(identity, path, tt.passphrase); err != nil {
+				t.Fatalf("SaveEncryptedIdentity: %v", err)
+			}
+			assertNoSecrets(t, path, identity)
+
+			if !IsEncryptedFile(path) {
+				t.Error("IsEncryptedFile = false")
+			}
+
+			loaded, err := LoadIdentityWithPassphrase(path, tt.passphrase)
+			if err != nil {
+				t.Fatalf("LoadIdentityWithPassphrase: %v", err)
+			}
+			if loaded.PrivateKey != identity.PrivateKey {
+				t.Errorf("private key = %s, want %s", loaded.PrivateKey, identity.PrivateKey)
+			}
+			if loaded.PublicKey != identity.PublicKey || loaded.Nsec != identity.Nsec {
+				t.Errorf("public key or nsec changed after the round trip")
+			}
+			if loaded.Mnemonic != identity.Mnemonic {
+				t.Errorf("mnemonic = %q, want %q", loaded.Mnemonic, identity.Mnemonic)
+			}
+		})
+	}
+}
+
+func TestLoadIdentityWithPassphraseErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "identity.json")
+	identity := testIdentity(t, false)
+	if err := SaveEncryptedIdentity(identity, path, "right"); err != nil {
+		t.Fatalf("SaveEncryptedIdentity: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		passphrase string
+		want       error
+	}{
+		{"wrong passphrase", "wrong", ErrWrongPassphrase},
+		{"empty passphrase", "", ErrPassphraseRequired},
+		{"different case", "Right", ErrWrongPassphrase},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadIdentityWithPassphrase(path, tt.passphrase)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveEncryptedIdentityRejects(t *testing.T) {
+	tests := []struct {
+		name       string
+		identity   *Identity
+		passphrase string
+	}{
+		{"empty passphrase", testIdentity(t, false), ""},
+		{"locked identity", &Identity{PublicKey: "00", Ncryptsec: nip49Ncryptsec}, "pass"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "identity.json")
+			if err := SaveEncryptedIdentity(tt.identity, path, tt.passphrase); err == nil {
+				t.Fatal("SaveEncryptedIdentity succeeded")
+			}
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("identity file was written")
+			}
+		})
+	}
+}
+
+func TestEncryptIdentityFile(t *testing.T) {
+	tests := []struct {
+		name         string
+		withMnemonic bool
+	}{
+		{"key only", false},
+		{"with mnemonic", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "identity.json")
+			identity := testIdentity(t, tt.withMnemonic)
+			if err := SaveIdentity(identity, path); err != nil {
+				t.Fatalf("SaveIdentity: %v", err)
+			}
+			if !strings.Contains(readFile(t, path), identity.PrivateKey) {
+				t.Fatal("plaintext file doesn't contain the key")
+			}
+
+			migrated, err := EncryptIdentityFile(path, "migrate me")
+			if err != nil {
+				t.Fatalf("EncryptIdentityFile: %v", err)
+			}
+			if migrated.PrivateKey != identity.PrivateKey {
+				t.Errorf("migrated identity has another key")
+			}
+			assertNoSecrets(t, path, identity)
+			if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+				t.Errorf("temporary file left behind")
+			}
+
+			loaded, err := LoadIdentityWithPassphrase(path, "migrate me")
+			if err != nil {
+				t.Fatalf("LoadIdentityWithPassphrase: %v", err)
+			}
+			if loaded.PrivateKey != identity.PrivateKey || loaded.Mnemonic != identity.Mnemonic {
+				t.Errorf("migrated file doesn't unlock to the original identity")
+			}
+
+			// A second migration would lose nothing, but is refused
+			if _, err := EncryptIdentityFile(path, "again"); err == nil {
+				t.Errorf("EncryptIdentityFile accepted an encrypted file")
+			}
+		})
+	}
+}
+
+func TestChangePassphrase(t *testing.T) {
+	tests := []struct {
+		name      string
+		encrypted bool
+		oldPass   string
+		newPass   string
+		want      error
+	}{
+		{"changed", true, "old", "new", nil},
+		{"wrong old passphrase", true, "nope", "new", ErrWrongPassphrase},
+		{"empty new passphrase", true, "old", "", ErrPassphraseRequired},
+		{"plaintext file", false, "old", "new", ErrNotEncrypted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "identity.json")
+			identity := testIdentity(t, true)
+			if tt.encrypted {
+				if err := SaveEncryptedIdentity(identity, path, "old"); err != nil {
+					t.Fatalf("SaveEncryptedIdentity: %v", err)
+				}
+			} else if err := SaveIdentity(identity, path); err != nil {
+				t.Fatalf("SaveIdentity: %v", err)
+			}
+
+			err := ChangePassphrase(path, tt.oldPass, tt.newPass)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if tt.want != nil {
+				if tt.encrypted {
+					// The file still opens with the old passphrase
+					if _, err := LoadIdentityWithPassphrase(path, "old"); err != nil {
+						t.Errorf("old passphrase no longer works: %v", err)
+					}
+				}
+				return
+			}
+
+			if _, err := LoadIdentityWithPassphrase(path, tt.oldPass); !errors.Is(err, ErrWrongPassphrase) {
+				t.Errorf("old passphrase: err = %v, want %v", err, ErrWrongPassphrase)
+			}
+			loaded, err := LoadIdentityWithPassphrase(path, tt.newPass)
+			if err != nil {
+				t.Fatalf("new passphrase: %v", err)
+			}
+			if loaded.PrivateKey != identity.PrivateKey || loaded.Mnemonic != identity.Mnemonic {
+				t.Errorf("identity changed with the passphrase")
+			}
+		})
+	}
+}
+
+func TestEnsureIdentityPolicy(t *testing.T) {
+	defer SetPassphrasePolicy(GetPassphrasePolicy())
+
+	tests := []struct {
+		name      string
+		policy    PassphrasePolicy
+		existing  string // "", "plaintext" or "encrypted"
+		want      error
+		wantNew   bool
+		wantSaved bool
+	}{
+		{"optional creates plaintext", PassphraseOptional, "", nil, true, true},
+		{"required refuses to create", PassphraseRequired, "", ErrPassphraseRequired, false, false},
+		{"required loads existing plaintext", PassphraseRequired, "plaintext", nil, false, true},
+		{"required on encrypted file", PassphraseRequired, "encrypted", ErrPassphraseRequired, false, true},
+		{"optional on encrypted file", PassphraseOptional, "encrypted", ErrPassphraseRequired, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetPassphrasePolicy(tt.policy)
+			path := filepath.Join(t.TempDir(), "identity.json")
+
+			switch tt.existing {
+			case "plaintext":
+				if err := SaveIdentity(testIdentity(t, false), path); err != nil {
+					t.Fatalf("SaveIdentity: %v", err)
+				}
+			case "encrypted":
+				if err := SaveEncryptedIdentity(testIdentity(t, false), path, "pass"); err != nil {
+					t.Fatalf("SaveEncryptedIdentity: %v", err)
+				}
+			}
+
+			identity, created, err := EnsureIdentity(path)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if created != tt.wantNew {
+				t.Errorf("created = %v, want %v", created, tt.wantNew)
+			}
+			if tt.want == nil && identity.PrivateKey == "" {
+				t.Errorf("identity has no private key")
+			}
+
+			_, statErr := os.Stat(path)
+			if saved := statErr == nil; saved != tt.wantSaved {
+				t.Errorf("file exists = %v, want %v", saved, tt.wantSaved)
+			}
+		})
+	}
+}
+
+func TestEnsureIdentityWithPassphraseUnderRequiredPolicy(t *testing.T) {
+	defer SetPassphrasePolicy(GetPassphrasePolicy())
+	SetPassphrasePolicy(PassphraseRequired)
+
+	path := filepath.Join(t.TempDir(), "identity.json")
+	identity, created, err := EnsureIdentityWithPassphrase(path, "pass")
+	if err != nil {
+		t.Fatalf("EnsureIdentityWithPassphrase: %v", err)
+	}
+	if !created {
+		t.Error("created = false for a new file")
+	}
+	assertNoSecrets(t, path, identity)
+
+	// The encrypted identity it wrote isn't readable without the passphrase
+	if _, _, err := EnsureIdentity(path); !errors.Is(err, ErrPassphraseRequired) {
+		t.Errorf("EnsureIdentity: err = %v, want %v", err, ErrPassphraseRequired)
+	}
+}
+
+func TestNIP49TestVector(t *testing.T) {
+	identity := &Identity{Ncryptsec: nip49Ncryptsec}
+	if err := identity.Unlock(nip49Passphrase); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if identity.PrivateKey != nip49Key {
+		t.Fatalf("private key = %s, want %s", identity.PrivateKey, nip49Key)
+	}
+
+	wantPub, err := GetPublicKeyFromPrivate(nip49Key)
+	if err != nil {
+		t.Fatalf("GetPublicKeyFromPrivate: %v", err)
+	}
+	if identity.PublicKey != wantPub {
+		t.Errorf("public key = %s, want %s", identity.PublicKey, wantPub)
+	}
+
+	// The same vector behind a public key that doesn't match is refused
+	swapped := &Identity{Ncryptsec: nip49Ncryptsec, PublicKey: strings.Repeat("ab", 32)}
+	if err := swapped.Unlock(nip49Passphrase); err == nil {
+		t.Error("Unlock accepted a file whose public key was swapped")
+	}
+}
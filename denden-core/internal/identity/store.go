@@ -9,10 +9,15 @@ import (
 
 // Identity represents a user's identity including all key formats
 type Identity struct {
-	PrivateKey string `json:"private_key"` // Private key in hex format
-	PublicKey  string `json:"public_key"`  // Public key in hex format
-	Nsec       string `json:"nsec"`        // Private key in Bech32 format (nsec1...)
-	Npub       string `json:"npub"`        // Public key in Bech32 format (npub1...)
+	PrivateKey string `json:"private_key,omitempty"` // Private key in hex format (empty while locked)
+	PublicKey  string `json:"public_key"`            // Public key in hex format
+	Nsec       string `json:"nsec,omitempty"`        // Private key in Bech32 format (nsec1...)
+	Npub       string `json:"npub"`                  // Public key in Bech32 format (npub1...)
+	Ncryptsec  string `json:"ncryptsec,omitempty"`   // Passphrase-encrypted private key (NIP-49, ncryptsec1...)
+
+	Mnemonic          string `json:"mnemonic,omitempty"`           // BIP-39 words the key was derived from (NIP-06, empty while locked)
+	EncryptedMnemonic string `json:"encrypted_mnemonic,omitempty"` // Mnemonic encrypted to the identity's own key (NIP-44)
+	Account           uint32 `json:"account,omitempty"`            // NIP-06 account index (m/44'/1237'/<account>'/0/0)
 }
 
 // SaveIdentity saves the identity to a JSON file
@@ -36,12 +41,20 @@ func SaveIdentity(identity *Identity, filePath string) error {
 		return fmt.Errorf("failed to marshal identity: %w", err)
 	}
 
-	// Write to file with restricted permissions (only owner can read)
-	err = os.WriteFile(filePath, data, 0600)
+	// Write to a temporary file with restricted permissions (only owner can read),
+	// then rename, so an interrupted write never leaves us without a key
+	tmpPath := filePath + ".tmp"
+	err = os.WriteFile(tmpPath, data, 0600)
 	if err != nil {
 		return fmt.Errorf("failed to write identity file: %w", err)
 	}
 
+	err = os.Rename(tmpPath, filePath)
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write identity file: %w", err)
+	}
+
 	return nil
 }
 
@@ -75,6 +88,8 @@ func LoadIdentity(filePath string) (*Identity, error) {
 // EnsureIdentity ensures an identity exists at the given path
 // If the file doesn't exist, generates a new identity and saves it
 // If the file exists, loads and returns it
+// Encrypted files, and new identities under PassphraseRequired, return
+// ErrPassphraseRequired (use EnsureIdentityWithPassphrase)
 //
 // Parameters:
 //   - filePath: path to the identity file
@@ -87,6 +102,9 @@ func EnsureIdentity(filePath string) (*Identity, bool, error) {
 	// Try to load existing identity
 	identity, err := LoadIdentity(filePath)
 	if err == nil {
+		if identity.IsEncrypted() {
+			return nil, false, fmt.Errorf("identity is encrypted: %w", ErrPassphraseRequired)
+		}
 		// Identity exists, return it
 		return identity, false, nil
 	}
@@ -97,17 +115,15 @@ func EnsureIdentity(filePath string) (*Identity, bool, error) {
 		return nil, false, fmt.Errorf("failed to load identity: %w", err)
 	}
 
-	// Identity doesn't exist, generate a new one
-	privKey, pubKey, nsec, npub, err := GenerateKeyPair()
-	if err != nil {
-		return nil, false, fmt.Errorf("failed to generate key pair: %w", err)
+	// Don't silently write a plaintext key if the policy asks for encryption
+	if passphrasePolicy == PassphraseRequired {
+		return nil, false, fmt.Errorf("refusing to create a plaintext identity: %w", ErrPassphraseRequired)
 	}
 
-	identity = &Identity{
-		PrivateKey: privKey,
-		PublicKey:  pubKey,
-		Nsec:       nsec,
-		Npub:       npub,
+	// Identity doesn't exist, generate a new one
+	identity, err = newIdentity()
+	if err != nil {
+		return nil, false, err
 	}
 
 	// Save to file
@@ -119,6 +135,65 @@ func EnsureIdentity(filePath string) (*Identity, bool, error) {
 	return identity, true, nil
 }
 
+// EnsureIdentityWithPassphrase is EnsureIdentity for encrypted identities
+// New identities are saved encrypted, encrypted files are unlocked, and
+// plaintext files are migrated to the encrypted format
+//
+// Parameters:
+//   - filePath: path to the identity file
+//   - passphrase: passphrase for the private key
+//
+// Returns:
+//   - *Identity: unlocked identity
+//   - bool: true if newly created, false if loaded from file
+//   - error: ErrWrongPassphrase, or error if any
+func EnsureIdentityWithPassphrase(filePath, passphrase string) (*Identity, bool, error) {
+	if passphrase == "" {
+		return nil, false, ErrPassphraseRequired
+	}
+
+	identity, err := LoadIdentity(filePath)
+	if err == nil {
+		if identity.IsEncrypted() {
+			if err := identity.Unlock(passphrase); err != nil {
+				return nil, false, err
+			}
+			return identity, false, nil
+		}
+
+		// Migrate the plaintext file
+		if err := SaveEncryptedIdentity(identity, filePath, passphrase); err != nil {
+			return nil, false, fmt.Errorf("failed to encrypt identity: %w", err)
+		}
+		return identity, false, nil
+	}
+
+	if _, statErr := os.Stat(filePath); !os.IsNotExist(statErr) {
+		return nil, false, fmt.Errorf("failed to load identity: %w", err)
+	}
+
+	identity, err = newIdentity()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := SaveEncryptedIdentity(identity, filePath, passphrase); err != nil {
+		return nil, false, fmt.Errorf("failed to save identity: %w", err)
+	}
+
+	return identity, true, nil
+}
+
+// newIdentity generates a fresh key pair from a new mnemonic (NIP-06),
+// so the identity can be backed up as words
+func newIdentity() (*Identity, error) {
+	identity, err := newIdentityFromMnemonic()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key pair: %w", err)
+	}
+	return identity, nil
+}
+
 // GetDefaultIdentityPath returns the default path for identity storage
 // Returns: ~/.denden/identity.json
 func GetDefaultIdentityPath() (string, error) {
@@ -0,0 +1,114 @@
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"denden-core/internal/pow"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+var (
+	// ErrInvalidID is returned when an event's ID isn't the hash of its content
+	ErrInvalidID = errors.New("invalid event id")
+
+	// ErrInvalidSignature is returned when an event's signature doesn't verify
+	ErrInvalidSignature = errors.New("invalid signature")
+
+	// ErrInsufficientPoW is returned when an event's committed difficulty
+	// is below the minimum for its kind
+	ErrInsufficientPoW = errors.New("insufficient proof of work")
+)
+
+// Pipeline checks events received from relays before the client uses or stores them
+// Events with an invalid ID or signature are dropped, and so are events whose
+// committed NIP-13 difficulty is below the minimum for their kind
+type Pipeline struct {
+	minDifficulty *pow.Policy // Minimum committed difficulty per kind
+	strict        atomic.Bool // Require the recommended minimums for public kinds
+}
+
+// NewPipeline creates a pipeline that requires no PoW by default
+// Most clients don't mine their events, so a minimum would drop almost all
+// normal traffic; opt in with SetStrict or per kind with SetMinDifficulty
+func NewPipeline() *Pipeline {
+	p := &Pipeline{}
+	p.minDifficulty = pow.NewPolicy(p.defaultMinDifficulty)
+	return p
+}
+
+// SetStrict turns the recommended minimums for public kinds (1, 6 and 16,
+// see pow.GetKindDifficulty) on or off. Private kinds (4, 1059) and group
+// kinds never require PoW by default; overrides from SetMinDifficulty still apply
+func (p *Pipeline) SetStrict(strict bool) {
+	p.strict.Store(strict)
+}
+
+// IsStrict reports whether the recommended minimums for public kinds apply
+func (p *Pipeline) IsStrict() bool {
+	return p.strict.Load()
+}
+
+// defaultMinDifficulty is the minimum for kinds without an override
+func (p *Pipeline) defaultMinDifficulty(kind int) int {
+	if !p.strict.Load() || pow.GetKindCategory(kind) != "public" {
+		return 0
+	}
+	return pow.GetKindDifficulty(kind)
+}
+
+// SetMinDifficulty overrides the minimum difficulty for a kind (0 disables the check)
+func (p *Pipeline) SetMinDifficulty(kind, difficulty int) {
+	p.minDifficulty.Set(kind, difficulty)
+}
+
+// ResetMinDifficulty goes back to the default minimum for a kind
+// (0, or the recommended one for public kinds in strict mode)
+func (p *Pipeline) ResetMinDifficulty(kind int) {
+	p.minDifficulty.Reset(kind)
+}
+
+// MinDifficulty returns the minimum difficulty required for a kind
+func (p *Pipeline) MinDifficulty(kind int) int {
+	return p.minDifficulty.Difficulty(kind)
+}
+
+// Check verifies an event
+// Parameters:
+//   - event: event received from a relay
+//
+// Returns:
+//   - error: ErrInvalidID, ErrInvalidSignature or ErrInsufficientPoW (wrapped), nil if valid
+func (p *Pipeline) Check(event *nostr.Event) error {
+	if !event.CheckID() {
+		return ErrInvalidID
+	}
+
+	ok, err := event.CheckSignature()
+	if err != nil || !ok {
+		return ErrInvalidSignature
+	}
+
+	// The ID is verified, so the committed difficulty can't be faked
+	required := p.MinDifficulty(event.Kind)
+	if required > 0 {
+		if committed := pow.CommittedDifficulty(event); committed < required {
+			return fmt.Errorf("%w: kind %d has difficulty %d, needs %d", ErrInsufficientPoW, event.Kind, committed, required)
+		}
+	}
+
+	return nil
+}
+
+// Filter returns the events that pass Check, in the same order
+func (p *Pipeline) Filter(events []*nostr.Event) []*nostr.Event {
+	valid := make([]*nostr.Event, 0, len(events))
+	for _, event := range events {
+		if p.Check(event) == nil {
+			valid = append(valid, event)
+		}
+	}
+	return valid
+}
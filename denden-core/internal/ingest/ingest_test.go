@@ -0,0 +1,129 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"denden-core/internal/pow"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// signedEvent returns a signed event of a kind, mined to difficulty if it's above 0
+func signedEvent(t *testing.T, kind, difficulty int) *nostr.Event {
+	t.Helper()
+
+	event := &nostr.Event{Kind: kind, CreatedAt: nostr.Now(), Content: "hello"}
+	sk := nostr.GeneratePrivateKey()
+	event.PubKey, _ = nostr.GetPublicKey(sk)
+	if difficulty > 0 {
+		if _, err := pow.Mine(context.Background(), event, difficulty, pow.Options{FixedTimestamp: true}); err != nil {
+			t.Fatalf("Mine: %v", err)
+		}
+	}
+	if err := event.Sign(sk); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return event
+}
+
+func TestPipelineAcceptsUnminedEventsByDefault(t *testing.T) {
+	p := NewPipeline()
+
+	kinds := []int{
+		nostr.KindTextNote,
+		nostr.KindRepost,
+		nostr.KindGenericRepost,
+		nostr.KindEncryptedDirectMessage,
+		nostr.KindGiftWrap,
+		nostr.KindSimpleGroupChatMessage,
+		nostr.KindChannelMessage,
+		nostr.KindReaction,
+	}
+	for _, kind := range kinds {
+		if got := p.MinDifficulty(kind); got != 0 {
+			t.Errorf("MinDifficulty(%d) = %d, want 0", kind, got)
+		}
+		if err := p.Check(signedEvent(t, kind, 0)); err != nil {
+			t.Errorf("kind %d: %v", kind, err)
+		}
+	}
+}
+
+func TestPipelineStrict(t *testing.T) {
+	p := NewPipeline()
+	p.SetStrict(true)
+
+	tests := []struct {
+		kind int
+		want int
+	}{
+		{nostr.KindTextNote, pow.GetKindDifficulty(nostr.KindTextNote)},
+		{nostr.KindRepost, pow.GetKindDifficulty(nostr.KindRepost)},
+		{nostr.KindGenericRepost, pow.GetKindDifficulty(nostr.KindGenericRepost)},
+		{nostr.KindEncryptedDirectMessage, 0},
+		{nostr.KindGiftWrap, 0},
+		{nostr.KindSimpleGroupChatMessage, 0},
+		{nostr.KindReaction, 0},
+	}
+	for _, tt := range tests {
+		if got := p.MinDifficulty(tt.kind); got != tt.want {
+			t.Errorf("MinDifficulty(%d) = %d, want %d", tt.kind, got, tt.want)
+		}
+	}
+
+	if err := p.Check(signedEvent(t, nostr.KindTextNote, 0)); !errors.Is(err, ErrInsufficientPoW) {
+		t.Errorf("unmined note: err = %v, want ErrInsufficientPoW", err)
+	}
+	if err := p.Check(signedEvent(t, nostr.KindEncryptedDirectMessage, 0)); err != nil {
+		t.Errorf("unmined DM: %v", err)
+	}
+
+	// Overrides win over strict mode, and Reset goes back to it
+	p.SetMinDifficulty(nostr.KindTextNote, 8)
+	if err := p.Check(signedEvent(t, nostr.KindTextNote, 8)); err != nil {
+		t.Errorf("note mined to the override: %v", err)
+	}
+	p.ResetMinDifficulty(nostr.KindTextNote)
+	p.SetStrict(false)
+	if got := p.MinDifficulty(nostr.KindTextNote); got != 0 {
+		t.Errorf("MinDifficulty after SetStrict(false) = %d, want 0", got)
+	}
+}
+
+func TestPipelineOptInPerKind(t *testing.T) {
+	p := NewPipeline()
+	p.SetMinDifficulty(nostr.KindEncryptedDirectMessage, 8)
+
+	if err := p.Check(signedEvent(t, nostr.KindEncryptedDirectMessage, 0)); !errors.Is(err, ErrInsufficientPoW) {
+		t.Errorf("unmined DM: err = %v, want ErrInsufficientPoW", err)
+	}
+	if err := p.Check(signedEvent(t, nostr.KindEncryptedDirectMessage, 8)); err != nil {
+		t.Errorf("mined DM: %v", err)
+	}
+	if err := p.Check(signedEvent(t, nostr.KindTextNote, 0)); err != nil {
+		t.Errorf("unmined note: %v", err)
+	}
+}
+
+func TestPipelineRejectsForgedEvents(t *testing.T) {
+	p := NewPipeline()
+
+	changed := signedEvent(t, nostr.KindTextNote, 0)
+	changed.Content = "changed"
+	if err := p.Check(changed); !errors.Is(err, ErrInvalidID) {
+		t.Errorf("changed content: err = %v, want ErrInvalidID", err)
+	}
+
+	other := signedEvent(t, nostr.KindTextNote, 0)
+	other.Sig = signedEvent(t, nostr.KindTextNote, 0).Sig
+	if err := p.Check(other); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("foreign signature: err = %v, want ErrInvalidSignature", err)
+	}
+
+	valid := signedEvent(t, nostr.KindTextNote, 0)
+	if got := p.Filter([]*nostr.Event{changed, valid, other}); len(got) != 1 || got[0] != valid {
+		t.Errorf("Filter kept %d events, want only the valid one", len(got))
+	}
+}
@@ -0,0 +1,302 @@
+package notify
+
+import (
+	"sort"
+	"sync"
+
+	"denden-core/internal/thread"
+	"denden-core/internal/zap"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// Notification types
+const (
+	TypeMention  = "mention"  // A post that tags the user
+	TypeReply    = "reply"    // A reply to one of the user's posts
+	TypeQuote    = "quote"    // A post quoting one of the user's posts (NIP-18 "q" tag)
+	TypeReaction = "reaction" // A reaction to one of the user's posts (Kind 7)
+	TypeRepost   = "repost"   // A repost of one of the user's posts (Kind 6)
+	TypeZap      = "zap"      // A zap to the user or one of their posts (Kind 9735)
+	TypeFollow   = "follow"   // A contact list that includes the user (Kind 3)
+)
+
+// Kinds are the event kinds that can notify the user (when they tag them)
+var Kinds = []int{1, 3, 6, 7, 9735}
+
+// maxEventsPerGroup caps the event IDs kept per group
+const maxEventsPerGroup = 50
+
+// Notification is one event about the user
+type Notification struct {
+	Type      string
+	Target    string // Event the notification is about (the user's post); "" for follows and profile zaps
+	Actor     string // Who did it (for zaps, the sender from the zap request)
+	EventID   string // The notifying event
+	Content   string // Reply/mention text, or the reaction ("+", emoji...)
+	Amount    int64  // Zap amount in sats, from the receipt's invoice
+	CreatedAt nostr.Timestamp
+}
+
+// Classify reads what an event means for the user
+// Returns false for events that aren't notifications (e.g. the user's own) and for
+// invalid zap receipts; who signed a receipt is checked by Feed.Add
+//
+// Parameters:
+//   - event: an event of one of Kinds
+//   - me: the user's public key (hex)
+func Classify(event *nostr.Event, me string) (Notification, bool) {
+	n := Notification{
+		Actor:     event.PubKey,
+		EventID:   event.ID,
+		CreatedAt: event.CreatedAt,
+	}
+
+	switch event.Kind {
+	case 1:
+		n.Content = event.Content
+		refs := thread.Parse(event)
+		switch {
+		case tagValue(event, "q") != "":
+			n.Type = TypeQuote
+			n.Target = tagValue(event, "q")
+		case refs.IsReply() && repliesTo(event, refs.Reply, me):
+			n.Type = TypeReply
+			n.Target = refs.Reply
+		default:
+			n.Type = TypeMention
+		}
+
+	case 3:
+		n.Type = TypeFollow
+
+	case 6:
+		n.Type = TypeRepost
+		n.Target = tagValue(event, "e")
+
+	case 7:
+		n.Type = TypeReaction
+		n.Content = event.Content
+		n.Target = lastTagValue(event, "e") // NIP-25: the reacted-to event is the last "e" tag
+
+	case 9735:
+		receipt, err := zap.ParseReceipt(event)
+		if err != nil {
+			return Notification{}, false
+		}
+		n.Type = TypeZap
+		n.Target = receipt.Target
+		n.Actor, n.Amount = receipt.Sender, receipt.Sats
+
+	default:
+		return Notification{}, false
+	}
+
+	if n.Actor == me || n.Actor == "" {
+		return Notification{}, false
+	}
+	return n, true
+}
+
+// repliesTo reports whether a reply's parent is the user's post
+// Replies carry the parent author in the "e" tag (NIP-10); without it, the
+// "p" tag that matched the user most likely means they wrote the parent
+func repliesTo(event *nostr.Event, parentID, me string) bool {
+	for _, tag := range event.Tags {
+		if len(tag) >= 5 && tag[0] == "e" && tag[1] == parentID && tag[4] != "" {
+			return tag[4] == me
+		}
+	}
+	return true
+}
+
+// tagValue returns the value of the first tag with a name
+func tagValue(event *nostr.Event, name string) string {
+	for _, tag := range event.Tags {
+		if len(tag) >= 2 && tag[0] == name {
+			return tag[1]
+		}
+	}
+	return ""
+}
+
+// lastTagValue returns the value of the last tag with a name
+func lastTagValue(event *nostr.Event, name string) string {
+	value := ""
+	for _, tag := range event.Tags {
+		if len(tag) >= 2 && tag[0] == name {
+			value = tag[1]
+		}
+	}
+	return value
+}
+
+// Group is the notifications of one type about one target
+// ("Alice and 4 others liked your post"). Replies, quotes and mentions
+// are never grouped: each one is its own group
+type Group struct {
+	ID        string          // Stable key: type and target
+	Type      string          // Notification type
+	Target    string          // Event the group is about ("" for follows and profile zaps)
+	Actors    []string        // Who, most recent first, without duplicates
+	Count     int             // Number of notifications (for follows, of followers)
+	EventIDs  []string        // Notifying events, most recent first (up to 50)
+	Content   string          // Content of the latest notification
+	Amount    int64           // Total zapped, in sats
+	LatestAt  nostr.Timestamp // Time of the latest notification
+	seenEvent map[string]bool
+}
+
+// groupID returns the key of the group a notification belongs to
+func groupID(n Notification) string {
+	switch n.Type {
+	case TypeReaction, TypeRepost, TypeZap:
+		return n.Type + ":" + n.Target
+	case TypeFollow:
+		return n.Type
+	default:
+		return n.Type + ":" + n.EventID
+	}
+}
+
+// Feed groups a user's notifications (safe for concurrent use)
+type Feed struct {
+	mu         sync.Mutex
+	me         string
+	groups     map[string]*Group
+	zapSigners zap.Signers // LNURL server key per zap recipient
+}
+
+// NewFeed creates an empty notification feed for a user
+func NewFeed(me string) *Feed {
+	return &Feed{
+		me:     me,
+		groups: make(map[string]*Group),
+	}
+}
+
+// SetZapSigners sets the keys allowed to sign zap receipts, per recipient
+// Zaps are only added once set, and only with receipts signed by the user's
+// LNURL server (see zap.Signers)
+func (f *Feed) SetZapSigners(signers zap.Signers) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.zapSigners = signers
+}
+
+// Add classifies an event and adds it to its group
+// Events can arrive in any order; duplicates are ignored, and so are the
+// contact lists of actors already counted as followers
+//
+// Returns:
+//   - Group: a copy of the updated group
+//   - bool: false if the event isn't a notification or was already added
+func (f *Feed) Add(event *nostr.Event) (Group, bool) {
+	n, ok := Classify(event, f.me)
+	if !ok {
+		return Group{}, false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if n.Type == TypeZap {
+		if _, err := f.zapSigners.Verify(event); err != nil {
+			return Group{}, false
+		}
+	}
+
+	id := groupID(n)
+	group, exists := f.groups[id]
+	if !exists {
+		group = &Group{
+			ID:        id,
+			Type:      n.Type,
+			Target:    n.Target,
+			seenEvent: make(map[string]bool),
+		}
+		f.groups[id] = group
+	}
+	if group.seenEvent[n.EventID] {
+		return Group{}, false
+	}
+	// Contact lists are republished on every follow and unfollow: an actor
+	// already in the follow group isn't a new follower
+	if n.Type == TypeFollow && contains(group.Actors, n.Actor) {
+		return Group{}, false
+	}
+	group.seenEvent[n.EventID] = true
+
+	group.Count++
+	group.Amount += n.Amount
+	latest := n.CreatedAt >= group.LatestAt
+	if latest {
+		group.LatestAt = n.CreatedAt
+		group.Content = n.Content
+		group.EventIDs = prepend(group.EventIDs, n.EventID)
+		group.Actors = prepend(remove(group.Actors, n.Actor), n.Actor)
+	} else {
+		group.EventIDs = append(group.EventIDs, n.EventID)
+		if !contains(group.Actors, n.Actor) {
+			group.Actors = append(group.Actors, n.Actor)
+		}
+	}
+	if len(group.EventIDs) > maxEventsPerGroup {
+		group.EventIDs = group.EventIDs[:maxEventsPerGroup]
+	}
+
+	return group.copy(), true
+}
+
+// Groups returns copies of the groups, most recent first
+func (f *Feed) Groups() []Group {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	groups := make([]Group, 0, len(f.groups))
+	for _, group := range f.groups {
+		groups = append(groups, group.copy())
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].LatestAt != groups[j].LatestAt {
+			return groups[i].LatestAt > groups[j].LatestAt
+		}
+		return groups[i].ID < groups[j].ID
+	})
+	return groups
+}
+
+// copy returns a copy that doesn't share slices with the group
+func (g *Group) copy() Group {
+	out := *g
+	out.Actors = append([]string(nil), g.Actors...)
+	out.EventIDs = append([]string(nil), g.EventIDs...)
+	out.seenEvent = nil
+	return out
+}
+
+// prepend adds a value at the front of a slice
+func prepend(values []string, value string) []string {
+	return append([]string{value}, values...)
+}
+
+// remove returns the slice without a value
+func remove(values []string, value string) []string {
+	out := values[:0]
+	for _, v := range values {
+		if v != value {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// contains reports whether a slice has a value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+const me = "1111111111111111111111111111111111111111111111111111111111111111"
+
+// contactList returns a Kind 3 event from author that follows the user
+func contactList(id, author string, createdAt nostr.Timestamp) *nostr.Event {
+	return &nostr.Event{
+		ID:        strings.Repeat(id, 64/len(id)),
+		PubKey:    author,
+		CreatedAt: createdAt,
+		Kind:      3,
+		Tags:      nostr.Tags{{"p", me}},
+	}
+}
+
+func TestFeedCountsEachFollowerOnce(t *testing.T) {
+	alice := strings.Repeat("a", 64)
+	bob := strings.Repeat("b", 64)
+
+	feed := NewFeed(me)
+	steps := []struct {
+		event *nostr.Event
+		isNew bool
+		count int
+	}{
+		{contactList("01", alice, 100), true, 1},
+		{contactList("02", alice, 200), false, 1}, // Alice follows someone else
+		{contactList("03", bob, 300), true, 2},
+		{contactList("04", alice, 400), false, 2},
+		{contactList("04", alice, 400), false, 2}, // Duplicate
+		{contactList("05", alice, 50), false, 2},  // An older list arriving late
+	}
+
+	for i, step := range steps {
+		group, isNew := feed.Add(step.event)
+		if isNew != step.isNew {
+			t.Fatalf("step %d: Add = %v, want %v", i, isNew, step.isNew)
+		}
+		groups := feed.Groups()
+		if len(groups) != 1 {
+			t.Fatalf("step %d: %d groups, want 1", i, len(groups))
+		}
+		if groups[0].Count != step.count || len(groups[0].Actors) != step.count {
+			t.Fatalf("step %d: Count = %d with %d actors, want %d", i, groups[0].Count, len(groups[0].Actors), step.count)
+		}
+		if isNew && group.Actors[0] != step.event.PubKey {
+			t.Errorf("step %d: latest actor = %s", i, group.Actors[0])
+		}
+	}
+
+	group := feed.Groups()[0]
+	if group.LatestAt != 300 {
+		t.Errorf("LatestAt = %d, want 300 (republished lists don't move it)", group.LatestAt)
+	}
+}
+
+func TestFeedGroupsReactions(t *testing.T) {
+	post := strings.Repeat("f", 64)
+	reaction := func(id, author string, createdAt nostr.Timestamp) *nostr.Event {
+		return &nostr.Event{
+			ID:        strings.Repeat(id, 32),
+			PubKey:    strings.Repeat(author, 64),
+			CreatedAt: createdAt,
+			Kind:      7,
+			Content:   "+",
+			Tags:      nostr.Tags{{"e", post}, {"p", me}},
+		}
+	}
+
+	feed := NewFeed(me)
+	feed.Add(reaction("01", "a", 100))
+	feed.Add(reaction("02", "b", 200))
+	feed.Add(reaction("03", "a", 150)) // A second reaction from Alice counts
+
+	own := reaction("04", "1", 300) // The user's own reaction isn't a notification
+	if _, ok := feed.Add(own); ok {
+		t.Error("own reaction added")
+	}
+
+	groups := feed.Groups()
+	if len(groups) != 1 {
+		t.Fatalf("%d groups, want 1", len(groups))
+	}
+	group := groups[0]
+	if group.ID != TypeReaction+":"+post || group.Count != 3 || len(group.Actors) != 2 {
+		t.Errorf("group = %+v", group)
+	}
+	if group.Actors[0] != strings.Repeat("b", 64) || group.LatestAt != 200 {
+		t.Errorf("latest actor = %s at %d, want b at 200", group.Actors[0], group.LatestAt)
+	}
+}
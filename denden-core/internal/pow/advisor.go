@@ -0,0 +1,206 @@
+package pow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// DefaultBenchmarkDuration is how long Benchmark mines to measure the hash rate
+const DefaultBenchmarkDuration = 500 * time.Millisecond
+
+// unknownRequirement marks a relay whose requirement couldn't be fetched
+const unknownRequirement = -1
+
+// Advisor picks the PoW difficulty for outgoing events and estimates how long
+// mining it will take on this device
+//
+// The difficulty for a kind is the lowest one that satisfies every target relay
+// (NIP-11 limitation.min_pow_difficulty), 0 if none requires PoW. While a
+// relay's requirement is unknown, the floor is GetPublishDifficulty, so nothing
+// is sent with too little work. Floors of its own belong in the publish Policy
+type Advisor struct {
+	mu       sync.RWMutex
+	relayMin map[string]int // relay URL -> min_pow_difficulty (0 if it has none, unknownRequirement if not fetched)
+	hashRate float64        // Attempts per second on this device (0 = not measured yet)
+
+	benchmark sync.Mutex // Serializes the one-time benchmark
+}
+
+// NewAdvisor creates an advisor with no relay requirements and no benchmark
+func NewAdvisor() *Advisor {
+	return &Advisor{
+		relayMin: make(map[string]int),
+	}
+}
+
+// SetRelayRequirement records a relay's minimum PoW (0 if the relay has none)
+func (a *Advisor) SetRelayRequirement(relayURL string, difficulty int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.relayMin[relayURL] = max(difficulty, 0)
+}
+
+// SetRelayUnknown records a relay whose requirement couldn't be fetched
+func (a *Advisor) SetRelayUnknown(relayURL string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.relayMin[relayURL] = unknownRequirement
+}
+
+// RemoveRelay forgets a relay's requirement
+func (a *Advisor) RemoveRelay(relayURL string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.relayMin, relayURL)
+}
+
+// RelayRequirements returns the requirement of every relay, by URL
+// (-1 for relays whose requirement is unknown)
+func (a *Advisor) RelayRequirements() map[string]int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	requirements := make(map[string]int, len(a.relayMin))
+	for url, difficulty := range a.relayMin {
+		requirements[url] = difficulty
+	}
+	return requirements
+}
+
+// RelayRequirement returns the highest minimum PoW of the known relays
+// Returns false if no relay was added, or if any relay's requirement is unknown
+func (a *Advisor) RelayRequirement() (int, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	required := 0
+	known := len(a.relayMin) > 0
+	for _, difficulty := range a.relayMin {
+		if difficulty == unknownRequirement {
+			known = false
+			continue
+		}
+		required = max(required, difficulty)
+	}
+	return required, known
+}
+
+// Difficulty returns the difficulty to mine an outgoing event of a kind with
+// Use it as the defaults of the publish Policy, so user overrides still win
+func (a *Advisor) Difficulty(kind int) int {
+	required, known := a.RelayRequirement()
+	if !known {
+		return max(required, GetPublishDifficulty(kind))
+	}
+	return required
+}
+
+// HashRate returns this device's hash rate, benchmarking it the first time
+// Parameters:
+//   - ctx: context (canceling it stops the benchmark)
+//
+// Returns:
+//   - float64: attempts per second
+//   - error: error if the benchmark was canceled
+func (a *Advisor) HashRate(ctx context.Context) (float64, error) {
+	a.benchmark.Lock()
+	defer a.benchmark.Unlock()
+
+	a.mu.RLock()
+	hashRate := a.hashRate
+	a.mu.RUnlock()
+	if hashRate > 0 {
+		return hashRate, nil
+	}
+
+	hashRate, err := Benchmark(ctx, DefaultBenchmarkDuration)
+	if err != nil {
+		return 0, err
+	}
+
+	a.SetHashRate(hashRate)
+	return hashRate, nil
+}
+
+// SetHashRate replaces the measured hash rate (e.g. with the rate of a real mining run)
+func (a *Advisor) SetHashRate(hashRate float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.hashRate = hashRate
+}
+
+// Estimate returns the expected time to mine a difficulty on this device
+// Benchmarks the device the first time it is called
+func (a *Advisor) Estimate(ctx context.Context, difficulty int) (time.Duration, error) {
+	hashRate, err := a.HashRate(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return EstimateDuration(difficulty, hashRate), nil
+}
+
+// Benchmark measures how many attempts per second Mine makes on this device
+// It mines a throwaway event at an unreachable difficulty for the given duration
+//
+// Parameters:
+//   - ctx: context (canceling it stops the benchmark early)
+//   - duration: how long to mine (0 = DefaultBenchmarkDuration)
+//
+// Returns:
+//   - float64: attempts per second, across every CPU
+//   - error: error if ctx was canceled before the benchmark finished
+func Benchmark(ctx context.Context, duration time.Duration) (float64, error) {
+	if duration <= 0 {
+		duration = DefaultBenchmarkDuration
+	}
+
+	benchCtx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	event := &nostr.Event{
+		PubKey:    nostr.GeneratePrivateKey(), // Any 32-byte hex works, it's never signed
+		CreatedAt: nostr.Now(),
+		Kind:      nostr.KindTextNote,
+		Content:   "benchmark",
+	}
+
+	_, err := Mine(benchCtx, event, 256, Options{})
+
+	var miningErr *MiningError
+	if !errors.As(err, &miningErr) || !errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
+		return 0, fmt.Errorf("benchmark interrupted: %w", err)
+	}
+	if miningErr.Elapsed <= 0 || miningErr.Attempts == 0 {
+		return 0, fmt.Errorf("benchmark made no attempts")
+	}
+
+	return float64(miningErr.Attempts) / miningErr.Elapsed.Seconds(), nil
+}
+
+// EstimateDuration returns the expected time to reach a difficulty at a hash rate
+// Each attempt succeeds with probability 2^-difficulty, so it takes 2^difficulty
+// attempts on average (a run can take several times longer)
+func EstimateDuration(difficulty int, hashRate float64) time.Duration {
+	if difficulty <= 0 {
+		return 0
+	}
+	if hashRate <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+
+	seconds := math.Exp2(float64(difficulty)) / hashRate
+	if seconds >= math.MaxInt64/float64(time.Second) {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,38 @@
+package pow
+
+import "testing"
+
+func TestAdvisorDifficulty(t *testing.T) {
+	a := NewAdvisor()
+
+	// No relay yet: conservative
+	if got, want := a.Difficulty(1), GetPublishDifficulty(1); got != want {
+		t.Errorf("Difficulty(1) without relays = %d, want %d", got, want)
+	}
+
+	a.SetRelayRequirement("wss://free", 0)
+	a.SetRelayUnknown("wss://slow")
+	if got, want := a.Difficulty(7), GetPublishDifficulty(7); got != want {
+		t.Errorf("Difficulty(7) with an unknown relay = %d, want %d", got, want)
+	}
+
+	// Every requirement known: exactly what the strictest relay asks for
+	a.SetRelayRequirement("wss://slow", 0)
+	for _, kind := range []int{0, 1, 3, 4, 5, 7, 1059} {
+		if got := a.Difficulty(kind); got != 0 {
+			t.Errorf("Difficulty(%d) with relays requiring no PoW = %d, want 0", kind, got)
+		}
+	}
+
+	a.SetRelayRequirement("wss://strict", 8)
+	for _, kind := range []int{1, 7} {
+		if got := a.Difficulty(kind); got != 8 {
+			t.Errorf("Difficulty(%d) = %d, want 8", kind, got)
+		}
+	}
+
+	a.RemoveRelay("wss://strict")
+	if got := a.Difficulty(1); got != 0 {
+		t.Errorf("Difficulty(1) after removing the strict relay = %d, want 0", got)
+	}
+}
@@ -1,17 +1,188 @@
 package pow
 
 import (
+	"context"
+	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/bits"
+	"runtime"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/nbd-wtf/go-nostr"
 )
 
-// MineEvent mines Nostr Event with PoW
-// Adheres to NIP-13 standard: Adjust nonce tag to make Event ID satisfy difficulty requirement
+const (
+	// DefaultProgressInterval is how often OnProgress is called
+	DefaultProgressInterval = 500 * time.Millisecond
+
+	// DefaultTimestampInterval is how often workers move CreatedAt to the current time
+	DefaultTimestampInterval = 5 * time.Second
+
+	// batchSize is how many attempts a worker makes between checks for
+	// cancellation, the attempt budget and timestamp refreshes
+	batchSize = 1024
+)
+
+// ErrAttemptBudget is returned (wrapped in a MiningError) when MaxAttempts runs out
+var ErrAttemptBudget = errors.New("attempt budget exhausted")
+
+// MiningError is returned when mining stops before the target difficulty was reached
+// Use errors.Is with context.Canceled, context.DeadlineExceeded or ErrAttemptBudget
+// to find out why
+type MiningError struct {
+	Err            error         // context.Canceled, context.DeadlineExceeded or ErrAttemptBudget
+	Attempts       int64         // Attempts made before stopping
+	Elapsed        time.Duration // Time spent mining
+	BestDifficulty int           // Highest difficulty reached
+}
+
+func (e *MiningError) Error() string {
+	return fmt.Sprintf("mining stopped after %d attempts (best difficulty %d): %v", e.Attempts, e.BestDifficulty, e.Err)
+}
+
+func (e *MiningError) Unwrap() error {
+	return e.Err
+}
+
+// Progress is a snapshot of a running miner
+type Progress struct {
+	Attempts       int64         // Attempts so far, across all workers
+	Elapsed        time.Duration // Time spent mining
+	HashRate       float64       // Attempts per second
+	BestDifficulty int           // Highest difficulty reached so far
+	Target         int           // Target difficulty
+}
+
+// Options configures Mine. The zero value mines on every CPU with no attempt budget
+type Options struct {
+	Workers           int            // Number of goroutines (0 = runtime.NumCPU())
+	MaxAttempts       int64          // Give up after about this many attempts (0 = unlimited)
+	OnProgress        func(Progress) // Called periodically from a separate goroutine, never after Mine returns (may be nil)
+	ProgressInterval  time.Duration  // How often OnProgress is called (0 = DefaultProgressInterval)
+	TimestampInterval time.Duration  // How often CreatedAt is refreshed (0 = DefaultTimestampInterval)
+	FixedTimestamp    bool           // Keep the event's CreatedAt (e.g. NIP-59 randomized timestamps)
+}
+
+// Result describes a successful mining run
+type Result struct {
+	Nonce    uint64        // Nonce that satisfied the difficulty
+	Attempts int64         // Attempts across all workers
+	Duration time.Duration // Time spent mining
+}
+
+// Mine mines a Nostr Event with PoW on several goroutines (NIP-13)
+// The nonce space is split across the workers, and each one refreshes
+// CreatedAt every TimestampInterval (unless FixedTimestamp) instead of on every attempt.
+// On success the event gets its nonce tag (moved to the end of the tags),
+// its CreatedAt and its ID; it still has to be signed
+//
+// Parameters:
+//   - ctx: context (cancel it, or give it a deadline, to stop mining)
+//   - event: the Nostr Event to mine (modified only on success)
+//   - targetDifficulty: target difficulty (number of leading zero bits in Event ID)
+//   - opts: worker count, attempt budget and progress reporting
+//
+// Returns:
+//   - *Result: winning nonce and statistics
+//   - error: *MiningError if ctx ended or the attempt budget ran out
+func Mine(ctx context.Context, event *nostr.Event, targetDifficulty int, opts Options) (*Result, error) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	timestampInterval := opts.TimestampInterval
+	if timestampInterval <= 0 {
+		timestampInterval = DefaultTimestampInterval
+	}
+
+	// Other tags are kept as they are, the nonce tag always goes last
+	baseTags := make(nostr.Tags, 0, len(event.Tags)+1)
+	for _, tag := range event.Tags {
+		if len(tag) > 0 && tag[0] == "nonce" {
+			continue
+		}
+		baseTags = append(baseTags, tag)
+	}
+
+	m := &miner{
+		event:      event,
+		baseTags:   baseTags,
+		target:     targetDifficulty,
+		workers:    uint64(workers),
+		budget:     opts.MaxAttempts,
+		refreshGap: timestampInterval,
+		fixedTime:  opts.FixedTimestamp,
+		start:      time.Now(),
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(worker uint64) {
+			defer wg.Done()
+			if m.work(ctx, worker) {
+				cancel() // Stop the other workers
+			}
+		}(uint64(w))
+	}
+
+	// Report progress until the workers are done
+	done := make(chan struct{})
+	reported := make(chan struct{})
+	if opts.OnProgress != nil {
+		interval := opts.ProgressInterval
+		if interval <= 0 {
+			interval = DefaultProgressInterval
+		}
+		go func() {
+			defer close(reported)
+			m.report(done, interval, opts.OnProgress)
+		}()
+	} else {
+		close(reported)
+	}
+
+	wg.Wait()
+	close(done)
+	<-reported // OnProgress is never called after Mine returns
+
+	elapsed := time.Since(m.start)
+	attempts := m.attempts.Load()
+
+	if m.winner == nil {
+		reason := ctx.Err()
+		if m.budgetExhausted.Load() {
+			reason = ErrAttemptBudget
+		}
+		return nil, &MiningError{
+			Err:            reason,
+			Attempts:       attempts,
+			Elapsed:        elapsed,
+			BestDifficulty: int(m.best.Load()),
+		}
+	}
+
+	event.Tags = append(baseTags, m.winner.nonceTag)
+	event.CreatedAt = m.winner.createdAt
+	event.ID = m.winner.id
+
+	return &Result{
+		Nonce:    m.winner.nonce,
+		Attempts: attempts,
+		Duration: elapsed,
+	}, nil
+}
+
+// MineEvent mines Nostr Event with PoW on every CPU, without a time limit
+// Kept for callers that can't cancel; prefer Mine
 //
 // Parameters:
 //   - event: the Nostr Event to mine (will be modified)
@@ -24,64 +195,160 @@ import (
 //   - error: error information
 func MineEvent(event *nostr.Event, targetDifficulty int) (int, int, time.Duration, error) {
 	fmt.Printf("\n⛏️  Mining PoW... (Target difficulty: %d leading zeros)\n", targetDifficulty)
-	start := time.Now()
 
-	nonce := 0
-	attempts := 0
+	result, err := Mine(context.Background(), event, targetDifficulty, Options{})
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	fmt.Printf("✅ Mining success! Nonce %d after %d attempts in %v\n", result.Nonce, result.Attempts, result.Duration)
+	return int(result.Nonce), int(result.Attempts), result.Duration, nil
+}
+
+// miner is the state shared by the workers of one Mine call
+type miner struct {
+	event      *nostr.Event
+	baseTags   nostr.Tags
+	target     int
+	workers    uint64
+	budget     int64
+	refreshGap time.Duration
+	fixedTime  bool
+	start      time.Time
+
+	attempts        atomic.Int64
+	best            atomic.Int64
+	budgetExhausted atomic.Bool
+
+	winnerOnce sync.Once
+	winner     *solution
+}
+
+// solution is the winning nonce and the event fields it was hashed with
+type solution struct {
+	nonce     uint64
+	nonceTag  nostr.Tag
+	createdAt nostr.Timestamp
+	id        string
+}
+
+// work tries nonces worker, worker+workers, worker+2*workers, ...
+// Returns true if this worker found the solution
+func (m *miner) work(ctx context.Context, worker uint64) bool {
+	targetStr := strconv.Itoa(m.target)
+	createdAt := nostr.Now()
+	if m.fixedTime {
+		createdAt = m.event.CreatedAt
+	}
+	prefix, suffix := m.template(createdAt, targetStr)
+	refreshed := time.Now()
+
+	buf := make([]byte, 0, len(prefix)+20+len(suffix))
+	nonce := worker
+	localBest := 0
 
 	for {
-		// 1. Build the nonce tag we want to use
-		nonceTag := nostr.Tag{
-			"nonce",
-			strconv.Itoa(nonce),
-			strconv.Itoa(targetDifficulty),
-		}
+		for i := 0; i < batchSize; i++ {
+			buf = append(buf[:0], prefix...)
+			buf = strconv.AppendUint(buf, nonce, 10)
+			buf = append(buf, suffix...)
+			hash := sha256.Sum256(buf)
+
+			difficulty := countLeadingZeroBits(hash[:])
+			if difficulty > localBest {
+				localBest = difficulty
+				m.raiseBest(difficulty)
+			}
 
-		// 2. Check if there's already a nonce tag in existing Tags
-		// IMPORTANT: Don't clear all tags! We need to preserve other tags like:
-		//   - ["p", "recipient_pubkey"] for Kind 4 (encrypted DM)
-		//   - ["e", "event_id"] for replies
-		//   - any other user-defined tags
-		found := false
-		for i, tag := range event.Tags {
-			if len(tag) > 0 && tag[0] == "nonce" {
-				// Found it! Replace its value, keep other tags intact
-				event.Tags[i] = nonceTag
-				found = true
-				break
+			if difficulty >= m.target {
+				m.attempts.Add(int64(i + 1))
+				won := false
+				m.winnerOnce.Do(func() {
+					m.winner = &solution{
+						nonce:     nonce,
+						nonceTag:  nostr.Tag{"nonce", strconv.FormatUint(nonce, 10), targetStr},
+						createdAt: createdAt,
+						id:        hex.EncodeToString(hash[:]),
+					}
+					won = true
+				})
+				return won
 			}
+
+			nonce += m.workers
+		}
+
+		total := m.attempts.Add(batchSize)
+		if m.budget > 0 && total >= m.budget {
+			m.budgetExhausted.Store(true)
+			return false
 		}
 
-		// 3. If not found (first iteration), append to the end
-		if !found {
-			event.Tags = append(event.Tags, nonceTag)
+		select {
+		case <-ctx.Done():
+			return false
+		default:
 		}
 
-		// 2. Calculate Event ID (without signing)
-		// Event ID is the SHA256 hash of the serialized Event
-		// go-nostr will automatically serialize and calculate ID according to Nostr standard
-		// Note: We only calculate ID here, signing will be done AFTER mining succeeds
-		event.CreatedAt = nostr.Timestamp(time.Now().Unix())
-		event.ID = event.GetID()
-
-		attempts++
-
-		// 3. Check difficulty
-		if CheckDifficulty(event.ID, targetDifficulty) {
-			duration := time.Since(start)
-			fmt.Printf("✅ Mining success!\n")
-			fmt.Printf("   Nonce: %d\n", nonce)
-			fmt.Printf("   Attempts: %d\n", attempts)
-			fmt.Printf("   Duration: %v\n", duration)
-			fmt.Printf("   Event ID: %s\n", event.ID)
-			return nonce, attempts, duration, nil
+		// Keep CreatedAt close to the time the event is published
+		if !m.fixedTime && time.Since(refreshed) >= m.refreshGap {
+			createdAt = nostr.Now()
+			prefix, suffix = m.template(createdAt, targetStr)
+			refreshed = time.Now()
+		}
+	}
+}
+
+// template serializes the event around the nonce value, so a worker only
+// has to put the nonce digits between prefix and suffix for each attempt
+func (m *miner) template(createdAt nostr.Timestamp, targetStr string) ([]byte, []byte) {
+	evt := nostr.Event{
+		PubKey:    m.event.PubKey,
+		CreatedAt: createdAt,
+		Kind:      m.event.Kind,
+		Tags:      append(m.baseTags[:len(m.baseTags):len(m.baseTags)], nostr.Tag{"nonce", "", targetStr}),
+		Content:   m.event.Content,
+	}
+	full := evt.Serialize()
+
+	// Without content the serialization ends with `","<target>"]],""]`,
+	// which locates the (empty) nonce value in the full serialization
+	evt.Content = ""
+	noContent := evt.Serialize()
+	pos := len(noContent) - len(`","`+targetStr+`"]],""]`)
+
+	return full[:pos], full[pos:]
+}
+
+// raiseBest records a new best difficulty if it beats the current one
+func (m *miner) raiseBest(difficulty int) {
+	for {
+		current := m.best.Load()
+		if int64(difficulty) <= current || m.best.CompareAndSwap(current, int64(difficulty)) {
+			return
 		}
+	}
+}
 
-		nonce++
+// report calls onProgress every interval until done is closed
+func (m *miner) report(done <-chan struct{}, interval time.Duration, onProgress func(Progress)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
-		// Print progress every 10000 attempts
-		if attempts%10000 == 0 {
-			fmt.Printf("   Attempts: %d...\n", attempts)
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			elapsed := time.Since(m.start)
+			attempts := m.attempts.Load()
+			onProgress(Progress{
+				Attempts:       attempts,
+				Elapsed:        elapsed,
+				HashRate:       float64(attempts) / elapsed.Seconds(),
+				BestDifficulty: int(m.best.Load()),
+				Target:         m.target,
+			})
 		}
 	}
 }
@@ -109,6 +376,35 @@ func CheckDifficulty(eventID string, difficulty int) bool {
 	return leadingZeros >= difficulty
 }
 
+// CommittedDifficulty returns the NIP-13 difficulty an event has committed to
+// That's the target in its nonce tag, as long as the ID reaches it; a lucky
+// hash above the target doesn't count, and an event without a nonce tag has none
+//
+// Parameters:
+//   - event: the event to check (its ID must already be verified)
+//
+// Returns:
+//   - int: committed difficulty (0 if none)
+func CommittedDifficulty(event *nostr.Event) int {
+	nonceTag := event.Tags.Find("nonce")
+	if len(nonceTag) < 3 {
+		return 0
+	}
+
+	target, err := strconv.Atoi(nonceTag[2])
+	if err != nil || target <= 0 {
+		return 0
+	}
+
+	idBytes, err := hex.DecodeString(event.ID)
+	if err != nil {
+		return 0
+	}
+
+	// A target the ID doesn't reach only counts for the work actually done
+	return min(target, countLeadingZeroBits(idBytes))
+}
+
 // countLeadingZeroBits counts the number of leading zeros in a byte array
 // This is the core algorithm of NIP-13 standard
 func countLeadingZeroBits(data []byte) int {
@@ -147,3 +443,29 @@ func GetDifficultyRecommendation(messageType string) int {
 		return 12
 	}
 }
+
+// GetKindCategory returns the message type of an event kind, as used by
+// GetDifficultyRecommendation ("private", "group" or "public")
+// Kinds without a PoW requirement (metadata, contacts, reactions...) return ""
+func GetKindCategory(kind int) string {
+	switch kind {
+	case nostr.KindEncryptedDirectMessage, nostr.KindGiftWrap:
+		return "private"
+	case nostr.KindSimpleGroupChatMessage, nostr.KindChannelMessage:
+		return "group"
+	case nostr.KindTextNote, nostr.KindRepost, nostr.KindGenericRepost:
+		return "public"
+	default:
+		return ""
+	}
+}
+
+// GetKindDifficulty returns the recommended difficulty for an event kind
+// (0 for kinds without a PoW requirement)
+func GetKindDifficulty(kind int) int {
+	category := GetKindCategory(kind)
+	if category == "" {
+		return 0
+	}
+	return GetDifficultyRecommendation(category)
+}
@@ -0,0 +1,290 @@
+package pow
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// testPubKey is any valid public key; mining doesn't need a signature
+const testPubKey = "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+
+func TestTemplateSurroundsNonce(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    int
+		tags    nostr.Tags
+		content string
+		target  int
+	}{
+		{"empty content", 1, nil, "", 8},
+		{"plain text", 1, nil, "hello nostr", 16},
+		{"escaped characters", 1, nil, "quote \" backslash \\ newline \n tab \t", 20},
+		{"looks like the suffix", 1, nil, `","20"]],""]`, 20},
+		{"unicode", 1, nil, "でんでん 🐢", 4},
+		{"with tags", 1, nostr.Tags{{"e", strings.Repeat("a", 64), "", "root"}, {"t", "denden"}}, "reply", 12},
+		{"DM kind", 4, nostr.Tags{{"p", testPubKey}}, "ciphertext?iv=abc", 12},
+		{"three-digit target", 1, nil, "x", 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &miner{
+				event:    &nostr.Event{PubKey: testPubKey, Kind: tt.kind, Content: tt.content},
+				baseTags: tt.tags,
+			}
+			targetStr := strconv.Itoa(tt.target)
+			createdAt := nostr.Timestamp(1700000000)
+			prefix, suffix := m.template(createdAt, targetStr)
+
+			for _, nonce := range []uint64{0, 7, 12345, 18446744073709551615} {
+				nonceStr := strconv.FormatUint(nonce, 10)
+				want := nostr.Event{
+					PubKey:    testPubKey,
+					CreatedAt: createdAt,
+					Kind:      tt.kind,
+					Tags:      append(append(nostr.Tags{}, tt.tags...), nostr.Tag{"nonce", nonceStr, targetStr}),
+					Content:   tt.content,
+				}
+
+				got := string(prefix) + nonceStr + string(suffix)
+				if got != string(want.Serialize()) {
+					t.Fatalf("nonce %d:\n got %s\nwant %s", nonce, got, want.Serialize())
+				}
+			}
+
+			// The template must not touch the caller's tags
+			if len(m.baseTags) != len(tt.tags) {
+				t.Errorf("template appended to baseTags")
+			}
+		})
+	}
+}
+
+func TestMine(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    nostr.Tags
+		target  int
+		workers int
+		fixed   bool
+	}{
+		{"one worker", nil, 8, 1, false},
+		{"several workers", nil, 10, 4, false},
+		{"keeps other tags", nostr.Tags{{"t", "a"}, {"p", testPubKey}}, 8, 2, false},
+		{"replaces an old nonce tag", nostr.Tags{{"nonce", "999", "30"}, {"t", "a"}}, 8, 2, false},
+		{"fixed timestamp", nil, 8, 2, true},
+		{"zero difficulty", nil, 0, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			createdAt := nostr.Timestamp(1600000000)
+			event := &nostr.Event{
+				PubKey:    testPubKey,
+				CreatedAt: createdAt,
+				Kind:      1,
+				Tags:      append(nostr.Tags{}, tt.tags...),
+				Content:   "mine me",
+			}
+
+			result, err := Mine(context.Background(), event, tt.target, Options{
+				Workers:        tt.workers,
+				FixedTimestamp: tt.fixed,
+			})
+			if err != nil {
+				t.Fatalf("Mine: %v", err)
+			}
+
+			if event.ID != event.GetID() {
+				t.Fatalf("ID %s doesn't match the serialized event (%s)", event.ID, event.GetID())
+			}
+			if !CheckDifficulty(event.ID, tt.target) {
+				t.Errorf("ID %s doesn't reach difficulty %d", event.ID, tt.target)
+			}
+
+			nonceTag := event.Tags[len(event.Tags)-1]
+			if nonceTag[0] != "nonce" || nonceTag[1] != strconv.FormatUint(result.Nonce, 10) || nonceTag[2] != strconv.Itoa(tt.target) {
+				t.Errorf("last tag = %v, want nonce %d with target %d", nonceTag, result.Nonce, tt.target)
+			}
+			nonces := 0
+			for _, tag := range event.Tags {
+				if tag[0] == "nonce" {
+					nonces++
+				}
+			}
+			if nonces != 1 || len(event.Tags) != len(tt.tags)-countNonceTags(tt.tags)+1 {
+				t.Errorf("tags = %v", event.Tags)
+			}
+
+			if tt.fixed && event.CreatedAt != createdAt {
+				t.Errorf("CreatedAt = %d, want the fixed %d", event.CreatedAt, createdAt)
+			}
+			if !tt.fixed && event.CreatedAt == createdAt {
+				t.Errorf("CreatedAt wasn't moved to the mining time")
+			}
+			if result.Attempts < 1 {
+				t.Errorf("Attempts = %d", result.Attempts)
+			}
+			if CommittedDifficulty(event) != tt.target {
+				t.Errorf("CommittedDifficulty = %d, want %d", CommittedDifficulty(event), tt.target)
+			}
+		})
+	}
+}
+
+// countNonceTags counts the nonce tags Mine drops
+func countNonceTags(tags nostr.Tags) int {
+	n := 0
+	for _, tag := range tags {
+		if tag[0] == "nonce" {
+			n++
+		}
+	}
+	return n
+}
+
+func TestMineStops(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		timeout time.Duration
+		want    error
+	}{
+		{"attempt budget", Options{Workers: 2, MaxAttempts: 10 * batchSize}, time.Minute, ErrAttemptBudget},
+		{"deadline", Options{Workers: 2}, 50 * time.Millisecond, context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+
+			event := &nostr.Event{PubKey: testPubKey, CreatedAt: 1600000000, Kind: 1, Content: "never"}
+			original := *event
+
+			// 256 bits can't be reached, so only the budget or the context stops it
+			result, err := Mine(ctx, event, 256, tt.opts)
+			if err == nil {
+				t.Fatalf("Mine succeeded with nonce %d", result.Nonce)
+			}
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+
+			var miningErr *MiningError
+			if !errors.As(err, &miningErr) {
+				t.Fatalf("err is %T, want *MiningError", err)
+			}
+			if miningErr.Attempts <= 0 || miningErr.BestDifficulty <= 0 || miningErr.Elapsed <= 0 {
+				t.Errorf("MiningError = %+v", miningErr)
+			}
+			if tt.opts.MaxAttempts > 0 {
+				// Workers check the budget every batch, so they may overshoot by one batch each
+				limit := tt.opts.MaxAttempts + int64(tt.opts.Workers)*batchSize
+				if miningErr.Attempts < tt.opts.MaxAttempts || miningErr.Attempts > limit {
+					t.Errorf("Attempts = %d, want between %d and %d", miningErr.Attempts, tt.opts.MaxAttempts, limit)
+				}
+			}
+
+			if event.ID != "" || event.CreatedAt != original.CreatedAt || len(event.Tags) != 0 {
+				t.Errorf("event was modified: %+v", event)
+			}
+		})
+	}
+}
+
+func TestMineReportsProgress(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	progress := make(chan Progress, 100)
+	event := &nostr.Event{PubKey: testPubKey, Kind: 1, Content: "slow"}
+	Mine(ctx, event, 256, Options{
+		Workers:          1,
+		ProgressInterval: 10 * time.Millisecond,
+		OnProgress: func(p Progress) {
+			select {
+			case progress <- p:
+			default:
+			}
+		},
+	})
+	close(progress)
+
+	var last Progress
+	count := 0
+	for p := range progress {
+		if p.Attempts < last.Attempts {
+			t.Errorf("attempts went down: %d after %d", p.Attempts, last.Attempts)
+		}
+		if p.Target != 256 {
+			t.Errorf("Target = %d, want 256", p.Target)
+		}
+		last = p
+		count++
+	}
+	if count == 0 {
+		t.Fatal("OnProgress was never called")
+	}
+	if last.HashRate <= 0 {
+		t.Errorf("HashRate = %f", last.HashRate)
+	}
+}
+
+func TestCommittedDifficulty(t *testing.T) {
+	zeros := func(n int) string { return strings.Repeat("0", n) }
+	id := func(prefix string) string { return prefix + strings.Repeat("f", 64-len(prefix)) }
+
+	tests := []struct {
+		name string
+		id   string
+		tags nostr.Tags
+		want int
+	}{
+		{"no nonce tag", id(zeros(4)), nil, 0},
+		{"nonce without target", id(zeros(4)), nostr.Tags{{"nonce", "1"}}, 0},
+		{"target reached", id(zeros(4)), nostr.Tags{{"nonce", "1", "16"}}, 16},
+		{"lucky hash above the target", id(zeros(6)), nostr.Tags{{"nonce", "1", "16"}}, 16},
+		{"target not reached", id(zeros(2)), nostr.Tags{{"nonce", "1", "16"}}, 8},
+		{"partial nibble", id("000" + "7"), nostr.Tags{{"nonce", "1", "20"}}, 13},
+		{"invalid target", id(zeros(4)), nostr.Tags{{"nonce", "1", "lots"}}, 0},
+		{"negative target", id(zeros(4)), nostr.Tags{{"nonce", "1", "-3"}}, 0},
+		{"invalid ID", "not hex", nostr.Tags{{"nonce", "1", "8"}}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &nostr.Event{ID: tt.id, Tags: tt.tags}
+			if got := CommittedDifficulty(event); got != tt.want {
+				t.Errorf("CommittedDifficulty = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckDifficulty(t *testing.T) {
+	tests := []struct {
+		id         string
+		difficulty int
+		want       bool
+	}{
+		{"00ff", 8, true},
+		{"00ff", 9, false},
+		{"0fff", 4, true},
+		{"0fff", 5, false},
+		{"0000", 16, true},
+		{"zz", 0, false},
+	}
+
+	for _, tt := range tests {
+		if got := CheckDifficulty(tt.id, tt.difficulty); got != tt.want {
+			t.Errorf("CheckDifficulty(%s, %d) = %v, want %v", tt.id, tt.difficulty, got, tt.want)
+		}
+	}
+}
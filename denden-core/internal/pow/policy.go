@@ -0,0 +1,71 @@
+package pow
+
+import (
+	"sort"
+	"sync"
+)
+
+// Policy maps event kinds to a PoW difficulty
+// Kinds without an override use the defaults function
+type Policy struct {
+	mu        sync.RWMutex
+	defaults  func(kind int) int
+	overrides map[int]int // kind -> difficulty
+}
+
+// NewPolicy creates a policy
+// Parameters:
+//   - defaults: difficulty for kinds without an override (e.g. GetKindDifficulty)
+func NewPolicy(defaults func(kind int) int) *Policy {
+	return &Policy{
+		defaults:  defaults,
+		overrides: make(map[int]int),
+	}
+}
+
+// Difficulty returns the difficulty for a kind
+func (p *Policy) Difficulty(kind int) int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if difficulty, ok := p.overrides[kind]; ok {
+		return difficulty
+	}
+	return p.defaults(kind)
+}
+
+// Set overrides the difficulty for a kind (0 disables PoW for it)
+func (p *Policy) Set(kind, difficulty int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.overrides[kind] = max(difficulty, 0)
+}
+
+// Reset goes back to the default difficulty for a kind
+func (p *Policy) Reset(kind int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.overrides, kind)
+}
+
+// Overrides returns the kinds with an override, sorted
+func (p *Policy) Overrides() []int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	kinds := make([]int, 0, len(p.overrides))
+	for kind := range p.overrides {
+		kinds = append(kinds, kind)
+	}
+	sort.Ints(kinds)
+	return kinds
+}
+
+// GetPublishDifficulty returns the difficulty to mine an outgoing event of a kind with
+// Every kind gets PoW: kinds outside the private/group/public categories
+// (metadata, contacts, reactions, deletions...) use the default recommendation
+func GetPublishDifficulty(kind int) int {
+	return GetDifficultyRecommendation(GetKindCategory(kind))
+}
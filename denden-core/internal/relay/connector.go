@@ -3,15 +3,47 @@ package relay
 import (
 	"context"
 	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/nbd-wtf/go-nostr"
 )
 
-// Relay represents a connection to a Nostr relay
+// ConnectionState describes the state of a supervised relay connection
+type ConnectionState string
+
+const (
+	StateConnecting ConnectionState = "connecting" // Dialing (first connect or reconnect)
+	StateConnected  ConnectionState = "connected"  // WebSocket is up
+	StateLost       ConnectionState = "lost"       // Connection dropped or dial failed
+)
+
+// StateHandler is called whenever a relay connection changes state
+type StateHandler func(relayURL string, state ConnectionState)
+
+// Reconnect backoff settings
+// The delay doubles after every failed attempt, capped at maxBackoff,
+// and half of it is randomized so clients don't reconnect in lockstep
+const (
+	initialBackoff = 1 * time.Second
+	maxBackoff     = 60 * time.Second
+)
+
+// Relay represents a supervised connection to a Nostr relay
+// If the WebSocket drops, it reconnects with exponential backoff
+// and replays active subscriptions
 type Relay struct {
-	*nostr.Relay
-	url string
+	url     string
+	onState StateHandler
+
+	mu     sync.RWMutex
+	conn   *nostr.Relay  // Current connection (nil while reconnecting)
+	ready  chan struct{} // Closed while conn is usable, replaced when the connection drops
+	state  ConnectionState
+	closed bool
+	done   chan struct{} // Closed by Close()
 }
 
 // Connect connects to a Nostr relay
@@ -24,24 +56,239 @@ type Relay struct {
 //   - *Relay: relay connection object
 //   - error: connection error
 func Connect(relayURL string) (*Relay, error) {
+	return ConnectWithHandler(relayURL, nil)
+}
+
+// ConnectWithHandler connects to a Nostr relay and reports state changes
+// The first connection must succeed; after that, dropped connections are
+// re-established in the background
+//
+// Parameters:
+//   - relayURL: WebSocket URL of the relay (e.g., "wss://relay.damus.io")
+//   - onState: called on every connection state change (may be nil)
+//
+// Returns:
+//   - *Relay: relay connection object
+//   - error: connection error
+func ConnectWithHandler(relayURL string, onState StateHandler) (*Relay, error) {
+	r, conn, err := connect(relayURL, onState)
+	if err != nil {
+		return nil, err
+	}
+
+	go r.supervise(conn)
+
+	return r, nil
+}
+
+// ConnectWithRetry connects to a Nostr relay like ConnectWithHandler, but a
+// failed first dial isn't final: the relay is returned along with the error
+// and keeps dialing in the background with the same backoff as a reconnect
+//
+// Parameters:
+//   - relayURL: WebSocket URL of the relay (e.g., "wss://relay.damus.io")
+//   - onState: called on every connection state change (may be nil)
+//
+// Returns:
+//   - *Relay: relay connection object (never nil; Close it to stop retrying)
+//   - error: error of the first dial (the relay is still retrying)
+func ConnectWithRetry(relayURL string, onState StateHandler) (*Relay, error) {
+	r, conn, err := connect(relayURL, onState)
+	if err != nil {
+		go r.retry()
+		return r, err
+	}
+
+	go r.supervise(conn)
+
+	return r, nil
+}
+
+// connect creates a relay and dials it once
+func connect(relayURL string, onState StateHandler) (*Relay, *nostr.Relay, error) {
+	r := &Relay{
+		url:     relayURL,
+		onState: onState,
+		ready:   make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
 	fmt.Printf("🔌 Connecting to relay: %s\n", relayURL)
+	r.setState(StateConnecting)
 
-	// Set connection timeout to 5 seconds
+	conn, err := r.dial()
+	if err != nil {
+		r.setState(StateLost)
+		return r, nil, fmt.Errorf("Connection failed: %w", err)
+	}
+
+	fmt.Printf("✅ Connected to relay: %s\n", relayURL)
+	r.setConn(conn)
+	return r, conn, nil
+}
+
+// dial opens a new WebSocket connection with a 5 second timeout
+func (r *Relay) dial() (*nostr.Relay, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	// Use go-nostr library to connect to relay
-	relay, err := nostr.RelayConnect(ctx, relayURL)
-	if err != nil {
-		return nil, fmt.Errorf("Connection failed: %w", err)
+	return nostr.RelayConnect(ctx, r.url)
+}
+
+// supervise waits for the connection to drop and reconnects
+// Runs until Close() is called
+func (r *Relay) supervise(conn *nostr.Relay) {
+	for {
+		select {
+		case <-conn.Context().Done():
+		case <-r.done:
+			return
+		}
+
+		if r.isClosed() {
+			return
+		}
+
+		fmt.Printf("⚠️  Lost connection to relay: %s\n", r.url)
+		r.clearConn()
+
+		conn = r.reconnect()
+		if conn == nil {
+			return
+		}
+	}
+}
+
+// retry keeps dialing a relay whose first connection failed,
+// then supervises it like any other connection
+func (r *Relay) retry() {
+	conn := r.reconnect()
+	if conn == nil {
+		return
 	}
+	r.supervise(conn)
+}
 
-	fmt.Printf("✅ Connected to relay: %s\n", relayURL)
+// reconnect dials until it succeeds, backing off exponentially with jitter
+// Returns nil if the relay was closed in the meantime
+func (r *Relay) reconnect() *nostr.Relay {
+	backoff := initialBackoff
+
+	for attempt := 1; ; attempt++ {
+		// Equal jitter: wait between backoff/2 and backoff
+		delay := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		fmt.Printf("🔄 Reconnecting to %s in %v (attempt %d)\n", r.url, delay.Round(time.Millisecond), attempt)
+
+		select {
+		case <-time.After(delay):
+		case <-r.done:
+			return nil
+		}
+
+		r.setState(StateConnecting)
+		conn, err := r.dial()
+		if err == nil {
+			if r.isClosed() {
+				conn.Close()
+				return nil
+			}
+			fmt.Printf("✅ Reconnected to relay: %s\n", r.url)
+			r.setConn(conn)
+			return conn
+		}
 
-	return &Relay{
-		Relay: relay,
-		url:   relayURL,
-	}, nil
+		r.setState(StateLost)
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// setConn installs a live connection and wakes up waiting subscriptions
+func (r *Relay) setConn(conn *nostr.Relay) {
+	r.mu.Lock()
+	r.conn = conn
+	close(r.ready)
+	r.mu.Unlock()
+
+	r.setState(StateConnected)
+}
+
+// clearConn drops the current connection and marks the relay as lost
+func (r *Relay) clearConn() {
+	r.mu.Lock()
+	r.conn = nil
+	r.ready = make(chan struct{})
+	r.mu.Unlock()
+
+	r.setState(StateLost)
+}
+
+// setState records the new state and notifies the handler
+func (r *Relay) setState(state ConnectionState) {
+	r.mu.Lock()
+	r.state = state
+	r.mu.Unlock()
+
+	if r.onState != nil {
+		r.onState(r.url, state)
+	}
+}
+
+// isClosed reports whether Close() has been called
+func (r *Relay) isClosed() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.closed
+}
+
+// current returns the live connection, or an error while reconnecting
+func (r *Relay) current() (*nostr.Relay, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.closed {
+		return nil, fmt.Errorf("relay %s is closed", r.url)
+	}
+	if r.conn == nil || !r.conn.IsConnected() {
+		return nil, fmt.Errorf("relay %s is reconnecting", r.url)
+	}
+	return r.conn, nil
+}
+
+// waitConnected blocks until a live connection is available
+// Returns false if ctx is done or the relay was closed
+func (r *Relay) waitConnected(ctx context.Context) (*nostr.Relay, bool) {
+	for {
+		r.mu.RLock()
+		conn, ready, closed := r.conn, r.ready, r.closed
+		r.mu.RUnlock()
+
+		if closed {
+			return nil, false
+		}
+		if conn != nil && conn.IsConnected() {
+			return conn, true
+		}
+
+		// A dead conn that the supervisor hasn't cleared yet still has
+		// a closed ready channel, so poll briefly instead of spinning
+		wake := (<-chan time.Time)(nil)
+		if conn != nil {
+			wake = time.After(100 * time.Millisecond)
+		}
+
+		select {
+		case <-ready:
+		case <-wake:
+		case <-ctx.Done():
+			return nil, false
+		case <-r.done:
+			return nil, false
+		}
+	}
 }
 
 // Publish publishes an Event to the relay
@@ -59,7 +306,7 @@ func (r *Relay) Publish(ctx context.Context, event *nostr.Event) error {
 	fmt.Printf("   Content: %s\n", event.Content)
 
 	// Publish Event to relay
-	err := r.Relay.Publish(ctx, *event)
+	err := r.send(ctx, event)
 	if err != nil {
 		return fmt.Errorf("Publish failed: %w", err)
 	}
@@ -68,44 +315,162 @@ func (r *Relay) Publish(ctx context.Context, event *nostr.Event) error {
 	return nil
 }
 
+// send publishes an Event on the current connection without logging
+func (r *Relay) send(ctx context.Context, event *nostr.Event) error {
+	conn, err := r.current()
+	if err != nil {
+		return err
+	}
+	return conn.Publish(ctx, *event)
+}
+
+// QuerySync fetches stored events matching the filter and waits for EOSE
+//
+// Parameters:
+//   - ctx: context (for timeout control)
+//   - filter: filter conditions
+//
+// Returns:
+//   - []*nostr.Event: matching events
+//   - error: query error
+func (r *Relay) QuerySync(ctx context.Context, filter nostr.Filter) ([]*nostr.Event, error) {
+	conn, err := r.current()
+	if err != nil {
+		return nil, err
+	}
+	return conn.QuerySync(ctx, filter)
+}
+
 // Subscribe subscribes to events that match the given filters
 // This function is used to receive messages
+// The subscription survives reconnects: after the connection comes back,
+// it is replayed with `since` set to the newest event already received
+// (two days earlier for gift wraps, see resumeFilters)
 //
 // Parameters:
 //   - ctx: context (for canceling subscription)
 //   - filters: filter conditions (e.g., subscribe to all messages from a specific author)
 //
 // Returns:
-//   - chan *nostr.Event: Event receive channel
+//   - chan *nostr.Event: Event receive channel (closed when ctx is done or the relay is closed)
 //   - error: subscription error
 func (r *Relay) Subscribe(ctx context.Context, filters []nostr.Filter) (chan *nostr.Event, error) {
 	fmt.Printf("\n📥 Subscribing to events...\n")
 
-	// Subscribe to events
-	sub, err := r.Relay.Subscribe(ctx, filters)
-	if err != nil {
-		return nil, fmt.Errorf("Subscription failed: %w", err)
+	if r.isClosed() {
+		return nil, fmt.Errorf("Subscription failed: relay %s is closed", r.url)
 	}
 
 	// Create Event receive channel
 	eventChan := make(chan *nostr.Event, 10)
 
 	// Start goroutine to receive Event
-	go func() {
-		defer close(eventChan)
-		for event := range sub.Events {
-			eventChan <- event
-		}
-	}()
+	go r.runSubscription(ctx, filters, eventChan)
 
 	fmt.Printf("✅ Subscription successful!\n")
 	return eventChan, nil
 }
 
-// Close closes the connection to the relay
+// runSubscription keeps a subscription alive across reconnects
+// and forwards its events to eventChan
+func (r *Relay) runSubscription(ctx context.Context, filters []nostr.Filter, eventChan chan *nostr.Event) {
+	defer close(eventChan)
+
+	var lastSeen nostr.Timestamp
+
+	for {
+		conn, ok := r.waitConnected(ctx)
+		if !ok {
+			return
+		}
+
+		sub, err := conn.Subscribe(ctx, resumeFilters(filters, lastSeen))
+		if err != nil {
+			if conn.IsConnected() {
+				// The relay refused the REQ, retrying won't help
+				return
+			}
+			continue
+		}
+
+		for event := range sub.Events {
+			if event.CreatedAt > lastSeen {
+				lastSeen = event.CreatedAt
+			}
+
+			select {
+			case eventChan <- event:
+			case <-ctx.Done():
+				sub.Unsub()
+				return
+			}
+		}
+
+		// sub.Events closes when ctx is done, the relay sent CLOSED,
+		// or the connection dropped; only the last one is worth replaying
+		if ctx.Err() != nil || r.isClosed() || conn.IsConnected() {
+			return
+		}
+	}
+}
+
+// giftWrapBackdate is how far in the past a NIP-59 gift wrap (Kind 1059) can
+// be dated: its created_at is randomized so it doesn't reveal when the message was sent
+const giftWrapBackdate = 2 * 24 * 60 * 60
+
+// resumeFilters copies filters with `since` moved forward to lastSeen
+// so a replayed subscription doesn't re-deliver everything
+// Filters that can match gift wraps start giftWrapBackdate before lastSeen instead: a
+// wrap sent while the connection was down can be dated that far back. The
+// events delivered again are dropped by the pool's deduplication
+func resumeFilters(filters []nostr.Filter, lastSeen nostr.Timestamp) nostr.Filters {
+	resumed := make(nostr.Filters, len(filters))
+	for i, f := range filters {
+		resumed[i] = f.Clone()
+
+		since := lastSeen
+		if len(f.Kinds) == 0 || slices.Contains(f.Kinds, nostr.KindGiftWrap) {
+			since -= giftWrapBackdate
+		}
+		if lastSeen > 0 && since > 0 && (f.Since == nil || *f.Since < since) {
+			resumed[i].Since = &since
+		}
+	}
+	return resumed
+}
+
+// Close closes the connection to the relay and stops reconnecting
 func (r *Relay) Close() error {
 	fmt.Printf("🔌 Closing connection to relay...\n")
-	return r.Relay.Close()
+
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	close(r.done)
+	conn := r.conn
+	r.conn = nil
+	r.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+	return conn.Close()
+}
+
+// IsConnected reports whether the relay currently has a live connection
+func (r *Relay) IsConnected() bool {
+	_, err := r.current()
+	return err == nil
+}
+
+// State returns the current connection state
+func (r *Relay) State() ConnectionState {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.state
 }
 
 // GetURL gets the URL of the relay
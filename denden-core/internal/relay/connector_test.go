@@ -0,0 +1,206 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// storeRelay is a minimal relay: it answers REQs with the stored events that
+// match, then EOSE, and can drop every connection to force a reconnect
+type storeRelay struct {
+	mu     sync.Mutex
+	events []*nostr.Event
+	conns  map[*websocket.Conn]bool
+	reqs   []nostr.Filters // Filters of every REQ received
+}
+
+// newStoreRelay starts a storeRelay
+func newStoreRelay(t *testing.T) (*storeRelay, *httptest.Server) {
+	t.Helper()
+
+	relay := &storeRelay{conns: make(map[*websocket.Conn]bool)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.CloseNow()
+
+		relay.mu.Lock()
+		relay.conns[conn] = true
+		relay.mu.Unlock()
+		defer func() {
+			relay.mu.Lock()
+			delete(relay.conns, conn)
+			relay.mu.Unlock()
+		}()
+
+		for {
+			_, message, err := conn.Read(r.Context())
+			if err != nil {
+				return
+			}
+			req, ok := nostr.ParseMessage(string(message)).(*nostr.ReqEnvelope)
+			if !ok {
+				continue
+			}
+
+			relay.mu.Lock()
+			relay.reqs = append(relay.reqs, req.Filters)
+			var matching []*nostr.Event
+			for _, event := range relay.events {
+				if req.Filters.Match(event) {
+					matching = append(matching, event)
+				}
+			}
+			relay.mu.Unlock()
+
+			for _, event := range matching {
+				subID := req.SubscriptionID
+				data, _ := json.Marshal(nostr.EventEnvelope{SubscriptionID: &subID, Event: *event})
+				conn.Write(r.Context(), websocket.MessageText, data)
+			}
+			data, _ := json.Marshal(nostr.EOSEEnvelope(req.SubscriptionID))
+			conn.Write(r.Context(), websocket.MessageText, data)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return relay, server
+}
+
+// store adds an event, sent to the next REQs
+func (s *storeRelay) store(event *nostr.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+}
+
+// dropConnections closes every open connection
+func (s *storeRelay) dropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		conn.CloseNow()
+	}
+}
+
+// reqCount returns the number of REQs received
+func (s *storeRelay) reqCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.reqs)
+}
+
+// giftWrap returns a signed Kind 1059 event for recipient, created at createdAt
+func giftWrap(t *testing.T, recipient string, createdAt nostr.Timestamp) *nostr.Event {
+	t.Helper()
+
+	event := &nostr.Event{
+		Kind:      nostr.KindGiftWrap,
+		CreatedAt: createdAt,
+		Tags:      nostr.Tags{{"p", recipient}},
+		Content:   "sealed",
+	}
+	if err := event.Sign(nostr.GeneratePrivateKey()); err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	return event
+}
+
+// waitFor reads events until one has the wanted ID
+func waitFor(t *testing.T, events chan *nostr.Event, id string) {
+	t.Helper()
+
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatalf("subscription closed before event %s", id)
+			}
+			if event.ID == id {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("event %s was never delivered", id)
+		}
+	}
+}
+
+func TestSubscribeDeliversBackdatedGiftWrapAfterReconnect(t *testing.T) {
+	store, server := newStoreRelay(t)
+	me := nostr.GeneratePrivateKey()
+
+	relay, err := Connect(wsURL(server))
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer relay.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	now := nostr.Now()
+	recent := giftWrap(t, me, now)
+	store.store(recent)
+
+	events, err := relay.Subscribe(ctx, []nostr.Filter{{
+		Kinds: []int{nostr.KindGiftWrap},
+		Tags:  nostr.TagMap{"p": {me}},
+	}})
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	waitFor(t, events, recent.ID)
+
+	// A message arrives while the connection is down, dated a day and a half back
+	backdated := giftWrap(t, me, now-36*60*60)
+	store.store(backdated)
+	store.dropConnections()
+
+	waitFor(t, events, backdated.ID)
+	if store.reqCount() < 2 {
+		t.Fatalf("REQs = %d, want the subscription replayed", store.reqCount())
+	}
+}
+
+func TestResumeFilters(t *testing.T) {
+	since := nostr.Timestamp(1000)
+	lastSeen := nostr.Timestamp(500_000)
+
+	tests := []struct {
+		name   string
+		filter nostr.Filter
+		want   nostr.Timestamp // 0: no since
+	}{
+		{"notes", nostr.Filter{Kinds: []int{1}}, lastSeen},
+		{"notes with an older since", nostr.Filter{Kinds: []int{1}, Since: &since}, lastSeen},
+		{"gift wraps", nostr.Filter{Kinds: []int{4, nostr.KindGiftWrap}}, lastSeen - giftWrapBackdate},
+		{"any kind", nostr.Filter{IDs: []string{"00"}}, lastSeen - giftWrapBackdate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resumed := resumeFilters([]nostr.Filter{tt.filter}, lastSeen)[0]
+			if resumed.Since == nil || *resumed.Since != tt.want {
+				t.Errorf("since = %v, want %d", resumed.Since, tt.want)
+			}
+		})
+	}
+
+	// Nothing seen yet, or less than the backdate: the original since stays
+	if resumed := resumeFilters([]nostr.Filter{{Kinds: []int{1}}}, 0)[0]; resumed.Since != nil {
+		t.Errorf("since = %d without events, want none", *resumed.Since)
+	}
+	gifts := nostr.Filter{Kinds: []int{nostr.KindGiftWrap}, Since: &since}
+	if resumed := resumeFilters([]nostr.Filter{gifts}, 5000)[0]; *resumed.Since != since {
+		t.Errorf("since = %d, want the filter's %d", *resumed.Since, since)
+	}
+}
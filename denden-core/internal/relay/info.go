@@ -0,0 +1,30 @@
+package relay
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nbd-wtf/go-nostr/nip11"
+)
+
+// FetchMinPowDifficulty reads a relay's minimum PoW from its NIP-11 document
+// (limitation.min_pow_difficulty)
+//
+// Parameters:
+//   - ctx: context (for timeout control)
+//   - relayURL: WebSocket URL of the relay
+//
+// Returns:
+//   - int: minimum difficulty (0 if the relay doesn't require PoW)
+//   - error: error if the document can't be fetched
+func FetchMinPowDifficulty(ctx context.Context, relayURL string) (int, error) {
+	info, err := nip11.Fetch(ctx, relayURL)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch relay information: %w", err)
+	}
+
+	if info.Limitation == nil {
+		return 0, nil
+	}
+	return info.Limitation.MinPowDifficulty, nil
+}
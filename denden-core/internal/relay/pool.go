@@ -0,0 +1,441 @@
+package relay
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// Pool manages connections to several relays at once
+// Events are published to every relay, and subscription/query results
+// from all relays are merged and de-duplicated by event ID
+type Pool struct {
+	mu      sync.RWMutex
+	relays  map[string]*Relay
+	order   []string     // URLs in the order they were added
+	onState StateHandler // Passed to every relay added after SetStateHandler
+}
+
+// PublishResult is the outcome of publishing an event to a single relay
+type PublishResult struct {
+	URL   string // Relay URL
+	OK    bool   // true if the relay accepted the event
+	Error error  // Rejection or connection error (nil if OK)
+}
+
+// NewPool creates an empty relay pool
+func NewPool() *Pool {
+	return &Pool{
+		relays: make(map[string]*Relay),
+	}
+}
+
+// ConnectPool connects to all given relays concurrently
+// Relays that fail to connect keep retrying in the background
+//
+// Parameters:
+//   - relayURLs: WebSocket URLs of the relays
+//
+// Returns:
+//   - *Pool: pool containing every relay
+//   - error: error if no relay could be reached
+func ConnectPool(relayURLs []string) (*Pool, error) {
+	p := NewPool()
+	if err := p.AddAll(relayURLs); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// SetStateHandler sets the handler that receives connection state changes
+// It applies to relays added afterwards, so call it before connecting
+func (p *Pool) SetStateHandler(onState StateHandler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onState = onState
+}
+
+// Add connects to a relay and adds it to the pool
+// A relay that can't be reached is added anyway and keeps retrying in the
+// background with backoff; the dial error is still returned
+// Adding a relay that is already in the pool is a no-op
+func (p *Pool) Add(relayURL string) error {
+	p.mu.RLock()
+	_, exists := p.relays[relayURL]
+	onState := p.onState
+	p.mu.RUnlock()
+	if exists {
+		return nil
+	}
+
+	r, err := ConnectWithRetry(relayURL, onState)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, exists := p.relays[relayURL]; exists {
+		// Lost a race with a concurrent Add, keep the first connection
+		r.Close()
+		return nil
+	}
+	p.relays[relayURL] = r
+	p.order = append(p.order, relayURL)
+	return err
+}
+
+// AddAll connects to all given relays concurrently
+// Relays that can't be reached yet stay in the pool and keep retrying
+// Returns an error only if no relay in the pool is connected; the relays
+// added by this call are removed again in that case
+func (p *Pool) AddAll(relayURLs []string) error {
+	if len(relayURLs) == 0 {
+		return fmt.Errorf("no relays given")
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(relayURLs))
+	for i, url := range relayURLs {
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			errs[i] = p.Add(url)
+		}(i, url)
+	}
+	wg.Wait()
+
+	var lastErr error
+	for _, err := range errs {
+		if err != nil {
+			lastErr = err
+		}
+	}
+
+	if p.Connected() == 0 {
+		for i, url := range relayURLs {
+			if errs[i] != nil {
+				p.remove(url)
+			}
+		}
+		return fmt.Errorf("failed to connect to any relay: %w", lastErr)
+	}
+	return nil
+}
+
+// remove closes a relay and takes it out of the pool
+func (p *Pool) remove(relayURL string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	r, exists := p.relays[relayURL]
+	if !exists {
+		return
+	}
+	r.Close()
+	delete(p.relays, relayURL)
+	for i, url := range p.order {
+		if url == relayURL {
+			p.order = append(p.order[:i], p.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// Relays returns the relays (connected or retrying) in the order they were added
+func (p *Pool) Relays() []*Relay {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	relays := make([]*Relay, 0, len(p.order))
+	for _, url := range p.order {
+		relays = append(relays, p.relays[url])
+	}
+	return relays
+}
+
+// URLs returns the URLs of the relays in the order they were added
+func (p *Pool) URLs() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	urls := make([]string, len(p.order))
+	copy(urls, p.order)
+	return urls
+}
+
+// Size returns the number of relays in the pool
+func (p *Pool) Size() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.order)
+}
+
+// Connected returns the number of relays in the pool with a live connection
+func (p *Pool) Connected() int {
+	connected := 0
+	for _, r := range p.Relays() {
+		if r.IsConnected() {
+			connected++
+		}
+	}
+	return connected
+}
+
+// PublishAll publishes an Event to every relay in the pool concurrently
+//
+// Parameters:
+//   - ctx: context (for timeout control)
+//   - event: signed Nostr Event to publish
+//
+// Returns:
+//   - []PublishResult: one result per relay, in pool order
+func (p *Pool) PublishAll(ctx context.Context, event *nostr.Event) []PublishResult {
+	relays := p.Relays()
+	results := make([]PublishResult, len(relays))
+
+	var wg sync.WaitGroup
+	for i, r := range relays {
+		wg.Add(1)
+		go func(i int, r *Relay) {
+			defer wg.Done()
+			err := r.send(ctx, event)
+			results[i] = PublishResult{
+				URL:   r.GetURL(),
+				OK:    err == nil,
+				Error: err,
+			}
+		}(i, r)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// Publish publishes an Event to every relay in the pool
+// Succeeds if at least one relay accepted the event
+//
+// Parameters:
+//   - ctx: context (for timeout control)
+//   - event: signed Nostr Event to publish
+//
+// Returns:
+//   - error: error if every relay rejected the event
+func (p *Pool) Publish(ctx context.Context, event *nostr.Event) error {
+	fmt.Printf("\n📤 Publishing Event %s to %d relays...\n", event.ID, p.Size())
+
+	results := p.PublishAll(ctx, event)
+
+	accepted := 0
+	var lastErr error
+	for _, res := range results {
+		if res.OK {
+			accepted++
+			fmt.Printf("   ✅ %s\n", res.URL)
+		} else {
+			lastErr = res.Error
+			fmt.Printf("   ❌ %s: %v\n", res.URL, res.Error)
+		}
+	}
+
+	if accepted == 0 {
+		if lastErr == nil {
+			return fmt.Errorf("Publish failed: no relays in pool")
+		}
+		return fmt.Errorf("Publish failed on all relays: %w", lastErr)
+	}
+
+	fmt.Printf("✅ Event accepted by %d/%d relays\n", accepted, len(results))
+	return nil
+}
+
+// Subscribe subscribes to events on every relay in the pool
+// Events from all relays are merged into one channel, de-duplicated by ID
+// (among the last maxSeenIDs events, so long-lived subscriptions stay bounded)
+// The channel is closed once every relay subscription has ended
+//
+// Parameters:
+//   - ctx: context (for canceling subscription)
+//   - filters: filter conditions
+//
+// Returns:
+//   - chan *nostr.Event: merged Event receive channel
+//   - error: error if no relay accepted the subscription
+func (p *Pool) Subscribe(ctx context.Context, filters []nostr.Filter) (chan *nostr.Event, error) {
+	var sources []chan *nostr.Event
+	var lastErr error
+	for _, r := range p.Relays() {
+		ch, err := r.Subscribe(ctx, filters)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		sources = append(sources, ch)
+	}
+
+	if len(sources) == 0 {
+		if lastErr == nil {
+			return nil, fmt.Errorf("Subscription failed: no relays in pool")
+		}
+		return nil, fmt.Errorf("Subscription failed on all relays: %w", lastErr)
+	}
+
+	eventChan := make(chan *nostr.Event, 10)
+
+	seen := newSeenIDs(maxSeenIDs)
+
+	var wg sync.WaitGroup
+	for _, src := range sources {
+		wg.Add(1)
+		go func(src chan *nostr.Event) {
+			defer wg.Done()
+			for event := range src {
+				if !seen.add(event.ID) {
+					continue
+				}
+
+				select {
+				case eventChan <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(src)
+	}
+
+	go func() {
+		wg.Wait()
+		close(eventChan)
+	}()
+
+	return eventChan, nil
+}
+
+// maxSeenIDs bounds the event IDs a subscription remembers for de-duplication
+// Copies of an event come from the other relays within seconds, and replays
+// after a reconnect (see resumeFilters) stay well within it
+const maxSeenIDs = 10000
+
+// seenIDs is a set of the last IDs added: past its capacity, the oldest are forgotten
+type seenIDs struct {
+	mu    sync.Mutex
+	ids   map[string]bool
+	order []string // Ring buffer of the IDs, in the order they were added
+	next  int      // Slot of order the next ID goes to
+}
+
+// newSeenIDs creates an empty set holding at most capacity IDs
+func newSeenIDs(capacity int) *seenIDs {
+	return &seenIDs{
+		ids:   make(map[string]bool, capacity),
+		order: make([]string, capacity),
+	}
+}
+
+// add records an ID
+// Returns: false if it was already in the set
+func (s *seenIDs) add(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ids[id] {
+		return false
+	}
+	if oldest := s.order[s.next]; oldest != "" {
+		delete(s.ids, oldest)
+	}
+	s.order[s.next] = id
+	s.next = (s.next + 1) % len(s.order)
+	s.ids[id] = true
+	return true
+}
+
+// QuerySync queries every relay in the pool and waits for all of them to finish
+// Results are de-duplicated by ID and sorted by created_at (newest first)
+// If filter.Limit is set, at most Limit events are returned
+//
+// Parameters:
+//   - ctx: context (for timeout control)
+//   - filter: filter conditions
+//
+// Returns:
+//   - []*nostr.Event: merged events
+//   - error: error if every relay failed
+func (p *Pool) QuerySync(ctx context.Context, filter nostr.Filter) ([]*nostr.Event, error) {
+	relays := p.Relays()
+	if len(relays) == 0 {
+		return nil, fmt.Errorf("Query failed: no relays in pool")
+	}
+
+	type queryResult struct {
+		events []*nostr.Event
+		err    error
+	}
+
+	results := make([]queryResult, len(relays))
+	var wg sync.WaitGroup
+	for i, r := range relays {
+		wg.Add(1)
+		go func(i int, r *Relay) {
+			defer wg.Done()
+			events, err := r.QuerySync(ctx, filter)
+			results[i] = queryResult{events: events, err: err}
+		}(i, r)
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	var merged []*nostr.Event
+	failed := 0
+	var lastErr error
+
+	for _, res := range results {
+		if res.err != nil {
+			failed++
+			lastErr = res.err
+			continue
+		}
+		for _, event := range res.events {
+			if seen[event.ID] {
+				continue
+			}
+			seen[event.ID] = true
+			merged = append(merged, event)
+		}
+	}
+
+	if failed == len(relays) {
+		return nil, fmt.Errorf("Query failed on all relays: %w", lastErr)
+	}
+
+	// Newest first, ID as tie-breaker so the order is deterministic
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].CreatedAt != merged[j].CreatedAt {
+			return merged[i].CreatedAt > merged[j].CreatedAt
+		}
+		return merged[i].ID < merged[j].ID
+	})
+
+	if filter.Limit > 0 && len(merged) > filter.Limit {
+		merged = merged[:filter.Limit]
+	}
+
+	return merged, nil
+}
+
+// Close closes every relay connection in the pool
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var lastErr error
+	for _, url := range p.order {
+		if err := p.relays[url].Close(); err != nil {
+			lastErr = err
+		}
+	}
+
+	p.relays = make(map[string]*Relay)
+	p.order = nil
+	return lastErr
+}
@@ -0,0 +1,154 @@
+package relay
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// newRelayServer starts a WebSocket server that accepts connections and
+// ignores every message, which is all a dial needs
+func newRelayServer(t *testing.T, listener net.Listener) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.CloseNow()
+		for {
+			if _, _, err := conn.Read(r.Context()); err != nil {
+				return
+			}
+		}
+	}))
+	if listener != nil {
+		server.Listener.Close()
+		server.Listener = listener
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// wsURL converts an httptest server URL to a WebSocket URL
+func wsURL(server *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+// freeAddress reserves a local port that nothing listens on yet
+func freeAddress(t *testing.T) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to reserve port: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+func TestPoolRetriesRelayThatFailedFirstDial(t *testing.T) {
+	up := newRelayServer(t, nil)
+	downAddr := freeAddress(t)
+	downURL := "ws://" + downAddr
+
+	p := NewPool()
+	defer p.Close()
+
+	if err := p.AddAll([]string{wsURL(up), downURL}); err != nil {
+		t.Fatalf("AddAll: %v", err)
+	}
+	if p.Size() != 2 {
+		t.Fatalf("Size = %d, want 2 (the unreachable relay stays in the pool)", p.Size())
+	}
+	if p.Connected() != 1 {
+		t.Fatalf("Connected = %d, want 1", p.Connected())
+	}
+
+	// The relay comes up later: the pool connects to it without another Add
+	listener, err := net.Listen("tcp", downAddr)
+	if err != nil {
+		t.Skipf("port %s was taken in the meantime: %v", downAddr, err)
+	}
+	newRelayServer(t, listener)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for p.Connected() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("relay %s was never retried", downURL)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestPoolAddAllFailsWhenNothingConnects(t *testing.T) {
+	p := NewPool()
+	defer p.Close()
+
+	err := p.AddAll([]string{"ws://" + freeAddress(t), "ws://" + freeAddress(t)})
+	if err == nil {
+		t.Fatal("AddAll succeeded without a reachable relay")
+	}
+	if p.Size() != 0 {
+		t.Fatalf("Size = %d, want 0 (failed relays are removed when nothing connects)", p.Size())
+	}
+}
+
+func TestPoolAddKeepsUnreachableRelay(t *testing.T) {
+	p := NewPool()
+	defer p.Close()
+
+	url := "ws://" + freeAddress(t)
+	if err := p.Add(url); err == nil {
+		t.Fatal("Add returned no error for an unreachable relay")
+	}
+	if urls := p.URLs(); len(urls) != 1 || urls[0] != url {
+		t.Fatalf("URLs = %v, want [%s]", urls, url)
+	}
+	if state := p.Relays()[0].State(); state == StateConnected {
+		t.Fatalf("State = %s for an unreachable relay", state)
+	}
+
+	// Adding it again is still a no-op
+	if err := p.Add(url); err != nil {
+		t.Fatalf("second Add: %v", err)
+	}
+	if p.Size() != 1 {
+		t.Fatalf("Size = %d, want 1", p.Size())
+	}
+}
+
+func TestSeenIDsForgetsOldest(t *testing.T) {
+	seen := newSeenIDs(3)
+
+	for _, id := range []string{"a", "b", "c"} {
+		if !seen.add(id) {
+			t.Fatalf("add(%s) = false for a new ID", id)
+		}
+	}
+	if seen.add("b") {
+		t.Fatal("add(b) = true for an ID already seen")
+	}
+
+	// A fourth ID pushes out the oldest
+	if !seen.add("d") {
+		t.Fatal("add(d) = false for a new ID")
+	}
+	if len(seen.ids) != 3 {
+		t.Fatalf("set holds %d IDs, want 3", len(seen.ids))
+	}
+	if seen.add("c") || seen.add("d") {
+		t.Fatal("recent IDs were forgotten")
+	}
+	if !seen.add("a") {
+		t.Fatal("add(a) = false, want the oldest ID forgotten")
+	}
+}
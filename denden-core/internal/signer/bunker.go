@@ -0,0 +1,549 @@
+package signer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"denden-core/internal/crypto"
+	"denden-core/internal/relay"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip44"
+)
+
+// KindNostrConnect is the event kind carrying NIP-46 requests and responses
+const KindNostrConnect = 24133
+
+// BunkerSession is everything needed to talk to a remote signer again after a restart
+// The client key only identifies this app to the signer, it is not the user's key
+type BunkerSession struct {
+	ClientSecretKey    string   `json:"client_secret_key"`    // Our throwaway key for NIP-46 traffic (hex)
+	RemoteSignerPubKey string   `json:"remote_signer_pubkey"` // Key the remote signer answers with (hex)
+	UserPubKey         string   `json:"user_pubkey"`          // The user's public key (hex)
+	Relays             []string `json:"relays"`               // Relays the signer listens on
+}
+
+// BunkerSigner is a Signer that forwards every request to a NIP-46 remote signer
+// Requests and responses are NIP-44 encrypted Kind 24133 events
+type BunkerSigner struct {
+	session      BunkerSession
+	clientPubKey string
+	pool         *relay.Pool
+	onAuth       func(authURL string) // Called when the signer asks the user to approve in a browser
+
+	idPrefix string // Random per instance, so a restored session never reuses an old request ID
+	serial   atomic.Uint64
+	mu       sync.Mutex
+	pending  map[string]chan bunkerResponse // Request ID -> waiting caller
+
+	handshakeSecret string      // nostrconnect:// secret we wait for (empty if not pairing)
+	handshake       chan string // Receives the remote signer's pubkey once paired
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// bunkerRequest is a NIP-46 JSON-RPC request
+type bunkerRequest struct {
+	ID     string   `json:"id"`
+	Method string   `json:"method"`
+	Params []string `json:"params"`
+}
+
+// bunkerResponse is a NIP-46 JSON-RPC response
+type bunkerResponse struct {
+	ID     string `json:"id"`
+	Result string `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ConnectBunker connects to a remote signer from a bunker:// URI
+// Format: bunker://<remote-signer-pubkey>?relay=wss://...&secret=<optional>
+//
+// Parameters:
+//   - ctx: context (for timeout control; the user may have to approve on the signer)
+//   - bunkerURI: connection URI shown by the signer app
+//   - onAuth: called with a URL if the signer wants the user to approve in a browser (may be nil)
+//
+// Returns:
+//   - *BunkerSigner: connected signer
+//   - error: error if the URI is invalid or the signer refused
+func ConnectBunker(ctx context.Context, bunkerURI string, onAuth func(authURL string)) (*BunkerSigner, error) {
+	parsed, err := url.Parse(bunkerURI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bunker URI: %w", err)
+	}
+	if parsed.Scheme != "bunker" {
+		return nil, fmt.Errorf("invalid bunker URI: scheme must be bunker://")
+	}
+
+	remotePubKey := parsed.Host
+	if !nostr.IsValidPublicKey(remotePubKey) {
+		return nil, fmt.Errorf("invalid bunker URI: '%s' is not a public key", remotePubKey)
+	}
+
+	relays := parsed.Query()["relay"]
+	if len(relays) == 0 {
+		return nil, fmt.Errorf("invalid bunker URI: no relay given")
+	}
+
+	b, err := newBunkerSigner(BunkerSession{
+		ClientSecretKey:    nostr.GeneratePrivateKey(),
+		RemoteSignerPubKey: remotePubKey,
+		Relays:             relays,
+	}, onAuth, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// connect: [remote-signer-pubkey, optional secret]
+	params := []string{remotePubKey}
+	if secret := parsed.Query().Get("secret"); secret != "" {
+		params = append(params, secret)
+	}
+	if _, err := b.rpc(ctx, "connect", params...); err != nil {
+		b.Close()
+		return nil, fmt.Errorf("remote signer refused connection: %w", err)
+	}
+
+	if err := b.fetchUserPubKey(ctx); err != nil {
+		b.Close()
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// NostrConnect is a pending nostrconnect:// pairing started by the client
+// Show URI() to the user (e.g. as a QR code), then call Wait
+type NostrConnect struct {
+	signer *BunkerSigner
+	uri    string
+}
+
+// StartNostrConnect starts a client-initiated pairing (nostrconnect://)
+// Parameters:
+//   - relays: relays both sides will use for NIP-46 traffic
+//   - appName: app name the signer shows to the user
+//
+// Returns:
+//   - *NostrConnect: pending pairing
+//   - error: error if no relay could be reached
+func StartNostrConnect(relays []string, appName string) (*NostrConnect, error) {
+	if len(relays) == 0 {
+		return nil, fmt.Errorf("at least one relay is required")
+	}
+
+	secretBytes := make([]byte, 16)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+	secret := hex.EncodeToString(secretBytes)
+
+	b, err := newBunkerSigner(BunkerSession{
+		ClientSecretKey: nostr.GeneratePrivateKey(),
+		Relays:          relays,
+	}, nil, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	for _, r := range relays {
+		query.Add("relay", r)
+	}
+	query.Set("secret", secret)
+	query.Set("perms", "sign_event,nip44_encrypt,nip44_decrypt,nip04_decrypt")
+	if appName != "" {
+		query.Set("name", appName)
+	}
+
+	return &NostrConnect{
+		signer: b,
+		uri:    "nostrconnect://" + b.clientPubKey + "?" + query.Encode(),
+	}, nil
+}
+
+// URI returns the nostrconnect:// URI to show to the user
+func (nc *NostrConnect) URI() string {
+	return nc.uri
+}
+
+// Wait blocks until the signer app accepts the pairing
+// Parameters:
+//   - ctx: context (cancel to abort pairing)
+//   - onAuth: called with a URL if the signer wants the user to approve in a browser (may be nil)
+//
+// Returns:
+//   - *BunkerSigner: connected signer
+//   - error: error if canceled or the signer can't be queried
+func (nc *NostrConnect) Wait(ctx context.Context, onAuth func(authURL string)) (*BunkerSigner, error) {
+	b := nc.signer
+	b.mu.Lock()
+	b.onAuth = onAuth
+	b.mu.Unlock()
+
+	select {
+	case remotePubKey := <-b.handshake:
+		b.mu.Lock()
+		b.session.RemoteSignerPubKey = remotePubKey
+		b.handshakeSecret = ""
+		b.mu.Unlock()
+	case <-ctx.Done():
+		b.Close()
+		return nil, fmt.Errorf("pairing canceled: %w", ctx.Err())
+	}
+
+	if err := b.fetchUserPubKey(ctx); err != nil {
+		b.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+// RestoreBunker reconnects to a remote signer from a saved session
+// The signer already knows our client key, so no new connect request is sent
+func RestoreBunker(session BunkerSession, onAuth func(authURL string)) (*BunkerSigner, error) {
+	if session.ClientSecretKey == "" || session.RemoteSignerPubKey == "" || session.UserPubKey == "" {
+		return nil, fmt.Errorf("incomplete bunker session")
+	}
+	return newBunkerSigner(session, onAuth, "")
+}
+
+// newBunkerSigner connects to the signer's relays and starts listening for responses
+func newBunkerSigner(session BunkerSession, onAuth func(string), handshakeSecret string) (*BunkerSigner, error) {
+	clientPubKey, err := nostr.GetPublicKey(session.ClientSecretKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client key: %w", err)
+	}
+
+	pool, err := relay.ConnectPool(session.Relays)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to signer relays: %w", err)
+	}
+
+	prefix := make([]byte, 4)
+	if _, err := rand.Read(prefix); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to generate request ID prefix: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Responses are addressed to our client key; older ones belong to past sessions
+	now := nostr.Now()
+	events, err := pool.Subscribe(ctx, []nostr.Filter{{
+		Kinds: []int{KindNostrConnect},
+		Tags:  nostr.TagMap{"p": []string{clientPubKey}},
+		Since: &now,
+	}})
+	if err != nil {
+		cancel()
+		pool.Close()
+		return nil, fmt.Errorf("failed to subscribe to signer relays: %w", err)
+	}
+
+	b := &BunkerSigner{
+		session:         session,
+		clientPubKey:    clientPubKey,
+		pool:            pool,
+		onAuth:          onAuth,
+		idPrefix:        hex.EncodeToString(prefix),
+		pending:         make(map[string]chan bunkerResponse),
+		handshakeSecret: handshakeSecret,
+		handshake:       make(chan string, 1),
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+
+	go b.listen(events)
+	return b, nil
+}
+
+// listen dispatches signer responses to the waiting requests
+func (b *BunkerSigner) listen(events chan *nostr.Event) {
+	for ev := range events {
+		if ev.Kind != KindNostrConnect || !ev.CheckID() {
+			continue
+		}
+		if ok, err := ev.CheckSignature(); err != nil || !ok {
+			continue
+		}
+
+		b.mu.Lock()
+		remotePubKey := b.session.RemoteSignerPubKey
+		handshakeSecret := b.handshakeSecret
+		onAuth := b.onAuth
+		b.mu.Unlock()
+
+		// Once paired, only the remote signer may answer
+		if remotePubKey != "" && ev.PubKey != remotePubKey {
+			continue
+		}
+
+		plaintext, _, err := crypto.DecryptDM(ev.Content, b.session.ClientSecretKey, ev.PubKey)
+		if err != nil {
+			continue
+		}
+
+		var resp bunkerResponse
+		if err := json.Unmarshal([]byte(plaintext), &resp); err != nil {
+			continue
+		}
+
+		// nostrconnect:// pairing: the signer answers with our secret
+		if remotePubKey == "" {
+			if handshakeSecret != "" && resp.Result == handshakeSecret {
+				select {
+				case b.handshake <- ev.PubKey:
+				default:
+				}
+			}
+			continue
+		}
+
+		// The signer wants the user to approve in a browser; the real answer follows later
+		if resp.Result == "auth_url" {
+			if onAuth != nil {
+				onAuth(resp.Error)
+			}
+			continue
+		}
+
+		b.mu.Lock()
+		waiter, ok := b.pending[resp.ID]
+		b.mu.Unlock()
+		if ok {
+			select {
+			case waiter <- resp:
+			default:
+			}
+		}
+	}
+}
+
+// rpc sends a request to the remote signer and waits for the response
+func (b *BunkerSigner) rpc(ctx context.Context, method string, params ...string) (string, error) {
+	b.mu.Lock()
+	remotePubKey := b.session.RemoteSignerPubKey
+	b.mu.Unlock()
+	if remotePubKey == "" {
+		return "", fmt.Errorf("remote signer is not paired yet")
+	}
+
+	id := b.idPrefix + "-" + strconv.FormatUint(b.serial.Add(1), 10)
+	req, err := json.Marshal(bunkerRequest{ID: id, Method: method, Params: params})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	conversationKey, err := nip44.GenerateConversationKey(remotePubKey, b.session.ClientSecretKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to derive conversation key: %w", err)
+	}
+	content, err := nip44.Encrypt(string(req), conversationKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt request: %w", err)
+	}
+
+	ev := &nostr.Event{
+		CreatedAt: nostr.Now(),
+		Kind:      KindNostrConnect,
+		Tags:      nostr.Tags{{"p", remotePubKey}},
+		Content:   content,
+	}
+	if err := ev.Sign(b.session.ClientSecretKey); err != nil {
+		return "", fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	waiter := make(chan bunkerResponse, 1)
+	b.mu.Lock()
+	b.pending[id] = waiter
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		delete(b.pending, id)
+		b.mu.Unlock()
+	}()
+
+	accepted := false
+	var lastErr error
+	for _, res := range b.pool.PublishAll(ctx, ev) {
+		if res.OK {
+			accepted = true
+		} else {
+			lastErr = res.Error
+		}
+	}
+	if !accepted {
+		return "", fmt.Errorf("failed to send %s request: %v", method, lastErr)
+	}
+
+	select {
+	case resp := <-waiter:
+		if resp.Error != "" {
+			return "", fmt.Errorf("remote signer: %s", resp.Error)
+		}
+		return resp.Result, nil
+	case <-ctx.Done():
+		return "", fmt.Errorf("%s request timed out: %w", method, ctx.Err())
+	case <-b.ctx.Done():
+		return "", fmt.Errorf("bunker signer closed")
+	}
+}
+
+// fetchUserPubKey asks the signer which key it signs with
+// This can differ from the remote signer's own key
+func (b *BunkerSigner) fetchUserPubKey(ctx context.Context) error {
+	pubKey, err := b.rpc(ctx, "get_public_key")
+	if err != nil {
+		return fmt.Errorf("failed to get public key: %w", err)
+	}
+	if !nostr.IsValidPublicKey(pubKey) {
+		return fmt.Errorf("remote signer returned an invalid public key")
+	}
+
+	b.mu.Lock()
+	b.session.UserPubKey = pubKey
+	b.mu.Unlock()
+	return nil
+}
+
+// Session returns the session to save for RestoreBunker
+func (b *BunkerSigner) Session() BunkerSession {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.session
+}
+
+// GetPublicKey returns the user's public key (hex)
+func (b *BunkerSigner) GetPublicKey(ctx context.Context) (string, error) {
+	b.mu.Lock()
+	pubKey := b.session.UserPubKey
+	b.mu.Unlock()
+
+	if pubKey == "" {
+		if err := b.fetchUserPubKey(ctx); err != nil {
+			return "", err
+		}
+		return b.GetPublicKey(ctx)
+	}
+	return pubKey, nil
+}
+
+// SignEvent asks the remote signer to sign the event
+// The signed event must have the same ID we computed, so the signer can't
+// change the content, tags or PoW nonce behind our back
+func (b *BunkerSigner) SignEvent(ctx context.Context, event *nostr.Event) error {
+	pubKey, err := b.GetPublicKey(ctx)
+	if err != nil {
+		return err
+	}
+	event.PubKey = pubKey
+	expectedID := event.GetID()
+
+	unsigned, err := json.Marshal(map[string]any{
+		"pubkey":     pubKey,
+		"kind":       event.Kind,
+		"content":    event.Content,
+		"tags":       event.Tags,
+		"created_at": event.CreatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	result, err := b.rpc(ctx, "sign_event", string(unsigned))
+	if err != nil {
+		return fmt.Errorf("failed to sign event: %w", err)
+	}
+
+	var signed nostr.Event
+	if err := json.Unmarshal([]byte(result), &signed); err != nil {
+		return fmt.Errorf("remote signer returned an invalid event: %w", err)
+	}
+	if signed.ID != expectedID || signed.PubKey != pubKey {
+		return fmt.Errorf("remote signer altered the event")
+	}
+	if ok, err := signed.CheckSignature(); err != nil || !ok {
+		return fmt.Errorf("remote signer returned an invalid signature")
+	}
+
+	event.ID = signed.ID
+	event.Sig = signed.Sig
+	return nil
+}
+
+// Encrypt asks the remote signer to NIP-44 encrypt plaintext for a recipient
+func (b *BunkerSigner) Encrypt(ctx context.Context, plaintext, recipientPubKey string) (string, error) {
+	return b.rpc(ctx, "nip44_encrypt", recipientPubKey, plaintext)
+}
+
+// Decrypt asks the remote signer to decrypt NIP-44 or NIP-04 ciphertext
+func (b *BunkerSigner) Decrypt(ctx context.Context, ciphertext, senderPubKey string) (string, error) {
+	method := "nip44_decrypt"
+	if crypto.DetectScheme(ciphertext) == crypto.SchemeNIP04 {
+		method = "nip04_decrypt"
+	}
+	return b.rpc(ctx, method, senderPubKey, ciphertext)
+}
+
+// Ping checks that the remote signer is reachable
+func (b *BunkerSigner) Ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := b.rpc(ctx, "ping")
+	return err
+}
+
+// Close stops listening and disconnects from the signer's relays
+func (b *BunkerSigner) Close() error {
+	b.cancel()
+	return b.pool.Close()
+}
+
+// SaveBunkerSession saves a bunker session to a JSON file
+// The file holds the client key (not the user's key), but is still owner-only
+func SaveBunkerSession(session BunkerSession, filePath string) error {
+	dir := filepath.Dir(filePath)
+	if dir != "" && dir != "." {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+	}
+
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal bunker session: %w", err)
+	}
+
+	if err := os.WriteFile(filePath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write bunker session: %w", err)
+	}
+	return nil
+}
+
+// LoadBunkerSession loads a bunker session from a JSON file
+func LoadBunkerSession(filePath string) (*BunkerSession, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bunker session: %w", err)
+	}
+
+	var session BunkerSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to parse bunker session: %w", err)
+	}
+	return &session, nil
+}
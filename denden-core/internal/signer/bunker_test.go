@@ -0,0 +1,665 @@
+package signer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"denden-core/internal/crypto"
+
+	"github.com/coder/websocket"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip04"
+)
+
+// testRelay is a minimal in-memory relay: it keeps every event it receives,
+// answers REQ with the stored matches and EOSE, then forwards new matches
+type testRelay struct {
+	server *httptest.Server
+
+	mu     sync.Mutex
+	events []*nostr.Event
+	subs   map[*relayConn]map[string]nostr.Filters
+}
+
+// relayConn is one client connection to the test relay
+type relayConn struct {
+	ws *websocket.Conn
+	mu sync.Mutex // Serializes writes
+}
+
+// write sends an envelope to the client
+func (c *relayConn) write(envelope json.Marshaler) {
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	c.ws.Write(ctx, websocket.MessageText, data)
+}
+
+func newTestRelay(t *testing.T) *testRelay {
+	t.Helper()
+
+	r := &testRelay{subs: make(map[*relayConn]map[string]nostr.Filters)}
+	r.server = httptest.NewServer(http.HandlerFunc(r.serve))
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+// URL returns the relay's WebSocket URL
+func (r *testRelay) URL() string {
+	return "ws" + strings.TrimPrefix(r.server.URL, "http")
+}
+
+// serve handles one client connection
+func (r *testRelay) serve(w http.ResponseWriter, req *http.Request) {
+	ws, err := websocket.Accept(w, req, nil)
+	if err != nil {
+		return
+	}
+	defer ws.CloseNow()
+
+	conn := &relayConn{ws: ws}
+	r.mu.Lock()
+	r.subs[conn] = make(map[string]nostr.Filters)
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.subs, conn)
+		r.mu.Unlock()
+	}()
+
+	for {
+		_, data, err := ws.Read(req.Context())
+		if err != nil {
+			return
+		}
+
+		switch env := nostr.ParseMessage(string(data)).(type) {
+		case *nostr.EventEnvelope:
+			r.publish(conn, &env.Event)
+
+		case *nostr.ReqEnvelope:
+			r.mu.Lock()
+			for _, ev := range r.events {
+				if env.Filters.Match(ev) {
+					id := env.SubscriptionID
+					conn.write(nostr.EventEnvelope{SubscriptionID: &id, Event: *ev})
+				}
+			}
+			conn.write(nostr.EOSEEnvelope(env.SubscriptionID))
+			r.subs[conn][env.SubscriptionID] = env.Filters
+			r.mu.Unlock()
+
+		case *nostr.CloseEnvelope:
+			r.mu.Lock()
+			delete(r.subs[conn], string(*env))
+			r.mu.Unlock()
+		}
+	}
+}
+
+// publish stores a valid event, acknowledges it and forwards it to subscribers
+func (r *testRelay) publish(from *relayConn, ev *nostr.Event) {
+	if ok, err := ev.CheckSignature(); !ev.CheckID() || err != nil || !ok {
+		from.write(nostr.OKEnvelope{EventID: ev.ID, OK: false, Reason: "invalid: bad signature"})
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, ev)
+	from.write(nostr.OKEnvelope{EventID: ev.ID, OK: true})
+
+	for conn, subs := range r.subs {
+		for id, filters := range subs {
+			if filters.Match(ev) {
+				subID := id
+				conn.write(nostr.EventEnvelope{SubscriptionID: &subID, Event: *ev})
+			}
+		}
+	}
+}
+
+// testBunker is an in-process NIP-46 remote signer
+// It answers with its own key and signs with the user's key, like real bunkers
+type testBunker struct {
+	t          *testing.T
+	relayURL   string
+	secretKey  string // The remote signer's own key
+	pubKey     string
+	userKey    string // The key it signs with
+	userPubKey string
+	secret     string // Secret connect must carry ("" = none required)
+
+	tamper  func(signed *nostr.Event) // Alters signed events before they are returned (nil = honest)
+	authURL string                    // If set, sign_event first answers with auth_url
+
+	conn *nostr.Relay
+}
+
+func startBunker(t *testing.T, relayURL string) *testBunker {
+	t.Helper()
+
+	bk := &testBunker{
+		t:         t,
+		relayURL:  relayURL,
+		secretKey: nostr.GeneratePrivateKey(),
+		userKey:   nostr.GeneratePrivateKey(),
+	}
+	bk.pubKey, _ = nostr.GetPublicKey(bk.secretKey)
+	bk.userPubKey, _ = nostr.GetPublicKey(bk.userKey)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	conn, err := nostr.RelayConnect(ctx, relayURL)
+	if err != nil {
+		t.Fatalf("bunker failed to connect: %v", err)
+	}
+	bk.conn = conn
+	t.Cleanup(func() { conn.Close() })
+
+	sub, err := conn.Subscribe(ctx, nostr.Filters{{
+		Kinds: []int{KindNostrConnect},
+		Tags:  nostr.TagMap{"p": []string{bk.pubKey}},
+	}})
+	if err != nil {
+		t.Fatalf("bunker failed to subscribe: %v", err)
+	}
+
+	go func() {
+		for ev := range sub.Events {
+			bk.handle(ctx, ev)
+		}
+	}()
+	return bk
+}
+
+// uri returns the bunker:// URI the signer app would show
+func (bk *testBunker) uri(secret string) string {
+	query := url.Values{"relay": {bk.relayURL}}
+	if secret != "" {
+		query.Set("secret", secret)
+	}
+	return "bunker://" + bk.pubKey + "?" + query.Encode()
+}
+
+// handle answers one request
+func (bk *testBunker) handle(ctx context.Context, ev *nostr.Event) {
+	plaintext, err := crypto.Decrypt(ev.Content, bk.secretKey, ev.PubKey)
+	if err != nil {
+		return
+	}
+	var req bunkerRequest
+	if err := json.Unmarshal([]byte(plaintext), &req); err != nil {
+		return
+	}
+
+	resp := bunkerResponse{ID: req.ID}
+	switch req.Method {
+	case "connect":
+		if len(req.Params) == 0 || req.Params[0] != bk.pubKey {
+			resp.Error = "wrong remote signer"
+		} else if bk.secret != "" && (len(req.Params) < 2 || req.Params[1] != bk.secret) {
+			resp.Error = "invalid secret"
+		} else {
+			resp.Result = "ack"
+		}
+
+	case "get_public_key":
+		resp.Result = bk.userPubKey
+
+	case "ping":
+		resp.Result = "pong"
+
+	case "sign_event":
+		var event nostr.Event
+		if err := json.Unmarshal([]byte(req.Params[0]), &event); err != nil {
+			resp.Error = "invalid event"
+			break
+		}
+		event.Sign(bk.userKey)
+		if bk.tamper != nil {
+			bk.tamper(&event)
+		}
+		signed, _ := json.Marshal(event)
+		resp.Result = string(signed)
+
+		if bk.authURL != "" {
+			bk.respond(ctx, ev.PubKey, bunkerResponse{ID: req.ID, Result: "auth_url", Error: bk.authURL})
+			time.Sleep(50 * time.Millisecond) // The user approves in the browser
+		}
+
+	case "nip44_encrypt":
+		resp.Result, err = crypto.Encrypt(req.Params[1], bk.userKey, req.Params[0])
+		if err != nil {
+			resp.Error = err.Error()
+		}
+
+	case "nip44_decrypt":
+		resp.Result, err = crypto.Decrypt(req.Params[1], bk.userKey, req.Params[0])
+		if err != nil {
+			resp.Error = err.Error()
+		}
+
+	case "nip04_decrypt":
+		resp.Result, _, err = crypto.DecryptDM(req.Params[1], bk.userKey, req.Params[0])
+		if err != nil {
+			resp.Error = err.Error()
+		}
+
+	default:
+		resp.Error = "unsupported method " + req.Method
+	}
+
+	bk.respond(ctx, ev.PubKey, resp)
+}
+
+// respond sends a NIP-44 encrypted response to a client key
+func (bk *testBunker) respond(ctx context.Context, clientPubKey string, resp bunkerResponse) {
+	data, _ := json.Marshal(resp)
+	content, err := crypto.Encrypt(string(data), bk.secretKey, clientPubKey)
+	if err != nil {
+		bk.t.Errorf("bunker failed to encrypt response: %v", err)
+		return
+	}
+
+	ev := nostr.Event{
+		CreatedAt: nostr.Now(),
+		Kind:      KindNostrConnect,
+		Tags:      nostr.Tags{{"p", clientPubKey}},
+		Content:   content,
+	}
+	ev.Sign(bk.secretKey)
+	if err := bk.conn.Publish(ctx, ev); err != nil {
+		bk.t.Errorf("bunker failed to publish response: %v", err)
+	}
+}
+
+// acceptNostrConnect pairs with a nostrconnect:// URI, as a signer app does
+// after scanning it: it answers the client key with the URI's secret
+func (bk *testBunker) acceptNostrConnect(ctx context.Context, uri string) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		bk.t.Fatalf("invalid nostrconnect URI: %v", err)
+	}
+	bk.respond(ctx, parsed.Host, bunkerResponse{ID: "pair", Result: parsed.Query().Get("secret")})
+}
+
+// testContext returns a context that fails the test instead of hanging
+func testContext(t *testing.T) context.Context {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// connectTestBunker starts a relay and a bunker, and connects to it with bunker://
+func connectTestBunker(t *testing.T) (*testBunker, *BunkerSigner) {
+	t.Helper()
+
+	bk := startBunker(t, newTestRelay(t).URL())
+	b, err := ConnectBunker(testContext(t), bk.uri(""), nil)
+	if err != nil {
+		t.Fatalf("ConnectBunker: %v", err)
+	}
+	t.Cleanup(func() { b.Close() })
+	return bk, b
+}
+
+func TestConnectBunker(t *testing.T) {
+	tests := []struct {
+		name       string
+		required   string // Secret the bunker expects
+		given      string // Secret in the URI
+		wantErr    bool
+		errContain string
+	}{
+		{"no secret", "", "", false, ""},
+		{"matching secret", "s3cret", "s3cret", false, ""},
+		{"wrong secret", "s3cret", "guess", true, "invalid secret"},
+		{"missing secret", "s3cret", "", true, "invalid secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bk := startBunker(t, newTestRelay(t).URL())
+			bk.secret = tt.required
+
+			b, err := ConnectBunker(testContext(t), bk.uri(tt.given), nil)
+			if tt.wantErr {
+				if err == nil {
+					b.Close()
+					t.Fatal("ConnectBunker succeeded")
+				}
+				if !strings.Contains(err.Error(), tt.errContain) {
+					t.Fatalf("err = %v, want it to mention %q", err, tt.errContain)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ConnectBunker: %v", err)
+			}
+			defer b.Close()
+
+			// The user's key comes from get_public_key, not from the URI
+			session := b.Session()
+			if session.RemoteSignerPubKey != bk.pubKey {
+				t.Errorf("remote signer = %s, want %s", session.RemoteSignerPubKey, bk.pubKey)
+			}
+			if session.UserPubKey != bk.userPubKey {
+				t.Errorf("user pubkey = %s, want %s", session.UserPubKey, bk.userPubKey)
+			}
+			if err := b.Ping(testContext(t)); err != nil {
+				t.Errorf("Ping: %v", err)
+			}
+		})
+	}
+}
+
+func TestConnectBunkerInvalidURI(t *testing.T) {
+	pubKey, _ := nostr.GetPublicKey(nostr.GeneratePrivateKey())
+
+	tests := []struct {
+		name string
+		uri  string
+	}{
+		{"wrong scheme", "nostrconnect://" + pubKey + "?relay=ws://localhost"},
+		{"not a pubkey", "bunker://npub1xyz?relay=ws://localhost"},
+		{"no relay", "bunker://" + pubKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if b, err := ConnectBunker(testContext(t), tt.uri, nil); err == nil {
+				b.Close()
+				t.Fatal("ConnectBunker accepted an invalid URI")
+			}
+		})
+	}
+}
+
+func TestNostrConnectHandshake(t *testing.T) {
+	relayURL := newTestRelay(t).URL()
+	bk := startBunker(t, relayURL)
+
+	nc, err := StartNostrConnect([]string{relayURL}, "Den Den")
+	if err != nil {
+		t.Fatalf("StartNostrConnect: %v", err)
+	}
+
+	parsed, err := url.Parse(nc.URI())
+	if err != nil || parsed.Scheme != "nostrconnect" {
+		t.Fatalf("URI = %s", nc.URI())
+	}
+	query := parsed.Query()
+	if query.Get("secret") == "" || query.Get("relay") != relayURL || query.Get("name") != "Den Den" {
+		t.Errorf("URI query = %v", query)
+	}
+
+	// Someone else answering with the wrong secret doesn't pair
+	impostor := startBunker(t, relayURL)
+	impostor.respond(testContext(t), parsed.Host, bunkerResponse{ID: "pair", Result: "wrong"})
+
+	bk.acceptNostrConnect(testContext(t), nc.URI())
+
+	b, err := nc.Wait(testContext(t), nil)
+	if err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	defer b.Close()
+
+	session := b.Session()
+	if session.RemoteSignerPubKey != bk.pubKey {
+		t.Errorf("paired with %s, want %s", session.RemoteSignerPubKey, bk.pubKey)
+	}
+	if session.UserPubKey != bk.userPubKey {
+		t.Errorf("user pubkey = %s, want %s", session.UserPubKey, bk.userPubKey)
+	}
+}
+
+func TestNostrConnectWaitCanceled(t *testing.T) {
+	nc, err := StartNostrConnect([]string{newTestRelay(t).URL()}, "")
+	if err != nil {
+		t.Fatalf("StartNostrConnect: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if b, err := nc.Wait(ctx, nil); err == nil {
+		b.Close()
+		t.Fatal("Wait succeeded without a signer")
+	}
+}
+
+func TestBunkerSignEvent(t *testing.T) {
+	bk, b := connectTestBunker(t)
+
+	event := &nostr.Event{
+		CreatedAt: nostr.Now(),
+		Kind:      1,
+		Tags:      nostr.Tags{{"t", "denden"}},
+		Content:   "signed remotely",
+	}
+	if err := b.SignEvent(testContext(t), event); err != nil {
+		t.Fatalf("SignEvent: %v", err)
+	}
+	if event.PubKey != bk.userPubKey {
+		t.Errorf("pubkey = %s, want %s", event.PubKey, bk.userPubKey)
+	}
+	if !event.CheckID() {
+		t.Error("event ID doesn't match its contents")
+	}
+	if ok, err := event.CheckSignature(); err != nil || !ok {
+		t.Errorf("invalid signature: %v", err)
+	}
+}
+
+func TestBunkerSignEventRejectsAlteredEvents(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  func(bk *testBunker, signed *nostr.Event)
+		wantErr string
+	}{
+		{
+			name: "content changed",
+			tamper: func(bk *testBunker, signed *nostr.Event) {
+				signed.Content = "something else"
+				signed.Sign(bk.userKey)
+			},
+			wantErr: "altered",
+		},
+		{
+			name: "PoW nonce removed",
+			tamper: func(bk *testBunker, signed *nostr.Event) {
+				signed.Tags = nil
+				signed.Sign(bk.userKey)
+			},
+			wantErr: "altered",
+		},
+		{
+			name: "ID changed, signature kept",
+			tamper: func(bk *testBunker, signed *nostr.Event) {
+				signed.ID = strings.Repeat("0", 64)
+			},
+			wantErr: "altered",
+		},
+		{
+			name: "signed with another key",
+			tamper: func(bk *testBunker, signed *nostr.Event) {
+				signed.Sign(bk.secretKey)
+			},
+			wantErr: "altered",
+		},
+		{
+			name: "invalid signature",
+			tamper: func(bk *testBunker, signed *nostr.Event) {
+				signed.Sig = strings.Repeat("ab", 64)
+			},
+			wantErr: "invalid signature",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bk, b := connectTestBunker(t)
+			bk.tamper = func(signed *nostr.Event) { tt.tamper(bk, signed) }
+
+			event := &nostr.Event{
+				CreatedAt: nostr.Now(),
+				Kind:      1,
+				Tags:      nostr.Tags{{"nonce", "1234", "16"}},
+				Content:   "hello",
+			}
+			err := b.SignEvent(testContext(t), event)
+			if err == nil {
+				t.Fatal("SignEvent accepted an altered event")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to mention %q", err, tt.wantErr)
+			}
+			if event.Sig != "" {
+				t.Error("the rejected signature was copied to the event")
+			}
+		})
+	}
+}
+
+func TestBunkerNIP44RoundTrip(t *testing.T) {
+	bk, b := connectTestBunker(t)
+
+	partnerKey := nostr.GeneratePrivateKey()
+	partnerPubKey, _ := nostr.GetPublicKey(partnerKey)
+
+	tests := []struct {
+		name      string
+		plaintext string
+	}{
+		{"short", "hi"},
+		{"unicode", "こんにちは 🌏"},
+		{"long", strings.Repeat("den den ", 500)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := testContext(t)
+
+			// Encrypt remotely, decrypt as the partner
+			ciphertext, err := b.Encrypt(ctx, tt.plaintext, partnerPubKey)
+			if err != nil {
+				t.Fatalf("Encrypt: %v", err)
+			}
+			if crypto.DetectScheme(ciphertext) != crypto.SchemeNIP44 {
+				t.Errorf("ciphertext isn't NIP-44")
+			}
+			got, err := crypto.Decrypt(ciphertext, partnerKey, bk.userPubKey)
+			if err != nil || got != tt.plaintext {
+				t.Fatalf("partner decrypted %q (%v), want %q", got, err, tt.plaintext)
+			}
+
+			// Encrypt as the partner, decrypt remotely
+			reply, err := crypto.Encrypt(tt.plaintext, partnerKey, bk.userPubKey)
+			if err != nil {
+				t.Fatalf("crypto.Encrypt: %v", err)
+			}
+			got, err = b.Decrypt(ctx, reply, partnerPubKey)
+			if err != nil || got != tt.plaintext {
+				t.Fatalf("Decrypt = %q (%v), want %q", got, err, tt.plaintext)
+			}
+		})
+	}
+}
+
+func TestBunkerDecryptNIP04(t *testing.T) {
+	bk, b := connectTestBunker(t)
+
+	partnerKey := nostr.GeneratePrivateKey()
+	partnerPubKey, _ := nostr.GetPublicKey(partnerKey)
+
+	shared, err := nip04.ComputeSharedSecret(bk.userPubKey, partnerKey)
+	if err != nil {
+		t.Fatalf("ComputeSharedSecret: %v", err)
+	}
+	ciphertext, err := nip04.Encrypt("legacy dm", shared)
+	if err != nil {
+		t.Fatalf("nip04.Encrypt: %v", err)
+	}
+
+	got, err := b.Decrypt(testContext(t), ciphertext, partnerPubKey)
+	if err != nil || got != "legacy dm" {
+		t.Fatalf("Decrypt = %q (%v), want %q", got, err, "legacy dm")
+	}
+}
+
+func TestBunkerAuthURL(t *testing.T) {
+	bk := startBunker(t, newTestRelay(t).URL())
+	bk.authURL = "https://signer.example/approve?id=42"
+
+	var mu sync.Mutex
+	var authURLs []string
+	onAuth := func(authURL string) {
+		mu.Lock()
+		authURLs = append(authURLs, authURL)
+		mu.Unlock()
+	}
+
+	b, err := ConnectBunker(testContext(t), bk.uri(""), onAuth)
+	if err != nil {
+		t.Fatalf("ConnectBunker: %v", err)
+	}
+	defer b.Close()
+
+	// The auth_url response isn't the answer: SignEvent waits for the real one
+	event := &nostr.Event{CreatedAt: nostr.Now(), Kind: 1, Content: "needs approval"}
+	if err := b.SignEvent(testContext(t), event); err != nil {
+		t.Fatalf("SignEvent: %v", err)
+	}
+	if ok, err := event.CheckSignature(); err != nil || !ok {
+		t.Errorf("invalid signature after approval: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(authURLs) != 1 || authURLs[0] != bk.authURL {
+		t.Errorf("onAuth got %v, want [%s]", authURLs, bk.authURL)
+	}
+}
+
+func TestRestoreBunker(t *testing.T) {
+	bk, b := connectTestBunker(t)
+	session := b.Session()
+	b.Close()
+
+	restored, err := RestoreBunker(session, nil)
+	if err != nil {
+		t.Fatalf("RestoreBunker: %v", err)
+	}
+	defer restored.Close()
+
+	pubKey, err := restored.GetPublicKey(testContext(t))
+	if err != nil || pubKey != bk.userPubKey {
+		t.Fatalf("GetPublicKey = %s (%v), want %s", pubKey, err, bk.userPubKey)
+	}
+
+	event := &nostr.Event{CreatedAt: nostr.Now(), Kind: 1, Content: "after restart"}
+	if err := restored.SignEvent(testContext(t), event); err != nil {
+		t.Fatalf("SignEvent: %v", err)
+	}
+
+	if _, err := RestoreBunker(BunkerSession{ClientSecretKey: session.ClientSecretKey}, nil); err == nil {
+		t.Error("RestoreBunker accepted an incomplete session")
+	}
+}
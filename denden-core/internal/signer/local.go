@@ -0,0 +1,59 @@
+package signer
+
+import (
+	"context"
+	"fmt"
+
+	"denden-core/internal/crypto"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// LocalSigner signs with a private key held in memory
+type LocalSigner struct {
+	privateKey string
+	publicKey  string
+}
+
+// NewLocalSigner creates a signer for a hex private key
+// Parameters:
+//   - privateKey: private key in hex format
+//
+// Returns:
+//   - *LocalSigner: signer for the key
+//   - error: error if the key is invalid
+func NewLocalSigner(privateKey string) (*LocalSigner, error) {
+	publicKey, err := nostr.GetPublicKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+
+	return &LocalSigner{
+		privateKey: privateKey,
+		publicKey:  publicKey,
+	}, nil
+}
+
+// GetPublicKey returns the public key (hex)
+func (s *LocalSigner) GetPublicKey(ctx context.Context) (string, error) {
+	return s.publicKey, nil
+}
+
+// SignEvent signs the event with the local key
+func (s *LocalSigner) SignEvent(ctx context.Context, event *nostr.Event) error {
+	if err := event.Sign(s.privateKey); err != nil {
+		return fmt.Errorf("failed to sign event: %w", err)
+	}
+	return nil
+}
+
+// Encrypt encrypts plaintext for a recipient with NIP-44
+func (s *LocalSigner) Encrypt(ctx context.Context, plaintext, recipientPubKey string) (string, error) {
+	return crypto.EncryptDM(plaintext, s.privateKey, recipientPubKey)
+}
+
+// Decrypt decrypts NIP-44 or NIP-04 ciphertext (the scheme is detected from the format)
+func (s *LocalSigner) Decrypt(ctx context.Context, ciphertext, senderPubKey string) (string, error) {
+	plaintext, _, err := crypto.DecryptDM(ciphertext, s.privateKey, senderPubKey)
+	return plaintext, err
+}
@@ -0,0 +1,26 @@
+package signer
+
+import (
+	"context"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// Signer holds (or talks to) the user's key
+// Every publish and DM path signs and encrypts through a Signer,
+// so the rest of the client never needs to see the private key
+type Signer interface {
+	// GetPublicKey returns the signer's public key (hex)
+	GetPublicKey(ctx context.Context) (string, error)
+
+	// SignEvent sets PubKey, ID and Sig on the event
+	// The event must be complete (tags, content, PoW nonce) before signing
+	SignEvent(ctx context.Context, event *nostr.Event) error
+
+	// Encrypt encrypts plaintext for a recipient with NIP-44
+	Encrypt(ctx context.Context, plaintext, recipientPubKey string) (string, error)
+
+	// Decrypt decrypts NIP-44 or legacy NIP-04 ciphertext from a sender
+	// Because ECDH is symmetric, this also decrypts messages we sent to senderPubKey
+	Decrypt(ctx context.Context, ciphertext, senderPubKey string) (string, error)
+}
@@ -0,0 +1,182 @@
+package stats
+
+import (
+	"denden-core/internal/thread"
+	"denden-core/internal/zap"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// Kinds are the event kinds that count towards post stats:
+// replies and quotes (1), reposts (6, 16), reactions (7) and zaps (9735)
+var Kinds = []int{1, 6, 7, 16, 9735}
+
+// Reaction contents with a meaning of their own (NIP-25)
+const (
+	Like    = "+"
+	Dislike = "-"
+)
+
+// Post is the engagement on one post
+// Replies, reposts and quotes count once per author (reactions once per
+// author and emoji); zaps count every receipt
+type Post struct {
+	Replies   int               // Authors of direct replies (NIP-10 parent)
+	Reposts   int               // Kind 6 and 16 reposts
+	Quotes    int               // Posts quoting it ("q" tag)
+	Reactions map[string]int    // Authors per reaction content ("+" for likes, "-" for dislikes, emoji...)
+	EmojiURLs map[string]string // Image per custom emoji reaction (":shortcode:" -> URL, NIP-30)
+	Zaps      int               // Verified zap receipts
+	ZapSats   int64             // Total zapped, in sats (from the receipts' invoices)
+
+	LikedByMe    bool // The user reacted with "+"
+	RepostedByMe bool // The user reposted it
+	RepliedByMe  bool // The user replied to it
+
+	seen map[string]bool // Events and (kind, author, content) already counted
+}
+
+// Likes returns the number of "+" reactions
+func (p *Post) Likes() int {
+	return p.Reactions[Like]
+}
+
+// Counter accumulates stats for a set of posts
+type Counter struct {
+	me         string
+	posts      map[string]*Post
+	zapSigners zap.Signers // LNURL server key per zap recipient
+}
+
+// NewCounter creates a counter for the given posts
+// Parameters:
+//   - me: the user's public key (hex), for the "by me" flags
+//   - postIDs: the posts to count for; events about other posts are ignored
+func NewCounter(me string, postIDs []string) *Counter {
+	c := &Counter{
+		me:    me,
+		posts: make(map[string]*Post, len(postIDs)),
+	}
+	for _, id := range postIDs {
+		c.posts[id] = &Post{
+			Reactions: make(map[string]int),
+			EmojiURLs: make(map[string]string),
+			seen:      make(map[string]bool),
+		}
+	}
+	return c
+}
+
+// SetZapSigners sets the keys allowed to sign zap receipts, per recipient
+// Zaps only count once set, and only with receipts signed by the zapped
+// author's LNURL server (see zap.Signers)
+func (c *Counter) SetZapSigners(signers zap.Signers) {
+	c.zapSigners = signers
+}
+
+// Add counts an event towards the posts it is about
+// A Kind 1 event can be both a reply to one post and a quote of another
+func (c *Counter) Add(event *nostr.Event) {
+	switch event.Kind {
+	case 1:
+		if post := c.post(thread.Parse(event).Reply); post != nil && post.once("1:"+event.PubKey) {
+			post.Replies++
+			post.RepliedByMe = post.RepliedByMe || event.PubKey == c.me
+		}
+		for _, tag := range event.Tags {
+			if len(tag) >= 2 && tag[0] == "q" {
+				if post := c.post(tag[1]); post != nil && post.once("q:"+event.PubKey) {
+					post.Quotes++
+				}
+			}
+		}
+
+	case 6, 16:
+		if post := c.post(firstTag(event, "e")); post != nil && post.once("r:"+event.PubKey) {
+			post.Reposts++
+			post.RepostedByMe = post.RepostedByMe || event.PubKey == c.me
+		}
+
+	case 7:
+		content := event.Content
+		if content == "" {
+			content = Like // NIP-25: an empty reaction is a like
+		}
+		if post := c.post(lastTag(event, "e")); post != nil && post.once("7:"+event.PubKey+":"+content) {
+			post.Reactions[content]++
+			if url := emojiURL(event, content); url != "" {
+				post.EmojiURLs[content] = url
+			}
+			post.LikedByMe = post.LikedByMe || (content == Like && event.PubKey == c.me)
+		}
+
+	case 9735:
+		receipt, err := c.zapSigners.Verify(event)
+		if err != nil {
+			break
+		}
+		if post := c.post(receipt.Target); post != nil && post.once(event.ID) {
+			post.Zaps++
+			post.ZapSats += receipt.Sats
+		}
+	}
+}
+
+// Post returns the stats of a post (nil if it isn't counted)
+func (c *Counter) Post(id string) *Post {
+	return c.posts[id]
+}
+
+// post returns the stats of a post being counted (nil for other IDs)
+func (c *Counter) post(id string) *Post {
+	if id == "" {
+		return nil
+	}
+	return c.posts[id]
+}
+
+// once reports whether key is new for the post, and remembers it
+func (p *Post) once(key string) bool {
+	if p.seen[key] {
+		return false
+	}
+	p.seen[key] = true
+	return true
+}
+
+// emojiURL returns the image of a custom emoji reaction (":shortcode:" with a
+// matching "emoji" tag, NIP-30), or "" for other reactions
+func emojiURL(event *nostr.Event, content string) string {
+	if len(content) < 3 || content[0] != ':' || content[len(content)-1] != ':' {
+		return ""
+	}
+	shortcode := content[1 : len(content)-1]
+	for _, tag := range event.Tags {
+		if len(tag) >= 3 && tag[0] == "emoji" && tag[1] == shortcode {
+			return tag[2]
+		}
+	}
+	return ""
+}
+
+// firstTag returns the value of the first tag with a name
+func firstTag(event *nostr.Event, name string) string {
+	for _, tag := range event.Tags {
+		if len(tag) >= 2 && tag[0] == name {
+			return tag[1]
+		}
+	}
+	return ""
+}
+
+// lastTag returns the value of the last tag with a name
+// (NIP-25: the reacted-to event is the last "e" tag)
+func lastTag(event *nostr.Event, name string) string {
+	value := ""
+	for _, tag := range event.Tags {
+		if len(tag) >= 2 && tag[0] == name {
+			value = tag[1]
+		}
+	}
+	return value
+}
@@ -0,0 +1,239 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"denden-core/internal/zap"
+
+	"github.com/btcsuite/btcd/btcutil/bech32"
+	"github.com/nbd-wtf/go-nostr"
+)
+
+var (
+	me    = strings.Repeat("1", 64)
+	alice = strings.Repeat("a", 64)
+	bob   = strings.Repeat("b", 64)
+	post  = strings.Repeat("f", 64)
+	other = strings.Repeat("e", 64)
+)
+
+// nextID numbers the test events
+var nextID = 0
+
+// event returns an event with a fresh fake ID
+func event(author string, kind int, content string, tags ...nostr.Tag) *nostr.Event {
+	nextID++
+	return &nostr.Event{
+		ID:        fmt.Sprintf("%064x", nextID),
+		PubKey:    author,
+		CreatedAt: nostr.Timestamp(1000 + nextID),
+		Kind:      kind,
+		Tags:      tags,
+		Content:   content,
+	}
+}
+
+// zapFixture signs zap requests and receipts for the post's author, paid
+// through an LNURL server
+type zapFixture struct {
+	senderKey, serverKey string
+	server               string
+}
+
+func newZapFixture() zapFixture {
+	f := zapFixture{senderKey: nostr.GeneratePrivateKey(), serverKey: nostr.GeneratePrivateKey()}
+	f.server, _ = nostr.GetPublicKey(f.serverKey)
+	return f
+}
+
+// receipt returns a receipt for a zap of the post, with an invoice of hrp
+// (e.g. "lnbc10u" for 1000 sats), signed with signerKey
+func (f zapFixture) receipt(t *testing.T, hrp, signerKey string) *nostr.Event {
+	t.Helper()
+
+	request := nostr.Event{
+		Kind:      nostr.KindZapRequest,
+		CreatedAt: nostr.Now(),
+		Tags:      nostr.Tags{{"p", alice}, {"e", post}},
+	}
+	if err := request.Sign(f.senderKey); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	description, _ := json.Marshal(request)
+
+	// Timestamp, "d" field with the request, empty signature
+	groups, _ := bech32.ConvertBits(description, 8, 5, true)
+	data := make([]byte, 7)
+	data = append(data, 13, byte(len(groups)>>5), byte(len(groups)&31))
+	data = append(data, groups...)
+	data = append(data, make([]byte, 104)...)
+	invoice, err := bech32.Encode(hrp, data)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	receipt := &nostr.Event{
+		Kind:      nostr.KindZap,
+		CreatedAt: nostr.Now(),
+		Tags:      nostr.Tags{{"p", alice}, {"e", post}, {"bolt11", invoice}, {"description", string(description)}},
+	}
+	if err := receipt.Sign(signerKey); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return receipt
+}
+
+func TestCounterAdd(t *testing.T) {
+	zaps := newZapFixture()
+	repeated := event(bob, 1, "reply", nostr.Tag{"e", post, "", "root"})
+
+	tests := []struct {
+		name   string
+		events []*nostr.Event
+		want   Post
+	}{
+		{
+			name: "replies by NIP-10 parent",
+			events: []*nostr.Event{
+				event(alice, 1, "root marker", nostr.Tag{"e", post, "", "root"}),
+				event(bob, 1, "positional", nostr.Tag{"e", post}),
+				event(me, 1, "reply marker", nostr.Tag{"e", other, "", "root"}, nostr.Tag{"e", post, "", "reply"}),
+				event(alice, 1, "reply to a reply", nostr.Tag{"e", post, "", "root"}, nostr.Tag{"e", other, "", "reply"}),
+				event(bob, 1, "mention", nostr.Tag{"e", post, "", "mention"}),
+			},
+			want: Post{Replies: 3, RepliedByMe: true},
+		},
+		{
+			name: "replies once per author",
+			events: []*nostr.Event{
+				repeated,
+				repeated,
+				event(bob, 1, "again", nostr.Tag{"e", post, "", "root"}),
+				event(alice, 1, "reply", nostr.Tag{"e", post, "", "root"}),
+			},
+			want: Post{Replies: 2},
+		},
+		{
+			name: "quotes once per author",
+			events: []*nostr.Event{
+				event(alice, 1, "look", nostr.Tag{"q", post}),
+				event(alice, 1, "look again", nostr.Tag{"q", post}),
+				event(bob, 1, "quote and reply", nostr.Tag{"q", post}, nostr.Tag{"e", post, "", "root"}),
+				event(bob, 1, "other post", nostr.Tag{"q", other}),
+			},
+			want: Post{Quotes: 2, Replies: 1},
+		},
+		{
+			name: "reposts once per author",
+			events: []*nostr.Event{
+				event(alice, 6, "", nostr.Tag{"e", post}),
+				event(alice, 16, "", nostr.Tag{"e", post}, nostr.Tag{"k", "1"}),
+				event(me, 16, "", nostr.Tag{"e", post}, nostr.Tag{"k", "1"}),
+				event(bob, 6, "", nostr.Tag{"e", other}),
+			},
+			want: Post{Reposts: 2, RepostedByMe: true},
+		},
+		{
+			name: "reactions by the last e tag",
+			events: []*nostr.Event{
+				event(alice, 7, "+", nostr.Tag{"e", other}, nostr.Tag{"e", post}),
+				event(bob, 7, "+", nostr.Tag{"e", post}, nostr.Tag{"e", other}),
+			},
+			want: Post{Reactions: map[string]int{Like: 1}},
+		},
+		{
+			name: "reactions once per author and content",
+			events: []*nostr.Event{
+				event(alice, 7, "+", nostr.Tag{"e", post}),
+				event(alice, 7, "", nostr.Tag{"e", post}), // Empty is a like too
+				event(alice, 7, "🔥", nostr.Tag{"e", post}),
+				event(me, 7, "", nostr.Tag{"e", post}),
+				event(bob, 7, "-", nostr.Tag{"e", post}),
+				event(bob, 7, "-", nostr.Tag{"e", post}),
+			},
+			want: Post{Reactions: map[string]int{Like: 2, "🔥": 1, Dislike: 1}, LikedByMe: true},
+		},
+		{
+			name: "custom emoji",
+			events: []*nostr.Event{
+				event(alice, 7, ":soapbox:", nostr.Tag{"e", post}, nostr.Tag{"emoji", "soapbox", "https://example.com/soapbox.png"}),
+				event(bob, 7, ":soapbox:", nostr.Tag{"e", post}),
+				event(bob, 7, ":nope:", nostr.Tag{"e", post}, nostr.Tag{"emoji", "other", "https://example.com/other.png"}),
+			},
+			want: Post{
+				Reactions: map[string]int{":soapbox:": 2, ":nope:": 1},
+				EmojiURLs: map[string]string{":soapbox:": "https://example.com/soapbox.png"},
+			},
+		},
+		{
+			name: "zaps signed by the recipient's server",
+			events: []*nostr.Event{
+				zaps.receipt(t, "lnbc10u", zaps.serverKey),
+				zaps.receipt(t, "lnbc2500n", zaps.serverKey),
+				zaps.receipt(t, "lnbc1m", nostr.GeneratePrivateKey()), // Forged
+			},
+			want: Post{Zaps: 2, ZapSats: 1250},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCounter(me, []string{post})
+			c.SetZapSigners(zap.Signers{alice: zaps.server})
+			for _, event := range tt.events {
+				c.Add(event)
+			}
+
+			got := c.Post(post)
+			if tt.want.Reactions == nil {
+				tt.want.Reactions = map[string]int{}
+			}
+			if tt.want.EmojiURLs == nil {
+				tt.want.EmojiURLs = map[string]string{}
+			}
+			if got.Replies != tt.want.Replies || got.Quotes != tt.want.Quotes || got.Reposts != tt.want.Reposts ||
+				got.Zaps != tt.want.Zaps || got.ZapSats != tt.want.ZapSats {
+				t.Errorf("replies/quotes/reposts/zaps/sats = %d/%d/%d/%d/%d, want %d/%d/%d/%d/%d",
+					got.Replies, got.Quotes, got.Reposts, got.Zaps, got.ZapSats,
+					tt.want.Replies, tt.want.Quotes, tt.want.Reposts, tt.want.Zaps, tt.want.ZapSats)
+			}
+			if fmt.Sprint(got.Reactions) != fmt.Sprint(tt.want.Reactions) {
+				t.Errorf("Reactions = %v, want %v", got.Reactions, tt.want.Reactions)
+			}
+			if fmt.Sprint(got.EmojiURLs) != fmt.Sprint(tt.want.EmojiURLs) {
+				t.Errorf("EmojiURLs = %v, want %v", got.EmojiURLs, tt.want.EmojiURLs)
+			}
+			if got.LikedByMe != tt.want.LikedByMe || got.RepostedByMe != tt.want.RepostedByMe || got.RepliedByMe != tt.want.RepliedByMe {
+				t.Errorf("by me (liked/reposted/replied) = %v/%v/%v, want %v/%v/%v",
+					got.LikedByMe, got.RepostedByMe, got.RepliedByMe,
+					tt.want.LikedByMe, tt.want.RepostedByMe, tt.want.RepliedByMe)
+			}
+		})
+	}
+}
+
+func TestCounterIgnoresZapsWithoutSigners(t *testing.T) {
+	zaps := newZapFixture()
+	c := NewCounter(me, []string{post})
+	c.Add(zaps.receipt(t, "lnbc10u", zaps.serverKey))
+
+	if got := c.Post(post); got.Zaps != 0 || got.ZapSats != 0 {
+		t.Errorf("zaps/sats = %d/%d without signers, want 0/0", got.Zaps, got.ZapSats)
+	}
+}
+
+func TestCounterIgnoresOtherPosts(t *testing.T) {
+	c := NewCounter(me, []string{post})
+	c.Add(event(alice, 7, "+", nostr.Tag{"e", other}))
+	c.Add(event(alice, 1, "reply", nostr.Tag{"e", other, "", "root"}))
+
+	if c.Post(other) != nil {
+		t.Error("Post() returned stats for a post that isn't counted")
+	}
+	if got := c.Post(post); got.Likes() != 0 || got.Replies != 0 {
+		t.Errorf("likes/replies = %d/%d, want 0/0", got.Likes(), got.Replies)
+	}
+}
@@ -0,0 +1,185 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// querier is what tombstone lookups need (*sql.DB or *sql.Tx)
+type querier interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// applyDeletion removes the events a deletion (Kind 5, NIP-09) references and
+// records tombstones for them. Only the deletion author's own events are
+// removed: "e" tags by event ID, "a" tags every version of the address up to
+// the deletion's created_at (replaceable and addressable kinds only).
+// Deletions can't be deleted
+func applyDeletion(tx *sql.Tx, deletion *nostr.Event) error {
+	for _, tag := range deletion.Tags {
+		if len(tag) < 2 {
+			continue
+		}
+
+		switch tag[0] {
+		case "e":
+			if err := addTombstone(tx, tag[1], deletion); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(
+				`DELETE FROM events WHERE id = ? AND pubkey = ? AND kind != ?`,
+				tag[1], deletion.PubKey, nostr.KindDeletion,
+			); err != nil {
+				return fmt.Errorf("failed to delete event: %w", err)
+			}
+
+		case "a":
+			kind, pubkey, d, ok := parseAddress(tag[1])
+			if !ok || pubkey != deletion.PubKey || !isAddressed(kind) {
+				continue
+			}
+			// Replaceable kinds have a single address, whatever the d tag says
+			ref := tag[1]
+			if !nostr.IsAddressableKind(kind) {
+				ref = fmt.Sprintf("%d:%s:", kind, pubkey)
+			}
+			if err := addTombstone(tx, ref, deletion); err != nil {
+				return err
+			}
+			query := `DELETE FROM events WHERE pubkey = ? AND kind = ? AND created_at <= ?`
+			args := []any{pubkey, kind, int64(deletion.CreatedAt)}
+			if nostr.IsAddressableKind(kind) {
+				query += ` AND id IN (SELECT event_id FROM tags WHERE name = 'd' AND value = ?)`
+				args = append(args, d)
+			}
+			if _, err := tx.Exec(query, args...); err != nil {
+				return fmt.Errorf("failed to delete address: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// addTombstone records that the deletion's author deleted ref (an event ID or address)
+func addTombstone(tx *sql.Tx, ref string, deletion *nostr.Event) error {
+	_, err := tx.Exec(
+		`INSERT INTO tombstones (ref, pubkey, deleted_at) VALUES (?, ?, ?)
+		ON CONFLICT(ref, pubkey) DO UPDATE SET deleted_at = MAX(deleted_at, excluded.deleted_at)`,
+		ref, deletion.PubKey, int64(deletion.CreatedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save tombstone: %w", err)
+	}
+	return nil
+}
+
+// isTombstoned reports whether an event was deleted by its author, by ID or,
+// for replaceable and addressable events, by an address deletion at least as recent
+func isTombstoned(q querier, event *nostr.Event) (bool, error) {
+	if event.Kind == nostr.KindDeletion {
+		return false, nil
+	}
+
+	query := `SELECT 1 FROM tombstones WHERE pubkey = ? AND ref = ?`
+	args := []any{event.PubKey, event.ID}
+	if address := EventAddress(event); address != "" {
+		query += ` UNION SELECT 1 FROM tombstones WHERE pubkey = ? AND ref = ? AND deleted_at >= ?`
+		args = append(args, event.PubKey, address, int64(event.CreatedAt))
+	}
+
+	var found int
+	err := q.QueryRow(query+` LIMIT 1`, args...).Scan(&found)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to look up tombstone: %w", err)
+	}
+	return true, nil
+}
+
+// IsDeleted reports whether an event was deleted by its author (NIP-09)
+// Database errors are treated as not deleted
+func (s *Store) IsDeleted(event *nostr.Event) bool {
+	deleted, err := isTombstoned(s.db, event)
+	return err == nil && deleted
+}
+
+// FilterDeleted returns the events that weren't deleted by their authors
+func (s *Store) FilterDeleted(events []*nostr.Event) []*nostr.Event {
+	kept := events[:0:0]
+	for _, event := range events {
+		if !s.IsDeleted(event) {
+			kept = append(kept, event)
+		}
+	}
+	return kept
+}
+
+// Deletes reports whether a deletion (Kind 5, NIP-09) removes an event, by the
+// rules applyDeletion and isTombstoned apply: only the deletion author's own
+// events, by ID ("e" tag) or by address ("a" tag) for the versions created up
+// to the deletion. Deletions can't be deleted
+func Deletes(deletion, event *nostr.Event) bool {
+	if deletion.Kind != nostr.KindDeletion || event.Kind == nostr.KindDeletion || deletion.PubKey != event.PubKey {
+		return false
+	}
+
+	for _, tag := range deletion.Tags {
+		if len(tag) < 2 {
+			continue
+		}
+
+		switch tag[0] {
+		case "e":
+			if tag[1] == event.ID {
+				return true
+			}
+		case "a":
+			kind, pubkey, d, ok := parseAddress(tag[1])
+			if !ok || !isAddressed(kind) || kind != event.Kind || pubkey != event.PubKey || event.CreatedAt > deletion.CreatedAt {
+				continue
+			}
+			if !nostr.IsAddressableKind(kind) || d == event.Tags.GetD() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// EventAddress returns the "kind:pubkey:d" address of a replaceable or
+// addressable event ("" for other events)
+func EventAddress(event *nostr.Event) string {
+	switch {
+	case nostr.IsAddressableKind(event.Kind):
+		return fmt.Sprintf("%d:%s:%s", event.Kind, event.PubKey, event.Tags.GetD())
+	case nostr.IsReplaceableKind(event.Kind):
+		return fmt.Sprintf("%d:%s:", event.Kind, event.PubKey)
+	default:
+		return ""
+	}
+}
+
+// isAddressed reports whether events of a kind have an address: replaceable
+// and addressable kinds
+func isAddressed(kind int) bool {
+	return nostr.IsReplaceableKind(kind) || nostr.IsAddressableKind(kind)
+}
+
+// parseAddress splits a "kind:pubkey:d" address
+func parseAddress(address string) (kind int, pubkey string, d string, ok bool) {
+	parts := strings.SplitN(address, ":", 3)
+	if len(parts) != 3 {
+		return 0, "", "", false
+	}
+	kind, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, "", "", false
+	}
+	return kind, parts[1], parts[2], true
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+var (
+	alice = strings.Repeat("a", 64)
+	bob   = strings.Repeat("b", 64)
+)
+
+// testEvent returns an event with a fake ID built from n
+func testEvent(n int, author string, kind int, createdAt nostr.Timestamp, tags ...nostr.Tag) *nostr.Event {
+	return &nostr.Event{
+		ID:        fmt.Sprintf("%064x", n),
+		PubKey:    author,
+		CreatedAt: createdAt,
+		Kind:      kind,
+		Tags:      tags,
+	}
+}
+
+func TestDeletes(t *testing.T) {
+	note := testEvent(1, alice, 1, 100)
+	profile := testEvent(2, alice, 0, 100)
+	article := testEvent(3, alice, 30023, 100, nostr.Tag{"d", "post"})
+
+	tests := []struct {
+		name     string
+		deletion *nostr.Event
+		event    *nostr.Event
+		want     bool
+	}{
+		{"by ID", testEvent(10, alice, 5, 200, nostr.Tag{"e", note.ID}), note, true},
+		{"other ID", testEvent(10, alice, 5, 200, nostr.Tag{"e", profile.ID}), note, false},
+		{"someone else's event", testEvent(10, bob, 5, 200, nostr.Tag{"e", note.ID}), note, false},
+		{"not a deletion", testEvent(10, alice, 1, 200, nostr.Tag{"e", note.ID}), note, false},
+		{"a deletion", testEvent(10, alice, 5, 200, nostr.Tag{"e", note.ID}), testEvent(1, alice, 5, 100), false},
+		{"address", testEvent(10, alice, 5, 200, nostr.Tag{"a", "30023:" + alice + ":post"}), article, true},
+		{"address at the same second", testEvent(10, alice, 5, 100, nostr.Tag{"a", "30023:" + alice + ":post"}), article, true},
+		{"newer version", testEvent(10, alice, 5, 50, nostr.Tag{"a", "30023:" + alice + ":post"}), article, false},
+		{"other d tag", testEvent(10, alice, 5, 200, nostr.Tag{"a", "30023:" + alice + ":draft"}), article, false},
+		{"other kind", testEvent(10, alice, 5, 200, nostr.Tag{"a", "30024:" + alice + ":post"}), article, false},
+		{"address of someone else", testEvent(10, bob, 5, 200, nostr.Tag{"a", "30023:" + alice + ":post"}), article, false},
+		{"replaceable", testEvent(10, alice, 5, 200, nostr.Tag{"a", "0:" + alice + ":"}), profile, true},
+		{"replaceable with a d tag", testEvent(10, alice, 5, 200, nostr.Tag{"a", "0:" + alice + ":x"}), profile, true},
+		{"regular kind address", testEvent(10, alice, 5, 200, nostr.Tag{"a", "1:" + alice + ":"}), note, false},
+		{"invalid address", testEvent(10, alice, 5, 200, nostr.Tag{"a", "post"}), article, false},
+		{"short tag", testEvent(10, alice, 5, 200, nostr.Tag{"e"}), note, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Deletes(tt.deletion, tt.event); got != tt.want {
+				t.Errorf("Deletes() = %v, want %v", got, tt.want)
+			}
+
+			// The store must agree, whichever of the two it sees first
+			for _, deletionFirst := range []bool{false, true} {
+				s, err := Open(filepath.Join(t.TempDir(), "events.db"))
+				if err != nil {
+					t.Fatalf("Open() error = %v", err)
+				}
+				defer s.Close()
+
+				first, second := tt.event, tt.deletion
+				if deletionFirst {
+					first, second = second, first
+				}
+				if _, err := s.SaveEvent(first); err != nil {
+					t.Fatalf("SaveEvent() error = %v", err)
+				}
+				if _, err := s.SaveEvent(second); err != nil {
+					t.Fatalf("SaveEvent() error = %v", err)
+				}
+
+				if got := s.IsDeleted(tt.event); got != tt.want {
+					t.Errorf("IsDeleted() = %v (deletion first: %v), want %v", got, deletionFirst, tt.want)
+				}
+				stored, err := s.QueryEvents(nostr.Filter{IDs: []string{tt.event.ID}})
+				if err != nil {
+					t.Fatalf("QueryEvents() error = %v", err)
+				}
+				if kept := len(stored) == 1; kept == tt.want && tt.event.Kind != nostr.KindDeletion {
+					t.Errorf("event stored = %v (deletion first: %v), want %v", kept, deletionFirst, !tt.want)
+				}
+			}
+		})
+	}
+}
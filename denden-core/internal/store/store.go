@@ -0,0 +1,345 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr"
+
+	_ "modernc.org/sqlite" // Pure-Go SQLite driver (works with gomobile, no cgo)
+)
+
+// Store is a persistent local event store backed by SQLite
+// Every event the client sees or publishes is kept here, so feeds,
+// threads and chats can be answered offline and at startup
+type Store struct {
+	db *sql.DB
+}
+
+// schema creates the event and tag tables with the indexes used by QueryEvents,
+// the tombstones left by deletions (NIP-09: an event ID or an "a" address, and
+// the author whose events it hides), and a key-value table for small local
+// state (e.g. read markers)
+const schema = `
+CREATE TABLE IF NOT EXISTS events (
+	id         TEXT PRIMARY KEY,
+	pubkey     TEXT NOT NULL,
+	kind       INTEGER NOT NULL,
+	created_at INTEGER NOT NULL,
+	raw        TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_events_pubkey_kind ON events(pubkey, kind, created_at);
+CREATE INDEX IF NOT EXISTS idx_events_kind ON events(kind, created_at);
+CREATE INDEX IF NOT EXISTS idx_events_created_at ON events(created_at);
+
+CREATE TABLE IF NOT EXISTS tags (
+	event_id TEXT NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+	name     TEXT NOT NULL,
+	value    TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_tags_name_value ON tags(name, value);
+CREATE INDEX IF NOT EXISTS idx_tags_event_id ON tags(event_id);
+
+CREATE TABLE IF NOT EXISTS tombstones (
+	ref        TEXT NOT NULL,
+	pubkey     TEXT NOT NULL,
+	deleted_at INTEGER NOT NULL,
+	PRIMARY KEY (ref, pubkey)
+);
+
+CREATE TABLE IF NOT EXISTS settings (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+`
+
+// Open opens (or creates) the event store at the given path
+// Parameters:
+//   - dbPath: path to the SQLite database file
+//
+// Returns:
+//   - *Store: opened store
+//   - error: error if the database can't be opened or migrated
+func Open(dbPath string) (*Store, error) {
+	// Create directory if it doesn't exist
+	dir := filepath.Dir(dbPath)
+	if dir != "" && dir != "." {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create directory: %w", err)
+		}
+	}
+
+	dsn := dbPath + "?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event store: %w", err)
+	}
+
+	// SQLite allows a single writer; serialize access through one connection
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create event store schema: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// GetDefaultStorePath returns the default path for the event store
+// Returns: ~/.denden/denden.db
+func GetDefaultStorePath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	return filepath.Join(homeDir, ".denden", "denden.db"), nil
+}
+
+// SaveEvent stores an event
+// Duplicate IDs are ignored. For replaceable kinds (0, 3, 10000-19999) and
+// addressable kinds (30000-39999) only the newest version is kept.
+// Deletions (Kind 5) remove the events they reference and leave tombstones,
+// and events hidden by a tombstone aren't stored
+//
+// Returns:
+//   - bool: true if the event was new and stored
+//   - error: database error
+func (s *Store) SaveEvent(event *nostr.Event) (bool, error) {
+	raw, err := json.Marshal(event)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Deleted events stay deleted, even when a relay sends them again
+	if deleted, err := isTombstoned(tx, event); err != nil || deleted {
+		return false, err
+	}
+
+	// Replaceable events: skip if we already have something newer, otherwise drop the older ones
+	if nostr.IsReplaceableKind(event.Kind) || nostr.IsAddressableKind(event.Kind) {
+		older, newer, err := s.findReplaced(tx, event)
+		if err != nil {
+			return false, err
+		}
+		if newer {
+			return false, nil
+		}
+		for _, id := range older {
+			if _, err := tx.Exec(`DELETE FROM events WHERE id = ?`, id); err != nil {
+				return false, fmt.Errorf("failed to replace event: %w", err)
+			}
+		}
+	}
+
+	res, err := tx.Exec(
+		`INSERT OR IGNORE INTO events (id, pubkey, kind, created_at, raw) VALUES (?, ?, ?, ?, ?)`,
+		event.ID, event.PubKey, event.Kind, int64(event.CreatedAt), string(raw),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to insert event: %w", err)
+	}
+
+	inserted, _ := res.RowsAffected()
+	if inserted == 0 {
+		return false, nil
+	}
+
+	// Index single-letter tags, which are the only ones relays filter on (NIP-01)
+	for _, tag := range event.Tags {
+		if len(tag) < 2 || len(tag[0]) != 1 {
+			continue
+		}
+		if _, err := tx.Exec(
+			`INSERT INTO tags (event_id, name, value) VALUES (?, ?, ?)`,
+			event.ID, tag[0], tag[1],
+		); err != nil {
+			return false, fmt.Errorf("failed to insert tag: %w", err)
+		}
+	}
+
+	if event.Kind == nostr.KindDeletion {
+		if err := applyDeletion(tx, event); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit event: %w", err)
+	}
+	return true, nil
+}
+
+// findReplaced looks up stored versions of a replaceable event
+// Returns the IDs of older versions, and whether a newer version exists
+func (s *Store) findReplaced(tx *sql.Tx, event *nostr.Event) ([]string, bool, error) {
+	query := `SELECT id, created_at FROM events WHERE pubkey = ? AND kind = ?`
+	args := []any{event.PubKey, event.Kind}
+
+	if nostr.IsAddressableKind(event.Kind) {
+		query += ` AND id IN (SELECT event_id FROM tags WHERE name = 'd' AND value = ?)`
+		args = append(args, event.Tags.GetD())
+	}
+
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to look up replaceable event: %w", err)
+	}
+	defer rows.Close()
+
+	var older []string
+	for rows.Next() {
+		var id string
+		var createdAt int64
+		if err := rows.Scan(&id, &createdAt); err != nil {
+			return nil, false, fmt.Errorf("failed to scan replaceable event: %w", err)
+		}
+		if id == event.ID {
+			continue
+		}
+		// NIP-01: on equal timestamps the lowest ID wins
+		if createdAt > int64(event.CreatedAt) || (createdAt == int64(event.CreatedAt) && id < event.ID) {
+			return nil, true, nil
+		}
+		older = append(older, id)
+	}
+
+	return older, false, rows.Err()
+}
+
+// QueryEvents returns stored events matching a Nostr filter
+// Results are sorted by created_at (newest first), ID as tie-breaker
+//
+// Parameters:
+//   - filter: standard NIP-01 filter (IDs, Authors, Kinds, Tags, Since, Until, Limit)
+//
+// Returns:
+//   - []*nostr.Event: matching events
+//   - error: database error
+func (s *Store) QueryEvents(filter nostr.Filter) ([]*nostr.Event, error) {
+	var where []string
+	var args []any
+
+	if len(filter.IDs) > 0 {
+		where = append(where, "id IN ("+placeholders(len(filter.IDs))+")")
+		for _, id := range filter.IDs {
+			args = append(args, id)
+		}
+	}
+	if len(filter.Authors) > 0 {
+		where = append(where, "pubkey IN ("+placeholders(len(filter.Authors))+")")
+		for _, pk := range filter.Authors {
+			args = append(args, pk)
+		}
+	}
+	if len(filter.Kinds) > 0 {
+		where = append(where, "kind IN ("+placeholders(len(filter.Kinds))+")")
+		for _, k := range filter.Kinds {
+			args = append(args, k)
+		}
+	}
+	for name, values := range filter.Tags {
+		if len(values) == 0 {
+			continue
+		}
+		where = append(where, "id IN (SELECT event_id FROM tags WHERE name = ? AND value IN ("+placeholders(len(values))+"))")
+		args = append(args, name)
+		for _, v := range values {
+			args = append(args, v)
+		}
+	}
+	if filter.Since != nil {
+		where = append(where, "created_at >= ?")
+		args = append(args, int64(*filter.Since))
+	}
+	if filter.Until != nil {
+		where = append(where, "created_at <= ?")
+		args = append(args, int64(*filter.Until))
+	}
+
+	query := "SELECT raw FROM events"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id ASC"
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*nostr.Event
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+
+		var event nostr.Event
+		if err := json.Unmarshal([]byte(raw), &event); err != nil {
+			continue // Corrupt row, skip
+		}
+		events = append(events, &event)
+	}
+
+	return events, rows.Err()
+}
+
+// DeleteEvent removes an event (and its tags) from the store
+func (s *Store) DeleteEvent(eventID string) error {
+	_, err := s.db.Exec(`DELETE FROM events WHERE id = ?`, eventID)
+	if err != nil {
+		return fmt.Errorf("failed to delete event: %w", err)
+	}
+	return nil
+}
+
+// GetSetting returns a stored setting ("" if it was never set)
+func (s *Store) GetSetting(key string) (string, error) {
+	var value string
+	err := s.db.QueryRow(`SELECT value FROM settings WHERE key = ?`, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read setting: %w", err)
+	}
+	return value, nil
+}
+
+// SetSetting stores a setting, replacing its previous value
+func (s *Store) SetSetting(key, value string) error {
+	_, err := s.db.Exec(
+		`INSERT INTO settings (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value`,
+		key, value,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save setting: %w", err)
+	}
+	return nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// placeholders returns "?, ?, ?" with n question marks
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
@@ -0,0 +1,230 @@
+package store
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// openStore opens an empty store, closed when the test ends
+func openStore(t *testing.T) *Store {
+	t.Helper()
+
+	s, err := Open(filepath.Join(t.TempDir(), "events.db"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// save stores events, failing the test on database errors
+func save(t *testing.T, s *Store, events ...*nostr.Event) {
+	t.Helper()
+
+	for _, event := range events {
+		if _, err := s.SaveEvent(event); err != nil {
+			t.Fatalf("SaveEvent(%s) error = %v", event.ID, err)
+		}
+	}
+}
+
+// storedIDs returns the IDs QueryEvents returns for a filter, in order
+func storedIDs(t *testing.T, s *Store, filter nostr.Filter) []string {
+	t.Helper()
+
+	events, err := s.QueryEvents(filter)
+	if err != nil {
+		t.Fatalf("QueryEvents() error = %v", err)
+	}
+	ids := []string{}
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	return ids
+}
+
+// id returns the fake ID testEvent gives event n
+func id(n int) string {
+	return testEvent(n, "", 0, 0).ID
+}
+
+func TestSaveEventDuplicate(t *testing.T) {
+	s := openStore(t)
+	note := testEvent(1, alice, 1, 100, nostr.Tag{"t", "nostr"})
+
+	if isNew, err := s.SaveEvent(note); err != nil || !isNew {
+		t.Fatalf("first SaveEvent() = %v, %v, want true", isNew, err)
+	}
+	if isNew, err := s.SaveEvent(note); err != nil || isNew {
+		t.Fatalf("second SaveEvent() = %v, %v, want false", isNew, err)
+	}
+
+	// Saved once, tags too
+	if got := storedIDs(t, s, nostr.Filter{Tags: nostr.TagMap{"t": {"nostr"}}}); !reflect.DeepEqual(got, []string{note.ID}) {
+		t.Errorf("events tagged nostr = %v, want [%s]", got, note.ID)
+	}
+}
+
+func TestSaveEventReplaceable(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []*nostr.Event
+		isNew  []bool
+		kept   []string
+	}{
+		{
+			name:   "newer replaces older",
+			events: []*nostr.Event{testEvent(1, alice, 0, 100), testEvent(2, alice, 0, 200)},
+			isNew:  []bool{true, true},
+			kept:   []string{id(2)},
+		},
+		{
+			name:   "older is ignored",
+			events: []*nostr.Event{testEvent(2, alice, 0, 200), testEvent(1, alice, 0, 100)},
+			isNew:  []bool{true, false},
+			kept:   []string{id(2)},
+		},
+		{
+			name:   "same second, lowest ID wins",
+			events: []*nostr.Event{testEvent(2, alice, 3, 100), testEvent(1, alice, 3, 100), testEvent(3, alice, 3, 100)},
+			isNew:  []bool{true, true, false},
+			kept:   []string{id(1)},
+		},
+		{
+			name:   "one version per author",
+			events: []*nostr.Event{testEvent(1, alice, 10002, 100), testEvent(2, bob, 10002, 200)},
+			isNew:  []bool{true, true},
+			kept:   []string{id(2), id(1)},
+		},
+		{
+			name: "addressable, per d tag",
+			events: []*nostr.Event{
+				testEvent(1, alice, 30023, 100, nostr.Tag{"d", "post"}),
+				testEvent(2, alice, 30023, 200, nostr.Tag{"d", "draft"}),
+				testEvent(3, alice, 30023, 300, nostr.Tag{"d", "post"}),
+				testEvent(4, alice, 30023, 50, nostr.Tag{"d", "draft"}),
+			},
+			isNew: []bool{true, true, true, false},
+			kept:  []string{id(3), id(2)},
+		},
+		{
+			name:   "regular kinds keep every event",
+			events: []*nostr.Event{testEvent(1, alice, 1, 100), testEvent(2, alice, 1, 200)},
+			isNew:  []bool{true, true},
+			kept:   []string{id(2), id(1)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := openStore(t)
+			for i, event := range tt.events {
+				isNew, err := s.SaveEvent(event)
+				if err != nil {
+					t.Fatalf("SaveEvent(%d) error = %v", i, err)
+				}
+				if isNew != tt.isNew[i] {
+					t.Errorf("SaveEvent(%d) = %v, want %v", i, isNew, tt.isNew[i])
+				}
+			}
+
+			if got := storedIDs(t, s, nostr.Filter{}); !reflect.DeepEqual(got, tt.kept) {
+				t.Errorf("stored = %v, want %v", got, tt.kept)
+			}
+		})
+	}
+}
+
+func TestQueryEvents(t *testing.T) {
+	s := openStore(t)
+	save(t, s,
+		testEvent(1, alice, 1, 100, nostr.Tag{"t", "nostr"}),
+		testEvent(2, bob, 1, 200, nostr.Tag{"e", id(1)}, nostr.Tag{"t", "go"}),
+		testEvent(3, alice, 7, 200, nostr.Tag{"e", id(1)}),
+		testEvent(4, bob, 6, 300, nostr.Tag{"e", id(2)}, nostr.Tag{"alt", "repost"}),
+		testEvent(5, alice, 1, 400, nostr.Tag{"t", "nostr"}, nostr.Tag{"t", "go"}),
+	)
+
+	since, until := nostr.Timestamp(200), nostr.Timestamp(300)
+	tests := []struct {
+		name   string
+		filter nostr.Filter
+		want   []string
+	}{
+		{"everything, newest first and ID on ties", nostr.Filter{}, []string{id(5), id(4), id(2), id(3), id(1)}},
+		{"ids", nostr.Filter{IDs: []string{id(1), id(4), id(9)}}, []string{id(4), id(1)}},
+		{"authors", nostr.Filter{Authors: []string{bob}}, []string{id(4), id(2)}},
+		{"kinds", nostr.Filter{Kinds: []int{6, 7}}, []string{id(4), id(3)}},
+		{"e tag", nostr.Filter{Tags: nostr.TagMap{"e": {id(1)}}}, []string{id(2), id(3)}},
+		{"tag values are ORed", nostr.Filter{Tags: nostr.TagMap{"e": {id(1), id(2)}}}, []string{id(4), id(2), id(3)}},
+		{"tag names are ANDed", nostr.Filter{Tags: nostr.TagMap{"t": {"nostr"}, "e": {id(1)}}}, []string{}},
+		{"repeated tag", nostr.Filter{Tags: nostr.TagMap{"t": {"go"}}}, []string{id(5), id(2)}},
+		{"multi-letter tags aren't indexed", nostr.Filter{Tags: nostr.TagMap{"alt": {"repost"}}}, []string{}},
+		{"since and until are inclusive", nostr.Filter{Since: &since, Until: &until}, []string{id(4), id(2), id(3)}},
+		{"limit keeps the newest", nostr.Filter{Kinds: []int{1}, Limit: 2}, []string{id(5), id(2)}},
+		{"limit with until", nostr.Filter{Until: &until, Limit: 2}, []string{id(4), id(2)}},
+		{"combined", nostr.Filter{Authors: []string{alice}, Kinds: []int{1}, Since: &since}, []string{id(5)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := storedIDs(t, s, tt.filter); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("QueryEvents() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryEventsReturnsStoredEvent(t *testing.T) {
+	s := openStore(t)
+	note := testEvent(1, alice, 1, 100, nostr.Tag{"t", "nostr"}, nostr.Tag{"alt", "a note"})
+	note.Content = "hello"
+	save(t, s, note)
+
+	events, err := s.QueryEvents(nostr.Filter{IDs: []string{note.ID}})
+	if err != nil {
+		t.Fatalf("QueryEvents() error = %v", err)
+	}
+	if len(events) != 1 || !reflect.DeepEqual(*events[0], *note) {
+		t.Errorf("QueryEvents() = %v, want %v", events, note)
+	}
+}
+
+func TestDeleteEvent(t *testing.T) {
+	s := openStore(t)
+	save(t, s, testEvent(1, alice, 1, 100, nostr.Tag{"t", "nostr"}), testEvent(2, alice, 1, 200))
+
+	if err := s.DeleteEvent(id(1)); err != nil {
+		t.Fatalf("DeleteEvent() error = %v", err)
+	}
+	if got := storedIDs(t, s, nostr.Filter{}); !reflect.DeepEqual(got, []string{id(2)}) {
+		t.Errorf("stored = %v, want [%s]", got, id(2))
+	}
+	if got := storedIDs(t, s, nostr.Filter{Tags: nostr.TagMap{"t": {"nostr"}}}); len(got) != 0 {
+		t.Errorf("tag index still returns %v", got)
+	}
+
+	// Not a deletion: the event can come back
+	if isNew, err := s.SaveEvent(testEvent(1, alice, 1, 100)); err != nil || !isNew {
+		t.Errorf("SaveEvent() after DeleteEvent = %v, %v, want true", isNew, err)
+	}
+}
+
+func TestSettings(t *testing.T) {
+	s := openStore(t)
+
+	if value, err := s.GetSetting("readAt"); err != nil || value != "" {
+		t.Fatalf("GetSetting() = %q, %v, want empty", value, err)
+	}
+	for _, value := range []string{"100", "200"} {
+		if err := s.SetSetting("readAt", value); err != nil {
+			t.Fatalf("SetSetting() error = %v", err)
+		}
+		if got, err := s.GetSetting("readAt"); err != nil || got != value {
+			t.Errorf("GetSetting() = %q, %v, want %q", got, err, value)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package thread
+
+import (
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// NIP-10 "e" tag markers
+const (
+	MarkerRoot    = "root"    // The thread's first post
+	MarkerReply   = "reply"   // The post being replied to
+	MarkerMention = "mention" // A referenced post that isn't part of the thread
+)
+
+// References are the thread positions an event points to (NIP-10)
+type References struct {
+	Root      string // Root event ID ("" if the event isn't a reply)
+	RootRelay string // Relay hint for the root
+	RootPub   string // Root author, if the tag carries it
+	Reply     string // Direct parent event ID (the root for direct replies)
+}
+
+// IsReply reports whether the event replies to another one
+func (r References) IsReply() bool {
+	return r.Reply != ""
+}
+
+// Parse reads the root and parent of an event from its "e" tags (NIP-10)
+// Marked tags ("root", "reply") are preferred; unmarked tags are read the
+// deprecated positional way (first = root, last = parent). Mentions are ignored
+func Parse(event *nostr.Event) References {
+	var refs References
+	var positional []nostr.Tag
+	marked := false
+
+	for _, tag := range event.Tags {
+		if len(tag) < 2 || tag[0] != "e" {
+			continue
+		}
+
+		marker := ""
+		if len(tag) >= 4 {
+			marker = tag[3]
+		}
+
+		switch marker {
+		case MarkerRoot:
+			marked = true
+			refs.Root = tag[1]
+			refs.RootRelay = tag[2]
+			if len(tag) >= 5 {
+				refs.RootPub = tag[4]
+			}
+		case MarkerReply:
+			marked = true
+			refs.Reply = tag[1]
+		case MarkerMention:
+			// Not part of the thread
+		default:
+			positional = append(positional, tag)
+		}
+	}
+
+	if !marked && len(positional) > 0 {
+		first := positional[0]
+		refs.Root = first[1]
+		if len(first) >= 3 {
+			refs.RootRelay = first[2]
+		}
+		refs.Reply = positional[len(positional)-1][1]
+	}
+
+	// A direct reply to the root only carries the root marker, and some
+	// clients only write the reply marker
+	if refs.Reply == "" {
+		refs.Reply = refs.Root
+	}
+	if refs.Root == "" {
+		refs.Root = refs.Reply
+	}
+
+	return refs
+}
+
+// ReplyTags builds the tags of a reply to parent (NIP-10)
+// The parent's root is carried over as "root" (the parent itself if it's the root),
+// the parent is marked "reply", and "p" tags notify the parent's author and
+// everyone the parent tagged. The reply's author is left out of the "p" tags
+//
+// Parameters:
+//   - parent: the event being replied to
+//   - relayHint: relay where the parent can be found ("" if unknown)
+//   - author: public key of the reply's author (hex)
+//
+// Returns:
+//   - nostr.Tags: "e" tags, then "p" tags
+func ReplyTags(parent *nostr.Event, relayHint string, author string) nostr.Tags {
+	var tags nostr.Tags
+
+	refs := Parse(parent)
+	if refs.IsReply() {
+		rootRelay := refs.RootRelay
+		if rootRelay == "" {
+			rootRelay = relayHint
+		}
+		rootTag := nostr.Tag{"e", refs.Root, rootRelay, MarkerRoot}
+		if refs.RootPub != "" {
+			rootTag = append(rootTag, refs.RootPub)
+		}
+		tags = append(tags,
+			rootTag,
+			nostr.Tag{"e", parent.ID, relayHint, MarkerReply, parent.PubKey},
+		)
+	} else {
+		// Replying to the root itself
+		tags = append(tags, nostr.Tag{"e", parent.ID, relayHint, MarkerRoot, parent.PubKey})
+	}
+
+	// Parent author first, then the thread participants it notified
+	seen := map[string]bool{author: true}
+	addParticipant := func(pubkey string) {
+		if pubkey == "" || seen[pubkey] {
+			return
+		}
+		seen[pubkey] = true
+		tags = append(tags, nostr.Tag{"p", pubkey})
+	}
+
+	addParticipant(parent.PubKey)
+	for _, tag := range parent.Tags {
+		if len(tag) >= 2 && tag[0] == "p" {
+			addParticipant(tag[1])
+		}
+	}
+
+	return tags
+}
@@ -0,0 +1,194 @@
+package thread
+
+import (
+	"sort"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// SortOrder is how siblings are ordered in a thread tree
+type SortOrder int
+
+const (
+	SortByTime  SortOrder = iota // Oldest first (conversation order)
+	SortByScore                  // Highest score first, then oldest
+)
+
+// Node is a post in a thread tree
+// Posts whose event isn't available (deleted, or not on our relays) are
+// placeholders: Event is nil, and their replies hang below them as usual
+type Node struct {
+	ID          string
+	Event       *nostr.Event // nil for a placeholder
+	Parent      *Node        // nil for the root
+	Children    []*Node
+	Depth       int // 0 for the root
+	Descendants int // Replies below this node, at any depth
+	Score       int // Set by Sort
+}
+
+// IsPlaceholder reports whether the node's event is missing
+func (n *Node) IsPlaceholder() bool {
+	return n.Event == nil
+}
+
+// Build assembles the thread below rootID from its events (NIP-10)
+// Each event hangs below its parent (References.Reply). A parent that isn't in
+// events becomes a placeholder below the root, so orphans keep their context;
+// events whose parents loop back to them are attached to the root
+//
+// Parameters:
+//   - rootID: ID of the thread's root event
+//   - events: the root (optional) and the replies, in any order, duplicates allowed
+//
+// Returns:
+//   - *Node: the root, with Depth and Descendants set on every node
+func Build(rootID string, events []*nostr.Event) *Node {
+	root := &Node{ID: rootID}
+	nodes := map[string]*Node{rootID: root}
+	for _, event := range events {
+		if event.ID == rootID {
+			root.Event = event
+			continue
+		}
+		if _, ok := nodes[event.ID]; !ok {
+			nodes[event.ID] = &Node{ID: event.ID, Event: event}
+		}
+	}
+
+	// Parent of every node; missing parents become placeholders below the root
+	parentOf := make(map[string]string, len(nodes))
+	for id, node := range nodes {
+		if node == root || node.Event == nil {
+			continue
+		}
+		parentID := Parse(node.Event).Reply
+		if parentID == "" || parentID == id {
+			parentID = rootID
+		}
+		if _, ok := nodes[parentID]; !ok {
+			nodes[parentID] = &Node{ID: parentID}
+			parentOf[parentID] = rootID
+		}
+		parentOf[id] = parentID
+	}
+
+	// Break cycles (A replies to B, B replies to A) by attaching to the root
+	// The oldest post of a cycle is cut, so the same events always give the same tree
+	ids := make([]string, 0, len(parentOf))
+	for id := range parentOf {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if ta, tb := nodes[ids[i]].createdAt(), nodes[ids[j]].createdAt(); ta != tb {
+			return ta < tb
+		}
+		return ids[i] < ids[j]
+	})
+	for _, id := range ids {
+		if leadsBack(id, parentOf, len(nodes)) {
+			parentOf[id] = rootID
+		}
+	}
+
+	for _, id := range ids {
+		node, parent := nodes[id], nodes[parentOf[id]]
+		node.Parent = parent
+		parent.Children = append(parent.Children, node)
+	}
+
+	root.measure(0)
+	root.Sort(SortByTime, nil)
+	return root
+}
+
+// leadsBack reports whether following parents from id comes back to id
+func leadsBack(id string, parentOf map[string]string, limit int) bool {
+	current := id
+	for step := 0; step < limit; step++ {
+		parentID, ok := parentOf[current]
+		if !ok {
+			return false
+		}
+		if parentID == id {
+			return true
+		}
+		current = parentID
+	}
+	return false // Stuck in a cycle that doesn't include id, its members are cut instead
+}
+
+// measure sets Depth and Descendants below n
+func (n *Node) measure(depth int) int {
+	n.Depth = depth
+	n.Descendants = 0
+	for _, child := range n.Children {
+		n.Descendants += 1 + child.measure(depth+1)
+	}
+	return n.Descendants
+}
+
+// Sort orders the siblings of every node below n
+// Parameters:
+//   - order: SortByTime or SortByScore
+//   - scores: score per event ID (e.g. reactions), stored on each node (can be nil)
+func (n *Node) Sort(order SortOrder, scores map[string]int) {
+	n.Score = scores[n.ID]
+	for _, child := range n.Children {
+		child.Sort(order, scores)
+	}
+
+	sort.SliceStable(n.Children, func(i, j int) bool {
+		a, b := n.Children[i], n.Children[j]
+		if order == SortByScore && a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if ta, tb := a.createdAt(), b.createdAt(); ta != tb {
+			return ta < tb
+		}
+		return a.ID < b.ID
+	})
+}
+
+// createdAt is the node's time; placeholders take the time of their earliest reply
+func (n *Node) createdAt() nostr.Timestamp {
+	if n.Event != nil {
+		return n.Event.CreatedAt
+	}
+
+	var earliest nostr.Timestamp
+	for _, child := range n.Children {
+		if t := child.createdAt(); earliest == 0 || t < earliest {
+			earliest = t
+		}
+	}
+	return earliest
+}
+
+// IDs returns the IDs of n and every node below it
+func (n *Node) IDs() []string {
+	ids := []string{n.ID}
+	for _, child := range n.Children {
+		ids = append(ids, child.IDs()...)
+	}
+	return ids
+}
+
+// MissingParents returns the parents that replies point to but aren't in events
+// (the root excluded), so they can be fetched before calling Build
+func MissingParents(rootID string, events []*nostr.Event) []string {
+	have := map[string]bool{rootID: true}
+	for _, event := range events {
+		have[event.ID] = true
+	}
+
+	var missing []string
+	for _, event := range events {
+		parentID := Parse(event).Reply
+		if parentID != "" && !have[parentID] {
+			have[parentID] = true
+			missing = append(missing, parentID)
+		}
+	}
+	return missing
+}
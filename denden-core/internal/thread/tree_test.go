@@ -0,0 +1,271 @@
+package thread
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// post returns an event replying to parent in the thread of root ("" parent = the root post)
+// Build doesn't check IDs, so short readable ones are used
+func post(id, root, parent string, createdAt nostr.Timestamp) *nostr.Event {
+	event := &nostr.Event{ID: id, CreatedAt: createdAt, Kind: nostr.KindTextNote}
+	if parent != "" {
+		event.Tags = nostr.Tags{
+			{"e", root, "", MarkerRoot},
+			{"e", parent, "", MarkerReply},
+		}
+	}
+	return event
+}
+
+// shape describes a tree as "id(child,child)", placeholders marked with "?"
+func shape(n *Node) string {
+	var b strings.Builder
+	b.WriteString(n.ID)
+	if n.IsPlaceholder() {
+		b.WriteString("?")
+	}
+	if len(n.Children) > 0 {
+		b.WriteString("(")
+		for i, child := range n.Children {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString(shape(child))
+		}
+		b.WriteString(")")
+	}
+	return b.String()
+}
+
+// checkTree verifies the links, Depth and Descendants of every node, and that
+// every node appears once
+func checkTree(t *testing.T, root *Node, wantNodes int) {
+	t.Helper()
+
+	seen := make(map[string]bool)
+	var walk func(n *Node, parent *Node, depth int) int
+	walk = func(n *Node, parent *Node, depth int) int {
+		if seen[n.ID] {
+			t.Fatalf("%s appears twice", n.ID)
+		}
+		seen[n.ID] = true
+		if n.Parent != parent {
+			t.Errorf("%s has the wrong parent", n.ID)
+		}
+		if n.Depth != depth {
+			t.Errorf("%s: Depth = %d, want %d", n.ID, n.Depth, depth)
+		}
+		descendants := 0
+		for _, child := range n.Children {
+			descendants += 1 + walk(child, n, depth+1)
+		}
+		if n.Descendants != descendants {
+			t.Errorf("%s: Descendants = %d, want %d", n.ID, n.Descendants, descendants)
+		}
+		return descendants
+	}
+	walk(root, nil, 0)
+
+	if len(seen) != wantNodes {
+		t.Errorf("tree has %d nodes, want %d", len(seen), wantNodes)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	events := []*nostr.Event{
+		post("d", "r", "b", 50),
+		post("c", "r", "r", 30),
+		post("r", "", "", 10),
+		post("b", "r", "a", 40),
+		post("a", "r", "r", 20),
+		post("a", "r", "r", 20), // Duplicate
+	}
+
+	root := Build("r", events)
+	checkTree(t, root, 5)
+	if got, want := shape(root), "r(a(b(d)),c)"; got != want {
+		t.Errorf("shape = %s, want %s", got, want)
+	}
+	if root.Event == nil || root.Event.ID != "r" {
+		t.Errorf("root event not set")
+	}
+}
+
+func TestBuildUnmarkedTags(t *testing.T) {
+	// Deprecated positional "e" tags: first = root, last = parent
+	reply := &nostr.Event{ID: "b", CreatedAt: 30, Tags: nostr.Tags{{"e", "r"}, {"e", "a"}}}
+	direct := &nostr.Event{ID: "a", CreatedAt: 20, Tags: nostr.Tags{{"e", "r"}}}
+	mention := &nostr.Event{ID: "c", CreatedAt: 40, Tags: nostr.Tags{{"e", "r", "", MarkerRoot}, {"e", "a", "", MarkerMention}}}
+
+	root := Build("r", []*nostr.Event{reply, direct, mention})
+	checkTree(t, root, 4)
+	if got, want := shape(root), "r?(a(b),c)"; got != want {
+		t.Errorf("shape = %s, want %s", got, want)
+	}
+}
+
+func TestBuildPlaceholders(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []*nostr.Event
+		want   string
+		nodes  int
+	}{
+		{
+			"missing root",
+			[]*nostr.Event{post("a", "r", "r", 20)},
+			"r?(a)",
+			2,
+		},
+		{
+			"missing parent",
+			[]*nostr.Event{post("r", "", "", 10), post("b", "r", "x", 30), post("c", "r", "x", 40)},
+			"r(x?(b,c))",
+			4,
+		},
+		{
+			"missing grandparent",
+			[]*nostr.Event{post("r", "", "", 10), post("c", "r", "b", 40), post("b", "r", "x", 30)},
+			"r(x?(b(c)))",
+			4,
+		},
+		{
+			// Placeholders sort by their earliest reply
+			"placeholder between posts",
+			[]*nostr.Event{post("r", "", "", 10), post("a", "r", "r", 20), post("b", "r", "x", 30), post("c", "r", "r", 40)},
+			"r(a,x?(b),c)",
+			5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := Build("r", tt.events)
+			checkTree(t, root, tt.nodes)
+			if got := shape(root); got != tt.want {
+				t.Errorf("shape = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildCycles(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []*nostr.Event
+		want   string
+		nodes  int
+	}{
+		{
+			"replies to itself",
+			[]*nostr.Event{post("r", "", "", 10), post("a", "r", "a", 20)},
+			"r(a)",
+			2,
+		},
+		{
+			"two posts reply to each other",
+			[]*nostr.Event{post("r", "", "", 10), post("a", "r", "b", 20), post("b", "r", "a", 30)},
+			"r(a(b))",
+			3,
+		},
+		{
+			"three posts in a loop",
+			[]*nostr.Event{post("r", "", "", 10), post("a", "r", "c", 20), post("b", "r", "a", 30), post("c", "r", "b", 40)},
+			"r(a(b(c)))",
+			4,
+		},
+		{
+			"reply into a loop",
+			[]*nostr.Event{post("r", "", "", 10), post("a", "r", "b", 20), post("b", "r", "a", 30), post("d", "r", "b", 50)},
+			"r(a(b(d)))",
+			4,
+		},
+		{
+			"loop without the root",
+			[]*nostr.Event{post("a", "r", "b", 20), post("b", "r", "a", 30)},
+			"r?(a(b))",
+			3,
+		},
+		{
+			"root replies to a reply",
+			[]*nostr.Event{post("r", "x", "a", 10), post("a", "r", "r", 20)},
+			"r(a)",
+			2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := Build("r", tt.events)
+			checkTree(t, root, tt.nodes)
+			if got := shape(root); got != tt.want {
+				t.Errorf("shape = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildIsDeterministic(t *testing.T) {
+	events := []*nostr.Event{
+		post("r", "", "", 10),
+		post("a", "r", "c", 20),
+		post("b", "r", "a", 30),
+		post("c", "r", "b", 40),
+		post("d", "r", "x", 50),
+		post("e", "r", "d", 60),
+		post("f", "r", "r", 60),
+	}
+	want := shape(Build("r", events))
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		shuffled := append([]*nostr.Event(nil), events...)
+		rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+		if got := shape(Build("r", shuffled)); got != want {
+			t.Fatalf("shape = %s, want %s", got, want)
+		}
+	}
+}
+
+func TestSortByScore(t *testing.T) {
+	root := Build("r", []*nostr.Event{
+		post("r", "", "", 10),
+		post("a", "r", "r", 20),
+		post("b", "r", "r", 30),
+		post("c", "r", "r", 40),
+		post("d", "r", "a", 50),
+		post("e", "r", "a", 60),
+	})
+
+	root.Sort(SortByScore, map[string]int{"b": 5, "c": 5, "e": 1})
+	if got, want := shape(root), "r(b,c,a(e,d))"; got != want {
+		t.Errorf("shape = %s, want %s", got, want)
+	}
+	if root.Children[0].Score != 5 {
+		t.Errorf("Score = %d, want 5", root.Children[0].Score)
+	}
+
+	root.Sort(SortByTime, nil)
+	if got, want := shape(root), "r(a(d,e),b,c)"; got != want {
+		t.Errorf("shape = %s, want %s", got, want)
+	}
+}
+
+func TestMissingParents(t *testing.T) {
+	events := []*nostr.Event{
+		post("a", "r", "r", 20),
+		post("b", "r", "x", 30),
+		post("c", "r", "x", 40),
+		post("d", "r", "a", 50),
+		post("e", "r", "y", 60),
+	}
+
+	got := MissingParents("r", events)
+	if strings.Join(got, ",") != "x,y" {
+		t.Errorf("MissingParents = %v, want [x y]", got)
+	}
+}
@@ -0,0 +1,122 @@
+package zap
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil/bech32"
+)
+
+// ErrInvalidInvoice is returned for invoices that aren't BOLT11 or carry no amount
+var ErrInvalidInvoice = errors.New("invalid bolt11 invoice")
+
+// BOLT11 layout, in 5-bit groups
+const (
+	timestampGroups = 7   // Invoice creation time
+	signatureGroups = 104 // 65-byte signature and recovery ID
+	tagDescription  = 13  // "d": description
+	tagDescHash     = 23  // "h": SHA-256 of the description
+)
+
+// multipliers are the amount units of an invoice: msats millisatoshis per
+// "per" units. 0 is a plain amount, in bitcoin
+var multipliers = map[byte]struct{ msats, per int64 }{
+	0:   {100_000_000_000, 1},
+	'm': {100_000_000, 1},
+	'u': {100_000, 1},
+	'n': {100, 1},
+	'p': {1, 10},
+}
+
+// Invoice is what a zap receipt needs from a BOLT11 invoice
+// The invoice signature isn't checked: the receipt's signer (the recipient's
+// LNURL server) vouches for it
+type Invoice struct {
+	MilliSats       int64  // Amount in millisatoshis
+	DescriptionHash []byte // "h" field (nil if the invoice has none)
+	Description     string // "d" field
+}
+
+// DecodeInvoice reads the amount and description fields of a BOLT11 invoice
+// Parameters:
+//   - invoice: the invoice ("lnbc10u1p..."), without a "lightning:" prefix
+//
+// Returns:
+//   - Invoice: the amount and description fields
+//   - error: ErrInvalidInvoice (wrapped) if it can't be read or has no amount
+func DecodeInvoice(invoice string) (Invoice, error) {
+	hrp, data, err := bech32.DecodeNoLimit(strings.ToLower(invoice))
+	if err != nil {
+		return Invoice{}, fmt.Errorf("%w: %v", ErrInvalidInvoice, err)
+	}
+
+	msats, err := invoiceAmount(hrp)
+	if err != nil {
+		return Invoice{}, err
+	}
+	inv := Invoice{MilliSats: msats}
+
+	if len(data) < timestampGroups+signatureGroups {
+		return Invoice{}, ErrInvalidInvoice
+	}
+	fields := data[timestampGroups : len(data)-signatureGroups]
+	for len(fields) > 0 {
+		if len(fields) < 3 {
+			return Invoice{}, ErrInvalidInvoice
+		}
+		tag := fields[0]
+		length := int(fields[1])<<5 | int(fields[2])
+		if len(fields) < 3+length {
+			return Invoice{}, ErrInvalidInvoice
+		}
+		value := fields[3 : 3+length]
+		fields = fields[3+length:]
+
+		switch tag {
+		case tagDescHash:
+			if hash, err := bech32.ConvertBits(value, 5, 8, false); err == nil && len(hash) == 32 {
+				inv.DescriptionHash = hash
+			}
+		case tagDescription:
+			if text, err := bech32.ConvertBits(value, 5, 8, false); err == nil {
+				inv.Description = string(text)
+			}
+		}
+	}
+
+	return inv, nil
+}
+
+// invoiceAmount reads the amount from the human-readable part ("lnbc2500u")
+func invoiceAmount(hrp string) (int64, error) {
+	if !strings.HasPrefix(hrp, "ln") {
+		return 0, ErrInvalidInvoice
+	}
+
+	// Currency prefix (bc, tb, bcrt, sb...), then the amount and its multiplier
+	rest := strings.TrimLeft(hrp[2:], "abcdefghijklmnopqrstuvwxyz")
+	if rest == "" {
+		return 0, fmt.Errorf("%w: no amount", ErrInvalidInvoice)
+	}
+
+	unit := multipliers[0]
+	if m, ok := multipliers[rest[len(rest)-1]]; ok {
+		unit = m
+		rest = rest[:len(rest)-1]
+	}
+	if rest == "" || strings.Trim(rest, "0123456789") != "" {
+		return 0, ErrInvalidInvoice
+	}
+
+	amount, err := strconv.ParseInt(rest, 10, 64)
+	if err != nil || amount <= 0 || amount > math.MaxInt64/unit.msats {
+		return 0, ErrInvalidInvoice
+	}
+	if amount%unit.per != 0 {
+		return 0, ErrInvalidInvoice // Fractions of a millisatoshi
+	}
+	return amount * unit.msats / unit.per, nil
+}
@@ -0,0 +1,97 @@
+package zap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil/bech32"
+	"github.com/nbd-wtf/go-nostr"
+)
+
+var (
+	// ErrNoLightningAddress is returned for profiles without a usable lud16 or lud06
+	ErrNoLightningAddress = errors.New("no lightning address")
+
+	// ErrZapsNotSupported is returned when an LNURL server doesn't issue zap receipts
+	ErrZapsNotSupported = errors.New("lightning server doesn't support zaps")
+)
+
+// maxLNURLResponse bounds the LNURL-pay response read from a server
+const maxLNURLResponse = 64 * 1024
+
+// PayURL returns the LNURL-pay endpoint of a profile's lightning address
+// Parameters:
+//   - lud16: lightning address ("name@domain", LUD-16), preferred
+//   - lud06: bech32 LNURL ("lnurl1...", LUD-06)
+//
+// Returns:
+//   - string: the https URL to fetch
+//   - error: ErrNoLightningAddress (wrapped) if neither can be used
+func PayURL(lud16, lud06 string) (string, error) {
+	if name, domain, ok := strings.Cut(strings.TrimSpace(lud16), "@"); ok && name != "" && domain != "" && !strings.ContainsAny(domain, "/?#@") {
+		endpoint := url.URL{Scheme: "https", Host: strings.ToLower(domain), Path: "/.well-known/lnurlp/" + strings.ToLower(name)}
+		return endpoint.String(), nil
+	}
+
+	if lud06 = strings.ToLower(strings.TrimSpace(lud06)); lud06 != "" {
+		hrp, data, err := bech32.DecodeNoLimit(lud06)
+		if err != nil || hrp != "lnurl" {
+			return "", fmt.Errorf("%w: invalid lud06", ErrNoLightningAddress)
+		}
+		raw, err := bech32.ConvertBits(data, 5, 8, false)
+		if err != nil {
+			return "", fmt.Errorf("%w: invalid lud06", ErrNoLightningAddress)
+		}
+		if endpoint, err := url.Parse(string(raw)); err == nil && endpoint.Scheme == "https" && endpoint.Host != "" {
+			return endpoint.String(), nil
+		}
+		return "", fmt.Errorf("%w: lud06 isn't an https URL", ErrNoLightningAddress)
+	}
+
+	return "", ErrNoLightningAddress
+}
+
+// FetchSigner asks an LNURL-pay server for the key it signs zap receipts with
+// (NIP-57: "allowsNostr" and "nostrPubkey" in the LNURL-pay response)
+// Parameters:
+//   - ctx: bounds the request
+//   - payURL: endpoint from PayURL
+//
+// Returns:
+//   - string: the server's nostrPubkey (hex)
+//   - error: ErrZapsNotSupported if the server doesn't issue receipts, or a request error
+func FetchSigner(ctx context.Context, payURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, payURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create LNURL request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch LNURL: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch LNURL: status %d", resp.StatusCode)
+	}
+
+	var payParams struct {
+		AllowsNostr bool   `json:"allowsNostr"`
+		NostrPubkey string `json:"nostrPubkey"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxLNURLResponse)).Decode(&payParams); err != nil {
+		return "", fmt.Errorf("failed to parse LNURL response: %w", err)
+	}
+
+	if !payParams.AllowsNostr || !nostr.IsValid32ByteHex(payParams.NostrPubkey) {
+		return "", ErrZapsNotSupported
+	}
+	return payParams.NostrPubkey, nil
+}
@@ -0,0 +1,149 @@
+package zap
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+var (
+	// ErrInvalidRequest is returned when a receipt's zap request (Kind 9734) is
+	// missing, unsigned, or about another recipient or event
+	ErrInvalidRequest = errors.New("invalid zap request")
+
+	// ErrAmountMismatch is returned when the invoice isn't for the amount the
+	// zap request asked for
+	ErrAmountMismatch = errors.New("invoice amount doesn't match the zap request")
+
+	// ErrDescriptionMismatch is returned when the invoice wasn't made for the zap request
+	ErrDescriptionMismatch = errors.New("invoice description doesn't match the zap request")
+
+	// ErrUnknownSigner is returned when the recipient's LNURL server key isn't known
+	ErrUnknownSigner = errors.New("unknown zap receipt signer")
+
+	// ErrWrongSigner is returned when a receipt isn't signed by the recipient's LNURL server
+	ErrWrongSigner = errors.New("zap receipt not signed by the recipient's lightning server")
+)
+
+// Receipt is what a zap receipt (Kind 9735, NIP-57) says about a zap
+type Receipt struct {
+	Sender    string // Who zapped (the zap request's author)
+	Recipient string // Who was zapped ("p" tag)
+	Target    string // Zapped event ("" for a profile zap)
+	Sats      int64  // Amount paid, from the bolt11 invoice
+	Signer    string // Who issued the receipt (should be the recipient's LNURL server)
+}
+
+// ParseReceipt reads and checks a zap receipt (NIP-57 appendix F)
+// The amount comes from the receipt's bolt11 invoice, which must be for the
+// embedded zap request: same description (or description hash) and, if the
+// request names one, same amount. The zap request must be signed and about the
+// same recipient and event. Who signed the receipt is checked by Signers.Verify
+//
+// Returns:
+//   - Receipt: the zap
+//   - error: ErrInvalidInvoice, ErrInvalidRequest, ErrAmountMismatch or ErrDescriptionMismatch (wrapped)
+func ParseReceipt(receipt *nostr.Event) (Receipt, error) {
+	r := Receipt{
+		Recipient: tagValue(receipt, "p"),
+		Target:    tagValue(receipt, "e"),
+		Signer:    receipt.PubKey,
+	}
+
+	invoice, err := DecodeInvoice(tagValue(receipt, "bolt11"))
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	description := tagValue(receipt, "description")
+	var request nostr.Event
+	if err := json.Unmarshal([]byte(description), &request); err != nil {
+		return Receipt{}, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+	if request.Kind != nostr.KindZapRequest || !request.CheckID() {
+		return Receipt{}, ErrInvalidRequest
+	}
+	if ok, err := request.CheckSignature(); err != nil || !ok {
+		return Receipt{}, fmt.Errorf("%w: bad signature", ErrInvalidRequest)
+	}
+	if tagValue(&request, "p") != r.Recipient || tagValue(&request, "e") != r.Target {
+		return Receipt{}, fmt.Errorf("%w: not about the receipt's recipient and event", ErrInvalidRequest)
+	}
+
+	hash := sha256.Sum256([]byte(description))
+	switch {
+	case invoice.DescriptionHash != nil:
+		if !bytes.Equal(invoice.DescriptionHash, hash[:]) {
+			return Receipt{}, ErrDescriptionMismatch
+		}
+	case invoice.Description != description:
+		return Receipt{}, ErrDescriptionMismatch
+	}
+
+	if amount := tagValue(&request, "amount"); amount != "" {
+		msats, err := strconv.ParseInt(amount, 10, 64)
+		if err != nil || msats != invoice.MilliSats {
+			return Receipt{}, fmt.Errorf("%w: invoice is for %d msats, request for %s", ErrAmountMismatch, invoice.MilliSats, amount)
+		}
+	}
+
+	r.Sender = request.PubKey
+	r.Sats = invoice.MilliSats / 1000
+	return r, nil
+}
+
+// Signers maps zap recipients (hex public keys) to the key their LNURL server
+// signs receipts with (its nostrPubkey, NIP-57). Anyone can publish a Kind 9735
+// event, so receipts from other keys are forged
+type Signers map[string]string
+
+// Verify parses a receipt and checks that the recipient's LNURL server signed it
+// Returns:
+//   - Receipt: the zap
+//   - error: a ParseReceipt error, ErrUnknownSigner or ErrWrongSigner
+func (s Signers) Verify(receipt *nostr.Event) (Receipt, error) {
+	r, err := ParseReceipt(receipt)
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	signer, ok := s[r.Recipient]
+	if !ok || signer == "" {
+		return Receipt{}, ErrUnknownSigner
+	}
+	if signer != r.Signer {
+		return Receipt{}, ErrWrongSigner
+	}
+	return r, nil
+}
+
+// Recipients returns the recipients of the zap receipts among events, without duplicates
+func Recipients(events []*nostr.Event) []string {
+	var recipients []string
+	seen := make(map[string]bool)
+	for _, event := range events {
+		if event.Kind != nostr.KindZap {
+			continue
+		}
+		if recipient := tagValue(event, "p"); recipient != "" && !seen[recipient] {
+			seen[recipient] = true
+			recipients = append(recipients, recipient)
+		}
+	}
+	return recipients
+}
+
+// tagValue returns the value of the first tag with a name
+func tagValue(event *nostr.Event, name string) string {
+	for _, tag := range event.Tags {
+		if len(tag) >= 2 && tag[0] == name {
+			return tag[1]
+		}
+	}
+	return ""
+}
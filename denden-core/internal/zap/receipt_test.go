@@ -0,0 +1,393 @@
+package zap
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/bech32"
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// encodeInvoice builds a BOLT11 invoice with the given tagged fields and a
+// zero signature (DecodeInvoice doesn't check it)
+func encodeInvoice(t *testing.T, hrp string, fields map[byte][]byte) string {
+	t.Helper()
+
+	data := make([]byte, timestampGroups)
+	for tag, value := range fields {
+		groups, err := bech32.ConvertBits(value, 8, 5, true)
+		if err != nil {
+			t.Fatalf("ConvertBits: %v", err)
+		}
+		data = append(data, tag, byte(len(groups)>>5), byte(len(groups)&31))
+		data = append(data, groups...)
+	}
+	data = append(data, make([]byte, signatureGroups)...)
+
+	invoice, err := bech32.Encode(hrp, data)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	return invoice
+}
+
+func TestDecodeInvoice(t *testing.T) {
+	// Examples from the BOLT11 specification
+	coffee := "lnbc2500u1pvjluezpp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqdq5xysxxatsyp3k7enxv4jsxqzpuaztrnwngzn3kdzw5hydlzf03qdgm2hdq27cqv3agm2awhz5se903vruatfhq77w3ls4evs3ch9zw97j25emudupq63nyw24cg27h2rspfj9srp"
+	cake := "lnbc20m1pvjluezpp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqhp58yjmdan79s6qqdhdzgynm4zwqd5d7xmw5fk98klysy043l2ahrqscc6gd6ql3jrc5yzme8v4ntcewwz5cnw92tz0pc8qcuufvq7khhr8wpald05e92xw006sq94mg8v2ndf4sefvf9sygkshp5zfem29trqq2yxxz7"
+
+	inv, err := DecodeInvoice(coffee)
+	if err != nil {
+		t.Fatalf("DecodeInvoice: %v", err)
+	}
+	if inv.MilliSats != 250_000_000 || inv.Description != "1 cup coffee" || inv.DescriptionHash != nil {
+		t.Errorf("coffee invoice = %+v", inv)
+	}
+
+	inv, err = DecodeInvoice(strings.ToUpper(cake))
+	if err != nil {
+		t.Fatalf("DecodeInvoice: %v", err)
+	}
+	wantHash := sha256.Sum256([]byte("One piece of chocolate cake, one icecream cone, one pickle, one slice of swiss cheese, one slice of salami, one lollypop, one piece of cherry pie, one sausage, one cupcake, and one slice of watermelon"))
+	if inv.MilliSats != 2_000_000_000 || fmt.Sprintf("%x", inv.DescriptionHash) != fmt.Sprintf("%x", wantHash) {
+		t.Errorf("cake invoice = %+v", inv)
+	}
+}
+
+func TestDecodeInvoiceAmounts(t *testing.T) {
+	tests := []struct {
+		hrp  string
+		want int64 // 0: invalid
+	}{
+		{"lnbc1", 100_000_000_000},
+		{"lnbc21m", 2_100_000_000},
+		{"lnbc10u", 1_000_000},
+		{"lnbc100n", 10_000},
+		{"lnbc10p", 1},
+		{"lntb5u", 500_000},
+		{"lnbcrt1m", 100_000_000},
+		{"lnbc", 0},                   // No amount
+		{"lnbc15p", 0},                // Half a millisatoshi
+		{"lnbc0u", 0},                 // Zero
+		{"lnbc99999999999999999m", 0}, // Overflow
+		{"lnbc1x", 0},                 // Unknown multiplier
+		{"bc10u", 0},                  // Not lightning
+		{"lnbc1u0", 0},                // Amount after the multiplier
+		{"lnbc+5u", 0},                // Sign
+		{"lnbc" + strings.Repeat("9", 30), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.hrp, func(t *testing.T) {
+			inv, err := DecodeInvoice(encodeInvoice(t, tt.hrp, nil))
+			if tt.want == 0 {
+				if !errors.Is(err, ErrInvalidInvoice) {
+					t.Fatalf("err = %v (amount %d), want ErrInvalidInvoice", err, inv.MilliSats)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeInvoice: %v", err)
+			}
+			if inv.MilliSats != tt.want {
+				t.Errorf("MilliSats = %d, want %d", inv.MilliSats, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeInvoiceInvalid(t *testing.T) {
+	valid := encodeInvoice(t, "lnbc10u", nil)
+
+	tests := []struct {
+		name    string
+		invoice string
+	}{
+		{"empty", ""},
+		{"not bech32", "lightning:lnbc10u"},
+		{"bad checksum", valid[:len(valid)-1] + "q"},
+		{"too short", encodeInvoiceData(t, "lnbc10u", make([]byte, 20))},
+		{"field past the end", encodeInvoiceData(t, "lnbc10u", append(make([]byte, timestampGroups), append([]byte{tagDescHash, 1, 0}, make([]byte, signatureGroups)...)...))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeInvoice(tt.invoice); !errors.Is(err, ErrInvalidInvoice) {
+				t.Errorf("err = %v, want ErrInvalidInvoice", err)
+			}
+		})
+	}
+}
+
+// encodeInvoiceData encodes raw 5-bit groups as an invoice
+func encodeInvoiceData(t *testing.T, hrp string, data []byte) string {
+	t.Helper()
+	invoice, err := bech32.Encode(hrp, data)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	return invoice
+}
+
+// zapFixture is a zap from a sender to a recipient's post, paid through an LNURL server
+type zapFixture struct {
+	senderKey, serverKey    string
+	recipient, server, post string
+}
+
+func newZapFixture() zapFixture {
+	f := zapFixture{
+		senderKey: nostr.GeneratePrivateKey(),
+		serverKey: nostr.GeneratePrivateKey(),
+		post:      strings.Repeat("e", 64),
+	}
+	f.recipient, _ = nostr.GetPublicKey(nostr.GeneratePrivateKey())
+	f.server, _ = nostr.GetPublicKey(f.serverKey)
+	return f
+}
+
+// request returns a signed zap request (Kind 9734), asking for msats if it isn't 0
+func (f zapFixture) request(t *testing.T, msats int64) *nostr.Event {
+	t.Helper()
+
+	request := &nostr.Event{
+		Kind:      nostr.KindZapRequest,
+		CreatedAt: nostr.Now(),
+		Tags:      nostr.Tags{{"relays", "wss://relay.example"}, {"p", f.recipient}, {"e", f.post}},
+		Content:   "great post",
+	}
+	if msats != 0 {
+		request.Tags = append(request.Tags, nostr.Tag{"amount", strconv.FormatInt(msats, 10)})
+	}
+	if err := request.Sign(f.senderKey); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return request
+}
+
+// receipt returns a receipt for a request, with an invoice of hrp committing
+// to description, signed by key
+func (f zapFixture) receipt(t *testing.T, request *nostr.Event, hrp, description, key string) *nostr.Event {
+	t.Helper()
+
+	hash := sha256.Sum256([]byte(description))
+	receipt := &nostr.Event{
+		Kind:      nostr.KindZap,
+		CreatedAt: nostr.Now(),
+		Tags: nostr.Tags{
+			{"p", f.recipient},
+			{"e", f.post},
+			{"P", request.PubKey},
+			{"bolt11", encodeInvoice(t, hrp, map[byte][]byte{tagDescHash: hash[:]})},
+			{"description", request.String()},
+		},
+	}
+	if err := receipt.Sign(key); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return receipt
+}
+
+func TestParseReceipt(t *testing.T) {
+	f := newZapFixture()
+
+	request := f.request(t, 21_000)
+	r, err := ParseReceipt(f.receipt(t, request, "lnbc210n", request.String(), f.serverKey))
+	if err != nil {
+		t.Fatalf("ParseReceipt: %v", err)
+	}
+	want := Receipt{Sender: request.PubKey, Recipient: f.recipient, Target: f.post, Sats: 21, Signer: f.server}
+	if r != want {
+		t.Errorf("receipt = %+v, want %+v", r, want)
+	}
+
+	// Without an amount in the request, the invoice's is used
+	open := f.request(t, 0)
+	r, err = ParseReceipt(f.receipt(t, open, "lnbc5u", open.String(), f.serverKey))
+	if err != nil {
+		t.Fatalf("ParseReceipt: %v", err)
+	}
+	if r.Sats != 500 {
+		t.Errorf("Sats = %d, want 500", r.Sats)
+	}
+}
+
+func TestParseReceiptRejects(t *testing.T) {
+	f := newZapFixture()
+	request := f.request(t, 21_000)
+
+	tests := []struct {
+		name   string
+		modify func(*nostr.Event)
+		want   error
+	}{
+		{
+			"invoice for less than the request",
+			func(r *nostr.Event) { *r = *f.receipt(t, request, "lnbc10n", request.String(), f.serverKey) },
+			ErrAmountMismatch,
+		},
+		{
+			"invoice for another description",
+			func(r *nostr.Event) { *r = *f.receipt(t, request, "lnbc210n", "something else", f.serverKey) },
+			ErrDescriptionMismatch,
+		},
+		{
+			"no invoice",
+			func(r *nostr.Event) { r.Tags = r.Tags[:3] },
+			ErrInvalidInvoice,
+		},
+		{
+			"no zap request",
+			func(r *nostr.Event) { r.Tags[4][1] = "not json" },
+			ErrInvalidRequest,
+		},
+		{
+			"inflated request amount",
+			func(r *nostr.Event) {
+				forged := *request
+				forged.Tags = append(nostr.Tags{}, request.Tags...)
+				forged.Tags[3] = nostr.Tag{"amount", "21000000"}
+				*r = *f.receipt(t, &forged, "lnbc210u", forged.String(), f.serverKey)
+			},
+			ErrInvalidRequest,
+		},
+		{
+			"request for another recipient",
+			func(r *nostr.Event) { r.Tags[0][1] = strings.Repeat("f", 64) },
+			ErrInvalidRequest,
+		},
+		{
+			"request for another post",
+			func(r *nostr.Event) { r.Tags[1][1] = strings.Repeat("f", 64) },
+			ErrInvalidRequest,
+		},
+		{
+			"not a zap request",
+			func(r *nostr.Event) {
+				note := *request
+				note.Kind = nostr.KindTextNote
+				note.Sign(f.senderKey)
+				*r = *f.receipt(t, &note, "lnbc210n", note.String(), f.serverKey)
+			},
+			ErrInvalidRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receipt := f.receipt(t, request, "lnbc210n", request.String(), f.serverKey)
+			tt.modify(receipt)
+			if r, err := ParseReceipt(receipt); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v (receipt %+v), want %v", err, r, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignersVerify(t *testing.T) {
+	f := newZapFixture()
+	request := f.request(t, 21_000)
+	signers := Signers{f.recipient: f.server}
+
+	if _, err := signers.Verify(f.receipt(t, request, "lnbc210n", request.String(), f.serverKey)); err != nil {
+		t.Errorf("receipt from the recipient's server: %v", err)
+	}
+
+	// Anyone can sign a receipt that looks valid
+	forged := f.receipt(t, request, "lnbc210n", request.String(), nostr.GeneratePrivateKey())
+	if _, err := signers.Verify(forged); !errors.Is(err, ErrWrongSigner) {
+		t.Errorf("forged receipt: err = %v, want ErrWrongSigner", err)
+	}
+
+	valid := f.receipt(t, request, "lnbc210n", request.String(), f.serverKey)
+	if _, err := (Signers{}).Verify(valid); !errors.Is(err, ErrUnknownSigner) {
+		t.Errorf("unknown recipient: err = %v, want ErrUnknownSigner", err)
+	}
+	if _, err := Signers(nil).Verify(valid); !errors.Is(err, ErrUnknownSigner) {
+		t.Errorf("nil signers: err = %v, want ErrUnknownSigner", err)
+	}
+
+	if got := Recipients([]*nostr.Event{valid, forged, request}); len(got) != 1 || got[0] != f.recipient {
+		t.Errorf("Recipients = %v, want [%s]", got, f.recipient)
+	}
+}
+
+func TestPayURL(t *testing.T) {
+	encodeLNURL := func(url string) string {
+		data, _ := bech32.ConvertBits([]byte(url), 8, 5, true)
+		lnurl, _ := bech32.Encode("lnurl", data)
+		return strings.ToUpper(lnurl)
+	}
+
+	tests := []struct {
+		name  string
+		lud16 string
+		lud06 string
+		want  string // "": no address
+	}{
+		{"lightning address", "Alice@Example.com", "", "https://example.com/.well-known/lnurlp/alice"},
+		{"lud16 preferred", "alice@example.com", encodeLNURL("https://other.example/lnurlp/bob"), "https://example.com/.well-known/lnurlp/alice"},
+		{"lnurl", "", encodeLNURL("https://other.example/lnurlp/bob"), "https://other.example/lnurlp/bob"},
+		{"bad lud16 falls back to lud06", "alice", encodeLNURL("https://other.example/lnurlp/bob"), "https://other.example/lnurlp/bob"},
+		{"domain with a path", "alice@example.com/evil", "", ""},
+		{"plain http lnurl", "", encodeLNURL("http://other.example/lnurlp/bob"), ""},
+		{"not an lnurl", "", "npub1xyz", ""},
+		{"nothing", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PayURL(tt.lud16, tt.lud06)
+			if tt.want == "" {
+				if !errors.Is(err, ErrNoLightningAddress) {
+					t.Fatalf("PayURL = %q, %v; want ErrNoLightningAddress", got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("PayURL = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchSigner(t *testing.T) {
+	server := strings.Repeat("a", 64)
+	responses := map[string]string{
+		"/zaps":    `{"callback":"https://example.com/cb","allowsNostr":true,"nostrPubkey":"` + server + `"}`,
+		"/no-zaps": `{"callback":"https://example.com/cb","minSendable":1000}`,
+		"/bad-key": `{"allowsNostr":true,"nostrPubkey":"npub1abc"}`,
+		"/garbage": `<html>`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	if got, err := FetchSigner(ctx, ts.URL+"/zaps"); err != nil || got != server {
+		t.Errorf("FetchSigner = %q, %v; want %q", got, err, server)
+	}
+	for _, path := range []string{"/no-zaps", "/bad-key"} {
+		if _, err := FetchSigner(ctx, ts.URL+path); !errors.Is(err, ErrZapsNotSupported) {
+			t.Errorf("%s: err = %v, want ErrZapsNotSupported", path, err)
+		}
+	}
+	for _, path := range []string{"/garbage", "/missing"} {
+		if _, err := FetchSigner(ctx, ts.URL+path); err == nil || errors.Is(err, ErrZapsNotSupported) {
+			t.Errorf("%s: err = %v, want a request error", path, err)
+		}
+	}
+}
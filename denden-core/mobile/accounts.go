@@ -0,0 +1,155 @@
+// Package mobile provides GoMobile-compatible wrappers for the DenDen client.
+// This file contains multi-account management (list, add, import, remove, switch).
+package mobile
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"denden-core/internal/identity"
+)
+
+// accountCache holds the in-memory caches of an account that isn't active
+type accountCache struct {
+	profiles map[string]Profile
+	likes    map[string]reactionSet
+	chats    map[string][]ChatMessage
+}
+
+// AccountInfo is an account as returned to Flutter
+type AccountInfo struct {
+	PublicKey string `json:"publicKey"`
+	Npub      string `json:"npub"`
+	Label     string `json:"label"`
+	Encrypted bool   `json:"encrypted"` // Switching to it needs a passphrase
+	Active    bool   `json:"active"`
+}
+
+// GetAccountsJSON returns the identities registered on this device as a JSON array
+func (d *DenDenClient) GetAccountsJSON() string {
+	active, _ := d.client.ActiveAccount()
+
+	accounts := []AccountInfo{}
+	for _, account := range d.client.ListAccounts() {
+		accounts = append(accounts, AccountInfo{
+			PublicKey: account.PublicKey,
+			Npub:      account.Npub,
+			Label:     account.Label,
+			Encrypted: d.client.IsAccountEncrypted(account.PublicKey),
+			Active:    account.PublicKey == active.PublicKey,
+		})
+	}
+
+	jsonBytes, _ := json.Marshal(accounts)
+	return string(jsonBytes)
+}
+
+// AddAccount generates a new identity and returns its npub (the active account doesn't change)
+// With an empty passphrase the identity file is stored in plaintext
+func (d *DenDenClient) AddAccount(label, passphrase string) (string, error) {
+	account, err := d.client.AddAccount(label, passphrase)
+	if err != nil {
+		return "", err
+	}
+	return account.Npub, nil
+}
+
+// ImportAccount registers an identity from an nsec and returns its npub
+// (the active account doesn't change)
+func (d *DenDenClient) ImportAccount(label, nsec, passphrase string) (string, error) {
+	account, err := d.client.ImportAccount(label, nsec, passphrase)
+	if err != nil {
+		return "", err
+	}
+	return account.Npub, nil
+}
+
+// RemoveAccount deletes an identity from this device (not the active one)
+func (d *DenDenClient) RemoveAccount(pubkey string) error {
+	account, ok := d.findAccount(pubkey)
+	if !ok {
+		return identity.ErrAccountNotFound
+	}
+
+	if err := d.client.RemoveAccount(account.PublicKey); err != nil {
+		return err
+	}
+
+	delete(d.accountCache, account.PublicKey)
+	return nil
+}
+
+// SwitchAccount switches to another registered identity
+// The profile, like and chat caches are swapped for the account's own, and if
+// StartListening was called, the subscription is re-established for the new pubkey
+// Parameters:
+//   - pubkey: public key of the account (hex or npub)
+//   - passphrase: passphrase of the account (ignored if its identity isn't encrypted)
+func (d *DenDenClient) SwitchAccount(pubkey, passphrase string) error {
+	previous := d.client.GetPublicKey()
+
+	if err := d.client.SwitchAccount(pubkey, passphrase); err != nil {
+		return fmt.Errorf("failed to switch account: %w", err)
+	}
+
+	d.swapCaches(previous, d.client.GetPublicKey())
+
+	if d.listenCancel != nil && d.callback != nil {
+		if err := d.StartListening(d.callback); err != nil {
+			return fmt.Errorf("failed to resubscribe: %w", err)
+		}
+	}
+	if err := d.restartHomeTimeline(); err != nil {
+		return fmt.Errorf("failed to resubscribe home timeline: %w", err)
+	}
+	if err := d.notifyFeed.restart(); err != nil {
+		return fmt.Errorf("failed to resubscribe notifications: %w", err)
+	}
+
+	return nil
+}
+
+// findAccount finds a registered account by hex public key or npub
+func (d *DenDenClient) findAccount(pubkey string) (identity.Account, bool) {
+	for _, account := range d.client.ListAccounts() {
+		if account.PublicKey == pubkey || account.Npub == pubkey {
+			return account, true
+		}
+	}
+	return identity.Account{}, false
+}
+
+// swapCaches stashes the caches of the previous account and installs the
+// caches of the next one (warmed from the local event store the first time)
+func (d *DenDenClient) swapCaches(previous, next string) {
+	d.cacheMutex.Lock()
+	d.likeMutex.Lock()
+	d.chatMutex.Lock()
+
+	d.accountCache[previous] = &accountCache{
+		profiles: d.profileCache,
+		likes:    d.likeCache,
+		chats:    d.chatCache,
+	}
+
+	cached, ok := d.accountCache[next]
+	if ok {
+		delete(d.accountCache, next)
+		d.profileCache = cached.profiles
+		d.likeCache = cached.likes
+		d.chatCache = cached.chats
+	} else {
+		d.profileCache = make(map[string]Profile)
+		d.likeCache = make(map[string]reactionSet)
+		d.chatCache = make(map[string][]ChatMessage)
+	}
+
+	d.chatMutex.Unlock()
+	d.likeMutex.Unlock()
+	d.cacheMutex.Unlock()
+
+	if !ok {
+		d.loadFromStore()
+		go d.syncReactions()
+	}
+}
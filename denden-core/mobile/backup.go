@@ -0,0 +1,75 @@
+// Package mobile provides GoMobile-compatible wrappers for the DenDen client.
+// This file contains mnemonic backup and restore (NIP-06).
+package mobile
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"denden-core/internal/identity"
+)
+
+// GenerateMnemonic generates a new mnemonic of 12 or 24 words
+func GenerateMnemonic(wordCount int) (string, error) {
+	return identity.GenerateMnemonic(wordCount)
+}
+
+// ValidateMnemonic reports whether the words are a valid mnemonic (checksum included)
+// Use it to check the user's input before RestoreIdentity
+func ValidateMnemonic(mnemonic string) bool {
+	return identity.ValidateMnemonic(mnemonic)
+}
+
+// RestoreIdentity restores an identity from a mnemonic into storageDir, before any client exists
+// Any identity already in storageDir is overwritten. Create the client afterwards with
+// NewDenDenClient (or NewDenDenClientWithPassphrase if a passphrase was given)
+func RestoreIdentity(storageDir, mnemonic string, account int, passphrase string) error {
+	if account < 0 {
+		return fmt.Errorf("invalid account index %d", account)
+	}
+
+	_, err := identity.RestoreIdentity(filepath.Join(storageDir, "identity.json"), mnemonic, uint32(account), passphrase)
+	return err
+}
+
+// BackupMnemonic returns the mnemonic to show the user for writing down
+// If the identity is encrypted, the passphrase must be the one that unlocks it
+func (d *DenDenClient) BackupMnemonic(passphrase string) (string, error) {
+	return d.client.BackupMnemonic(passphrase)
+}
+
+// HasMnemonic reports whether the identity can be backed up as words
+// (identities imported from a raw key can only be exported with ExportSecretKey)
+func (d *DenDenClient) HasMnemonic() bool {
+	return !d.client.IsUsingBunker() && d.client.GetIdentity().HasMnemonic()
+}
+
+// RestoreFromMnemonic replaces the current identity with the one derived from a mnemonic
+// The caches of the old identity are dropped, and if StartListening was called,
+// the subscription is re-established for the restored pubkey
+func (d *DenDenClient) RestoreFromMnemonic(mnemonic string, account int, passphrase string) error {
+	if account < 0 {
+		return fmt.Errorf("invalid account index %d", account)
+	}
+
+	previous := d.client.GetPublicKey()
+	if err := d.client.RestoreFromMnemonic(mnemonic, uint32(account), passphrase); err != nil {
+		return err
+	}
+
+	d.swapCaches(previous, d.client.GetPublicKey())
+	delete(d.accountCache, previous)
+
+	if d.listenCancel != nil && d.callback != nil {
+		if err := d.StartListening(d.callback); err != nil {
+			return fmt.Errorf("failed to resubscribe: %w", err)
+		}
+	}
+	if err := d.restartHomeTimeline(); err != nil {
+		return fmt.Errorf("failed to resubscribe home timeline: %w", err)
+	}
+	if err := d.notifyFeed.restart(); err != nil {
+		return fmt.Errorf("failed to resubscribe notifications: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// Package mobile provides GoMobile-compatible wrappers for the DenDen client.
+// This file contains NIP-46 remote signer (bunker) support.
+package mobile
+
+import (
+	"fmt"
+)
+
+// Default relay for nostrconnect:// pairing (supported by most signer apps)
+var defaultNostrConnectRelays = []string{"wss://relay.nsec.app"}
+
+// ConnectBunker signs with a remote signer app from a bunker:// URI (NIP-46)
+// Blocks until the user approves the connection in the signer app (up to 2 minutes)
+// The session is saved and restored on the next start
+func (d *DenDenClient) ConnectBunker(bunkerURI string) error {
+	return d.client.ConnectBunker(bunkerURI, d.onBunkerAuth)
+}
+
+// StartNostrConnect starts pairing with a signer app and returns the nostrconnect:// URI
+// Show it as a QR code or deep link, then call WaitNostrConnect
+func (d *DenDenClient) StartNostrConnect() (string, error) {
+	nc, err := d.client.StartNostrConnect(defaultNostrConnectRelays, "Den Den")
+	if err != nil {
+		return "", err
+	}
+
+	d.nostrConnect = nc
+	return nc.URI(), nil
+}
+
+// WaitNostrConnect blocks until the signer app accepts the pairing (up to 2 minutes)
+func (d *DenDenClient) WaitNostrConnect() error {
+	nc := d.nostrConnect
+	if nc == nil {
+		return fmt.Errorf("no pairing in progress, call StartNostrConnect first")
+	}
+	d.nostrConnect = nil
+
+	return d.client.CompleteNostrConnect(nc, d.onBunkerAuth)
+}
+
+// DisconnectBunker forgets the remote signer and signs with the local key again
+func (d *DenDenClient) DisconnectBunker() error {
+	return d.client.DisconnectBunker()
+}
+
+// IsUsingBunker reports whether signing goes through a remote signer
+func (d *DenDenClient) IsUsingBunker() bool {
+	return d.client.IsUsingBunker()
+}
+
+// onBunkerAuth asks Flutter to open the signer's approval page
+// Message format: {"type":"bunker_auth","url":"https://..."}
+func (d *DenDenClient) onBunkerAuth(authURL string) {
+	if d.callback == nil {
+		return
+	}
+
+	d.callback.OnMessage(fmt.Sprintf(`{"type":"bunker_auth","url":"%s"}`, escapeJSON(authURL)))
+}
@@ -3,11 +3,16 @@
 package mobile
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"path/filepath"
 	"sync"
 
 	"denden-core/internal/client"
+	"denden-core/internal/identity"
+	"denden-core/internal/relay"
+	"denden-core/internal/signer"
 )
 
 // StringCallback is the interface that mobile platforms must implement
@@ -23,22 +28,34 @@ type Profile struct {
 	About   string `json:"about"`
 	Banner  string `json:"banner,omitempty"`
 	Website string `json:"website,omitempty"`
+	Lud16   string `json:"lud16,omitempty"` // Lightning address, where zaps are paid (LUD-16)
+	Lud06   string `json:"lud06,omitempty"` // Bech32 LNURL, used when there's no lud16 (LUD-06)
 }
 
 // DenDenClient is the mobile-friendly wrapper for the Den Den client
 // This struct will be exposed to mobile platforms via gomobile
 type DenDenClient struct {
-	client       *client.Client
-	callback     StringCallback
-	stopChan     chan struct{}
-	seedRelays   []string                 // Seed relay pool for Ocean feature
-	connectedTo  string                   // Currently connected relay
-	profileCache map[string]Profile       // In-memory cache for user profiles (pubkey -> Profile)
-	cacheMutex   sync.RWMutex             // Mutex for thread-safe cache access
-	likeCache    map[string]string        // In-memory cache for likes (postId -> likeEventId)
-	likeMutex    sync.RWMutex             // Mutex for thread-safe like cache access
-	chatCache    map[string][]ChatMessage // In-memory cache for chats (pubkey -> messages)
-	chatMutex    sync.RWMutex
+	client        *client.Client
+	callback      StringCallback
+	stopChan      chan struct{}
+	seedRelays    []string                 // Seed relay pool for Ocean feature
+	profileCache  map[string]Profile       // In-memory cache for user profiles (pubkey -> Profile)
+	cacheMutex    sync.RWMutex             // Mutex for thread-safe cache access
+	likeCache     map[string]reactionSet   // In-memory cache for the user's reactions (postId -> content -> reaction event ID)
+	likeMutex     sync.RWMutex             // Mutex for thread-safe like cache access
+	chatCache     map[string][]ChatMessage // In-memory cache for chats (pubkey -> messages)
+	chatMutex     sync.RWMutex
+	nostrConnect  *signer.NostrConnect       // Pending nostrconnect:// pairing (nil if none)
+	listenCancel  context.CancelFunc         // Stops the StartListening subscription (nil if not listening)
+	accountCache  map[string]*accountCache   // Caches of the accounts switched away from (account pubkey -> caches)
+	miningCancels map[int]context.CancelFunc // Cancels the PoW runs in progress (mining id -> cancel)
+	miningID      int                        // Last mining id handed out
+	miningMutex   sync.Mutex
+	homeFeed      liveFeed             // StartHomeTimeline stream
+	nearbyFeed    liveFeed             // StartNearbyFeed stream
+	notifyFeed    liveFeed             // StartNotifications stream
+	zapSigners    map[string]zapSigner // LNURL server key per zap recipient (pubkey -> key)
+	zapMutex      sync.Mutex
 }
 
 // ChatMessage represents a decrypted message
@@ -48,6 +65,7 @@ type ChatMessage struct {
 	Content   string `json:"content"`
 	CreatedAt int64  `json:"created_at"`
 	IsMine    bool   `json:"is_mine"`
+	Scheme    string `json:"scheme"` // Encryption scheme: "nip04", "nip44" or "nip17"
 }
 
 // Default seed relays for Ocean (public timeline)
@@ -58,76 +76,190 @@ var defaultSeedRelays = []string{
 }
 
 // NewDenDenClient creates a new Den Den client for mobile use
+// Fails if the identity is encrypted (see IsIdentityEncrypted and NewDenDenClientWithPassphrase)
 func NewDenDenClient(storageDir string) (*DenDenClient, error) {
-	// Initialize core client with SQLite storage
+	return newDenDenClient(storageDir, "")
+}
+
+// NewDenDenClientWithPassphrase creates a client whose identity is encrypted with a passphrase (NIP-49)
+// A new identity is saved encrypted, and a plaintext one is migrated
+func NewDenDenClientWithPassphrase(storageDir, passphrase string) (*DenDenClient, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase can't be empty")
+	}
+	return newDenDenClient(storageDir, passphrase)
+}
+
+// IsIdentityEncrypted reports whether the identity in storageDir needs a passphrase to unlock
+// (the active account's identity if several are registered)
+func IsIdentityEncrypted(storageDir string) bool {
+	return identity.IsEncryptedFile(identity.ResolveIdentityPath(filepath.Join(storageDir, "identity.json")))
+}
+
+// newDenDenClient creates the client, unlocking the identity if a passphrase is given
+func newDenDenClient(storageDir, passphrase string) (*DenDenClient, error) {
+	identityPath := filepath.Join(storageDir, "identity.json")
 	dbPath := filepath.Join(storageDir, "denden.db")
-	c, err := client.NewClient(dbPath)
+
+	// Older versions stored the identity JSON at denden.db, move it out of the way
+	if err := migrateLegacyIdentity(dbPath, identityPath); err != nil {
+		return nil, err
+	}
+
+	// Initialize core client
+	var c *client.Client
+	var err error
+	if passphrase != "" {
+		c, err = client.NewClientWithPassphrase(identityPath, passphrase)
+	} else {
+		c, err = client.NewClient(identityPath)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create client: %w", err)
 	}
 
-	return &DenDenClient{
-		client:       c,
-		stopChan:     make(chan struct{}),
-		seedRelays:   []string{"wss://relay.damus.io", "wss://nos.lol", "wss://relay.primal.net"}, // Default pool
-		profileCache: make(map[string]Profile),
-		likeCache:    make(map[string]string),
-		chatCache:    make(map[string][]ChatMessage),
-	}, nil
+	// Open the SQLite event store; the app still works (online only) without it
+	if err := c.OpenStore(dbPath); err != nil {
+		fmt.Printf("GO: Event store unavailable: %v\n", err)
+	}
+
+	d := &DenDenClient{
+		client:        c,
+		stopChan:      make(chan struct{}),
+		seedRelays:    []string{"wss://relay.damus.io", "wss://nos.lol", "wss://relay.primal.net"}, // Default pool
+		profileCache:  make(map[string]Profile),
+		likeCache:     make(map[string]reactionSet),
+		chatCache:     make(map[string][]ChatMessage),
+		accountCache:  make(map[string]*accountCache),
+		miningCancels: make(map[int]context.CancelFunc),
+		zapSigners:    make(map[string]zapSigner),
+	}
+
+	// Forward relay connection state changes (connecting/connected/lost) to Flutter
+	c.SetRelayStateHandler(d.onRelayState)
+
+	// Show cached profiles and chats immediately, before any relay answers
+	d.loadFromStore()
+
+	return d, nil
 }
 
-// Connect connects to a specific Nostr relay
+// Connect connects to a specific Nostr relay and adds it to the relay pool
 func (d *DenDenClient) Connect(relayURL string) error {
 	err := d.client.Connect(relayURL)
 	if err != nil {
 		return fmt.Errorf("connection failed: %w", err)
 	}
-	d.connectedTo = relayURL
+
+	// Pick up likes and reactions made on other devices
+	go d.syncReactions()
 	return nil
 }
 
-// ConnectToDefault attempts to connect to one of the default seed relays
+// ConnectToDefault connects to every default seed relay
+// Succeeds as long as at least one of them is reachable
 func (d *DenDenClient) ConnectToDefault() error {
-	var lastErr error
-
-	for _, relayURL := range d.seedRelays {
-		err := d.Connect(relayURL)
-		if err == nil {
-			return nil
-		}
-		lastErr = err
+	if len(d.seedRelays) == 0 {
+		return fmt.Errorf("no seed relays available")
+	}
+
+	err := d.client.ConnectAll(d.seedRelays)
+	if err != nil {
+		return fmt.Errorf("failed to connect to any seed relay: %w", err)
 	}
 
-	if lastErr != nil {
-		return fmt.Errorf("failed to connect to any seed relay: %w", lastErr)
+	// Pick up likes and reactions made on other devices
+	go d.syncReactions()
+	return nil
+}
+
+// onRelayState notifies the mobile callback about relay connection state changes
+// Message format: {"type":"connection","relay":"wss://...","state":"lost"}
+func (d *DenDenClient) onRelayState(relayURL string, state relay.ConnectionState) {
+	if d.callback == nil {
+		return
 	}
-	return fmt.Errorf("no seed relays available")
+
+	msg := fmt.Sprintf(
+		`{"type":"connection","relay":"%s","state":"%s"}`,
+		escapeJSON(relayURL),
+		state,
+	)
+	d.callback.OnMessage(msg)
 }
 
-// Send sends an encrypted message to a recipient
+// Send is kept for backward compatibility, but SendDirectMessage is preferred
+// It used to send a Kind 4 message, which shows relays who talks to whom and
+// when; it now sends the same NIP-17 gift wrap as SendDirectMessage
+// Deprecated: Use SendDirectMessage instead
 func (d *DenDenClient) Send(recipientPubKey, content string) error {
-	err := d.client.SendEncryptedMessage(recipientPubKey, content)
-	if err != nil {
+	if err := d.SendDirectMessage(recipientPubKey, content); err != nil {
 		return fmt.Errorf("send failed: %w", err)
 	}
 	return nil
 }
 
-// GetIdentityJSON returns the user's identity as JSON string
+// GetIdentityJSON returns the user's public identity as JSON string
+// Secrets are never included, use BackupMnemonic or ExportSecretKey to back up the key
 func (d *DenDenClient) GetIdentityJSON() string {
 	ident := d.client.GetIdentity()
 	return fmt.Sprintf(
-		`{"npub":"%s","nsec":"%s","publicKey":"%s","privateKey":"%s"}`,
+		`{"npub":"%s","publicKey":"%s","encrypted":%t,"remoteSigner":%t,"hasMnemonic":%t,"account":%d}`,
 		ident.Npub,
-		ident.Nsec,
 		ident.PublicKey,
-		ident.PrivateKey,
+		ident.IsEncrypted(),
+		d.client.IsUsingBunker(),
+		d.HasMnemonic(),
+		ident.Account,
 	)
 }
 
-// GetConnectedRelay returns the currently connected relay URL
+// ExportSecretKey returns the private key encrypted with a passphrase (NIP-49 ncryptsec1...)
+// If the identity file is encrypted, the passphrase must be the one that unlocks it
+func (d *DenDenClient) ExportSecretKey(passphrase string) (string, error) {
+	return d.client.ExportSecretKey(passphrase)
+}
+
+// LockIdentity encrypts the plaintext identity file with a passphrase (NIP-49)
+// From the next start on, the client must be created with NewDenDenClientWithPassphrase
+func (d *DenDenClient) LockIdentity(passphrase string) error {
+	return d.client.LockIdentity(passphrase)
+}
+
+// ChangePassphrase re-encrypts the identity file with a new passphrase
+func (d *DenDenClient) ChangePassphrase(oldPassphrase, newPassphrase string) error {
+	return d.client.ChangePassphrase(oldPassphrase, newPassphrase)
+}
+
+// GetConnectedRelay returns the URL of the first connected relay
+// Kept for compatibility, use GetConnectedRelays for the whole pool
 func (d *DenDenClient) GetConnectedRelay() string {
-	return d.connectedTo
+	return d.relayHint()
+}
+
+// GetConnectedRelays returns the URLs of all connected relays as a JSON array
+func (d *DenDenClient) GetConnectedRelays() string {
+	pool := d.client.GetPool()
+	if pool == nil {
+		return "[]"
+	}
+
+	jsonBytes, _ := json.Marshal(pool.URLs())
+	return string(jsonBytes)
+}
+
+// relayHint returns a relay URL to put in e/q tag hints (empty if not connected)
+func (d *DenDenClient) relayHint() string {
+	pool := d.client.GetPool()
+	if pool == nil {
+		return ""
+	}
+
+	urls := pool.URLs()
+	if len(urls) == 0 {
+		return ""
+	}
+	return urls[0]
 }
 
 // Close closes the client and cleans up resources
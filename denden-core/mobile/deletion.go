@@ -0,0 +1,230 @@
+// Package mobile provides GoMobile-compatible wrappers for the DenDen client.
+// This file contains deletion (NIP-09): deleting the user's events and hiding deleted ones.
+package mobile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"denden-core/internal/store"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// DeleteEvents asks relays to delete some of the user's events (Kind 5, NIP-09)
+// Works for notes, reposts, reactions and addressable events such as long-form
+// articles. The events are removed from the local store right away and
+// tombstoned, so they stay hidden even if a relay keeps serving them
+// Parameters:
+//   - refsJson: JSON array of event IDs and/or "kind:pubkey:d" addresses (e.g. "30023:<pubkey>:my-article")
+//   - reason: optional reason shown by clients ("" for none)
+//
+// Returns: the deletion event ID
+func (d *DenDenClient) DeleteEvents(refsJson string, reason string) (string, error) {
+	var refs []string
+	if err := json.Unmarshal([]byte(refsJson), &refs); err != nil {
+		return "", fmt.Errorf("invalid refs json: %w", err)
+	}
+
+	id, err := d.deleteEvents(refs, reason)
+	if err != nil {
+		return "", err
+	}
+
+	// Drop deleted reactions from the like state
+	d.loadReactions()
+	return id, nil
+}
+
+// deleteEvents publishes a deletion for event IDs and addresses
+// The kinds of the deleted events go in "k" tags (looked up in the local store
+// for IDs); events known to belong to someone else are refused
+func (d *DenDenClient) deleteEvents(refs []string, reason string) (string, error) {
+	if d.client.GetPool() == nil {
+		return "", fmt.Errorf("not connected to relay")
+	}
+	if len(refs) == 0 {
+		return "", fmt.Errorf("nothing to delete")
+	}
+
+	me := d.client.GetPublicKey()
+	kinds := make(map[int]bool)
+	var ids []string
+	ev := nostr.Event{
+		Kind:    nostr.KindDeletion,
+		Content: reason,
+	}
+
+	for _, ref := range refs {
+		if kind, pubkey, ok := splitAddress(ref); ok {
+			if pubkey != me {
+				return "", fmt.Errorf("can't delete %s: not your event", ref)
+			}
+			kinds[kind] = true
+			ev.Tags = append(ev.Tags, nostr.Tag{"a", ref})
+			continue
+		}
+		if !nostr.IsValid32ByteHex(ref) {
+			return "", fmt.Errorf("invalid event id or address: %s", ref)
+		}
+		ids = append(ids, ref)
+		ev.Tags = append(ev.Tags, nostr.Tag{"e", ref})
+	}
+
+	if len(ids) > 0 {
+		known, _ := d.client.QueryLocal(nostr.Filter{IDs: ids})
+		for _, evt := range known {
+			if evt.PubKey != me {
+				return "", fmt.Errorf("can't delete %s: not your event", evt.ID)
+			}
+			kinds[evt.Kind] = true
+		}
+	}
+
+	sortedKinds := make([]int, 0, len(kinds))
+	for kind := range kinds {
+		sortedKinds = append(sortedKinds, kind)
+	}
+	sort.Ints(sortedKinds)
+	for _, kind := range sortedKinds {
+		ev.Tags = append(ev.Tags, nostr.Tag{"k", strconv.Itoa(kind)})
+	}
+
+	if err := d.publishEvent(&ev); err != nil {
+		return "", fmt.Errorf("failed to publish deletion: %w", err)
+	}
+	return ev.ID, nil
+}
+
+// splitAddress reads the kind and author of a "kind:pubkey:d" address
+func splitAddress(ref string) (int, string, bool) {
+	parts := strings.SplitN(ref, ":", 3)
+	if len(parts) != 3 {
+		return 0, "", false
+	}
+	kind, err := strconv.Atoi(parts[0])
+	if err != nil || !nostr.IsValid32ByteHex(parts[1]) {
+		return 0, "", false
+	}
+	return kind, parts[1], true
+}
+
+// deletionMessage is what streams send when events they already delivered are
+// deleted (Kind 5), so the app can remove them:
+// {"kind":5,"sender","eventId","time","deleted":[event IDs],"addresses":["kind:pubkey:d"]}
+// An address covers the versions created up to the deletion's time
+func deletionMessage(deletion *nostr.Event) map[string]interface{} {
+	deleted, addresses := []string{}, []string{}
+	for _, tag := range deletion.Tags {
+		if len(tag) < 2 {
+			continue
+		}
+		switch tag[0] {
+		case "e":
+			deleted = append(deleted, tag[1])
+		case "a":
+			addresses = append(addresses, tag[1])
+		}
+	}
+
+	return map[string]interface{}{
+		"kind":      nostr.KindDeletion,
+		"sender":    deletion.PubKey,
+		"eventId":   deletion.ID,
+		"time":      deletion.CreatedAt.Time().Format(time.RFC3339),
+		"deleted":   deleted,
+		"addresses": addresses,
+	}
+}
+
+// deletionLookups remembers which events were already looked up for
+// deletions, so that events coming back in several rounds (pagination,
+// ancestor walks) cost a single relay query
+type deletionLookups struct {
+	checked   map[string]bool // Event IDs and addresses already looked up
+	deletions []*nostr.Event  // Deletions found so far
+}
+
+// newDeletionLookups creates an empty lookup cache
+func newDeletionLookups() *deletionLookups {
+	return &deletionLookups{checked: make(map[string]bool)}
+}
+
+// hideDeleted looks up deletions (Kind 5) of events from the relays and
+// returns the events their authors didn't delete: by ID ("e" tags) or, for
+// replaceable and addressable events, by address ("a" tags), by the rules of
+// the local store. The deletions are saved to the local store, which
+// tombstones the events for later queries; refs are queried in chunks of
+// homeAuthorsPerFilter
+// Parameters:
+//   - lookups: refs already looked up are skipped (nil to look up every event)
+func (d *DenDenClient) hideDeleted(ctx context.Context, events []*nostr.Event, lookups *deletionLookups) []*nostr.Event {
+	if len(events) == 0 || d.client.GetPool() == nil {
+		return events
+	}
+	if lookups == nil {
+		lookups = newDeletionLookups()
+	}
+
+	var ids, addresses []string
+	for _, evt := range events {
+		if !lookups.checked[evt.ID] {
+			lookups.checked[evt.ID] = true
+			ids = append(ids, evt.ID)
+		}
+		if address := store.EventAddress(evt); address != "" && !lookups.checked[address] {
+			lookups.checked[address] = true
+			addresses = append(addresses, address)
+		}
+	}
+
+	var filters []nostr.Filter
+	for _, refs := range []struct {
+		tag    string
+		values []string
+	}{{"e", ids}, {"a", addresses}} {
+		for start := 0; start < len(refs.values); start += homeAuthorsPerFilter {
+			filters = append(filters, nostr.Filter{
+				Kinds: []int{nostr.KindDeletion},
+				Tags:  nostr.TagMap{refs.tag: refs.values[start:min(start+homeAuthorsPerFilter, len(refs.values))]},
+			})
+		}
+	}
+
+	if len(filters) > 0 {
+		deletions, err := d.queryAll(ctx, filters, 0)
+		if err != nil {
+			// Look them up again next time
+			for _, ref := range append(ids, addresses...) {
+				delete(lookups.checked, ref)
+			}
+		}
+		lookups.deletions = append(lookups.deletions, deletions...)
+	}
+	if len(lookups.deletions) == 0 {
+		return events
+	}
+
+	kept := make([]*nostr.Event, 0, len(events))
+	for _, evt := range events {
+		if !isDeletedBy(evt, lookups.deletions) {
+			kept = append(kept, evt)
+		}
+	}
+	return kept
+}
+
+// isDeletedBy reports whether any of the deletions removes an event
+func isDeletedBy(evt *nostr.Event, deletions []*nostr.Event) bool {
+	for _, deletion := range deletions {
+		if store.Deletes(deletion, evt) {
+			return true
+		}
+	}
+	return false
+}
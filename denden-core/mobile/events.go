@@ -8,15 +8,15 @@ import (
 	"fmt"
 	"time"
 
-	"denden-core/internal/crypto"
-
 	"github.com/nbd-wtf/go-nostr"
 )
 
 // StartListening starts listening for incoming messages
-// Listens to both Kind 0 (Metadata) and Kind 1 (Text Notes)
+// Listens to public notes and reposts from everyone (the Ocean feed), plus
+// direct messages addressed to us. When one of them is deleted later, a Kind 5
+// message lists it (see deletionMessage). See StartHomeTimeline for followed accounts only
 func (d *DenDenClient) StartListening(callback StringCallback) error {
-	if d.client.GetRelay() == nil {
+	if d.client.GetPool() == nil {
 		return fmt.Errorf("not connected to relay")
 	}
 
@@ -28,33 +28,49 @@ func (d *DenDenClient) StartListening(callback StringCallback) error {
 			Kinds: []int{1, 6}, // Kind 1 = Text Note, Kind 6 = Repost
 			Limit: 20,
 		},
+		{
+			Kinds: []int{4, 1059}, // Direct messages addressed to us (Kind 4 and NIP-17 gift wraps)
+			Tags:  nostr.TagMap{"p": []string{d.client.GetPublicKey()}},
+			Limit: 20,
+		},
 	}
 
-	// Subscribe to events
-	ctx := context.Background()
-	eventChan, err := d.client.GetRelay().Subscribe(ctx, filters)
+	// Subscribe to events (canceled when switching accounts)
+	ctx, cancel := context.WithCancel(context.Background())
+	eventChan, err := d.client.Subscribe(ctx, filters)
 	if err != nil {
+		cancel()
 		return fmt.Errorf("subscription failed: %w", err)
 	}
 
+	if d.listenCancel != nil {
+		d.listenCancel()
+	}
+	d.listenCancel = cancel
+
 	// Start background goroutine to consume events and call callback
-	go d.handleIncomingEvents(eventChan)
+	go d.handleIncomingEvents(ctx, eventChan)
 
 	return nil
 }
 
 // handleIncomingEvents processes incoming events and calls the mobile callback
-func (d *DenDenClient) handleIncomingEvents(eventChan chan *nostr.Event) {
+func (d *DenDenClient) handleIncomingEvents(ctx context.Context, eventChan chan *nostr.Event) {
 	for {
 		select {
 		case <-d.stopChan:
 			return
 
+		case <-ctx.Done():
+			return
+
 		case <-d.client.GetContext().Done():
 			return
 
 		case event, ok := <-eventChan:
 			if !ok {
+				// Subscription ended for good; dropped connections are
+				// re-established and replayed by the relay layer
 				return
 			}
 
@@ -93,12 +109,8 @@ func (d *DenDenClient) processEvent(event *nostr.Event) {
 		}
 
 	case 4:
-		// Kind 4: Encrypted Direct Message
-		decrypted, err := crypto.Decrypt(
-			event.Content,
-			d.client.GetIdentity().PrivateKey,
-			event.PubKey,
-		)
+		// Kind 4: Encrypted Direct Message (NIP-04 or NIP-44)
+		dm, err := d.client.DecryptDirectMessage(event)
 		if err != nil {
 			errorJSON := fmt.Sprintf(
 				`{"kind":4,"error":"Failed to decrypt message","sender":"%s"}`,
@@ -110,22 +122,71 @@ func (d *DenDenClient) processEvent(event *nostr.Event) {
 			return
 		}
 
+		// Keep the chat cache in sync with live messages
+		d.chatMutex.Lock()
+		d.cacheDirectMessage(dm)
+		d.sortChatCache()
+		d.chatMutex.Unlock()
+
 		profile := d.getProfileFromCache(event.PubKey)
 
 		messageJSON := fmt.Sprintf(
-			`{"kind":4,"sender":"%s","content":"%s","time":"%s","eventId":"%s","authorName":"%s","avatarUrl":"%s"}`,
+			`{"kind":4,"sender":"%s","content":"%s","time":"%s","eventId":"%s","authorName":"%s","avatarUrl":"%s","scheme":"%s"}`,
 			event.PubKey,
-			escapeJSON(decrypted),
+			escapeJSON(dm.Content),
 			event.CreatedAt.Time().Format(time.RFC3339),
 			event.ID,
 			escapeJSON(profile.Name),
 			escapeJSON(profile.Picture),
+			dm.Scheme,
+		)
+
+		if d.callback != nil {
+			d.callback.OnMessage(messageJSON)
+		}
+
+	case 1059:
+		// Kind 1059: NIP-17 private message inside a gift wrap
+		// The wrap is signed by a throwaway key, so sender and time come from the rumor
+		dm, err := d.client.UnwrapPrivateMessage(event)
+		if err != nil {
+			return // Not for us, or not a chat message
+		}
+
+		d.chatMutex.Lock()
+		added := d.cacheDirectMessage(dm)
+		d.sortChatCache()
+		d.chatMutex.Unlock()
+
+		// Our own copies only need to be cached, and each message may arrive from several relays
+		if dm.IsMine || !added {
+			return
+		}
+
+		profile := d.getProfileFromCache(dm.Sender)
+
+		messageJSON := fmt.Sprintf(
+			`{"kind":14,"sender":"%s","content":"%s","time":"%s","eventId":"%s","authorName":"%s","avatarUrl":"%s","scheme":"%s"}`,
+			dm.Sender,
+			escapeJSON(dm.Content),
+			dm.CreatedAt.Time().Format(time.RFC3339),
+			dm.ID,
+			escapeJSON(profile.Name),
+			escapeJSON(profile.Picture),
+			dm.Scheme,
 		)
 
 		if d.callback != nil {
 			d.callback.OnMessage(messageJSON)
 		}
 
+	case nostr.KindDeletion:
+		// Kind 5: a note, repost or message sent earlier was deleted by its author
+		jsonBytes, err := json.Marshal(deletionMessage(event))
+		if err == nil && d.callback != nil {
+			d.callback.OnMessage(string(jsonBytes))
+		}
+
 	case 6:
 		// Kind 6: Repost
 		profile := d.getProfileFromCache(event.PubKey)
@@ -7,202 +7,167 @@ import (
 	"sort"
 	"time"
 
+	"denden-core/internal/client"
+
 	"github.com/nbd-wtf/go-nostr"
-	"github.com/nbd-wtf/go-nostr/nip04"
 )
 
-// GetUserFeed returns a list of posts (Kind 1) and reposts (Kind 6) authored by the given pubkey.
+// GetUserFeed returns the newest posts (Kind 1) and reposts (Kind 6) authored by the given pubkey.
 // limit: maximum number of events to return.
+// Use GetUserFeedPage to load older events.
 func (d *DenDenClient) GetUserFeed(pubkey string, limit int) (string, error) {
-	if d.client.GetRelay() == nil {
-		return "", fmt.Errorf("not connected to relay")
-	}
-
-	filter := nostr.Filter{
-		Kinds:   []int{1, 6},
-		Authors: []string{pubkey},
-		Limit:   limit,
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	events, err := d.client.GetRelay().QuerySync(ctx, filter)
+	events, _, err := d.fetchPage([]nostr.Filter{userFilter(pubkey, 1, 6)}, "", limit, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to query feed: %w", err)
 	}
+	return d.eventsToEnrichedJson(events)
+}
 
-	// Process events to enrich them (handle reposts, fetch profiles/likes if needed)
-	// We reuse the existing processEvents logic from events.go if possible,
-	// but since processEvent is for single event, we can iterate.
-	// However, processEvent in events.go might return a JSON string.
-	// It's better to construct our own list of enriched objects.
-
-	// For now, let's use a simplified approach similar to GetPostThread's structure or just a raw list.
-	// Since the UI expects a list of NostrPost objects (JSON), we should return a JSON array.
-
-	var resultEvents []map[string]interface{}
-
-	for _, evt := range events {
-		// Basic enrichment
-		enriched := map[string]interface{}{
-			"kind":    evt.Kind,
-			"sender":  evt.PubKey,
-			"content": evt.Content,
-			"time":    evt.CreatedAt.Time().Format(time.RFC3339),
-			"eventId": evt.ID,
-			"tags":    evt.Tags,
-		}
-
-		// Attach profile if cached
-		d.cacheMutex.RLock()
-		if profile, ok := d.profileCache[evt.PubKey]; ok {
-			enriched["authorName"] = profile.Name
-			enriched["avatarUrl"] = profile.Picture
-		}
-		d.cacheMutex.RUnlock()
-
-		// Handle Kind 6 Repost specific fields
-		if evt.Kind == 6 {
-			// Parse the inner event from content (NIP-18)
-			var innerEvent nostr.Event
-			if err := json.Unmarshal([]byte(evt.Content), &innerEvent); err == nil {
-				// We don't need to recursively enrich the inner event fully for the list view right now,
-				// but the flutter side needs it to display the original post.
-				// The flutter NostrPost.fromJson handles parsing the inner JSON if it's a repost.
-				// So passing the raw content (which IS the inner JSON) is correct.
-				enriched["repostBy"] = evt.PubKey
-			}
-		}
-
-		resultEvents = append(resultEvents, enriched)
+// GetUserFeedPage returns a page of posts (Kind 1) and reposts (Kind 6) authored by the given pubkey,
+// newest first: {"events":[...],"nextCursor":"..."}
+// cursor: nextCursor of the previous page ("" for the first page); nextCursor is "" on the last page.
+func (d *DenDenClient) GetUserFeedPage(pubkey string, limit int, cursor string) (string, error) {
+	events, next, err := d.fetchPage([]nostr.Filter{userFilter(pubkey, 1, 6)}, cursor, limit, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to query feed: %w", err)
 	}
+	return d.pageToJson(events, next)
+}
 
-	// Sort by CreatedAt desc
-	// events from QuerySync might not be sorted.
-	// (Simple bubble sort or just relying on relay order? Relays usually sort desc.
-	// Let's assume relay returns desc for now, or we can sort if needed.
-	// QuerySync usually returns somewhat sorted but not guaranteed across multiple relays,
-	// but here we are probably connected to one pool/relay logic.)
-
-	jsonBytes, err := json.Marshal(resultEvents)
+// GetCachedUserFeedPage is GetUserFeedPage from the local event store only
+// It returns at once, without waiting for relays; the cursors of both work with either
+func (d *DenDenClient) GetCachedUserFeedPage(pubkey string, limit int, cursor string) (string, error) {
+	events, next, err := d.fetchLocalPage([]nostr.Filter{userFilter(pubkey, 1, 6)}, cursor, limit, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal feed: %w", err)
+		return "", fmt.Errorf("failed to query cached feed: %w", err)
 	}
-
-	return string(jsonBytes), nil
+	return d.pageToJson(events, next)
 }
 
 // GetSingleEvent fetches a single event by ID (for Reply context).
+// Returns a list of 1 enriched event, so the Flutter side can reuse its list parsing
 func (d *DenDenClient) GetSingleEvent(eventId string) (string, error) {
-	if d.client.GetRelay() == nil {
-		return "", fmt.Errorf("not connected to relay")
+	event, err := d.lookupEvent(eventId)
+	if err != nil {
+		return "", err
 	}
+	return d.eventsToEnrichedJson([]*nostr.Event{event})
+}
 
+// lookupEvent finds an event by ID, in the local store first, then on the relays
+func (d *DenDenClient) lookupEvent(eventId string) (*nostr.Event, error) {
 	filter := nostr.Filter{
 		IDs:   []string{eventId},
 		Limit: 1,
 	}
 
+	// Events never change, so a local hit doesn't need a relay round trip
+	if local, err := d.client.QueryLocal(filter); err == nil && len(local) > 0 {
+		return local[0], nil
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	events, err := d.client.GetRelay().QuerySync(ctx, filter)
+	events, err := d.queryWithStore(ctx, filter)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if len(events) == 0 {
-		return "", fmt.Errorf("event not found")
+		return nil, fmt.Errorf("event not found")
 	}
-
-	// Enrich the single event
-	// We need to return a JSON string of the SINGLE event (or a list of 1? The UI helper expects list usually, but let's see)
-	// Actually, let's return a list of 1 to reuse the parsing logic on dart side if needed, or just a single object.
-	// Helper `eventsToEnrichedJson` returns a list. Let's return a list of 1.
-	return d.eventsToEnrichedJson(events)
+	return events[0], nil
 }
 
 // GetUserPosts returns Kind 1 (excluding replies) ONLY. No Reposts.
+// Use GetUserPostsPage to load older posts.
 func (d *DenDenClient) GetUserPosts(pubkey string, limit int) (string, error) {
-	// Fetch Kind 1 only
-	events, err := d.fetchUserEvents(pubkey, []int{1}, limit*2)
+	events, _, err := d.fetchUserPage(pubkey, "", limit, d.isPost)
 	if err != nil {
 		return "", err
 	}
+	return d.eventsToEnrichedJson(events)
+}
 
-	var filtered []*nostr.Event
-	for _, evt := range events {
-		// Strictly NO replies
-		if !d.isReply(evt) {
-			filtered = append(filtered, evt)
-		}
-	}
-
-	if len(filtered) > limit {
-		filtered = filtered[:limit]
+// GetUserPostsPage returns a page of Kind 1 events that are not replies: {"events":[...],"nextCursor":"..."}
+func (d *DenDenClient) GetUserPostsPage(pubkey string, limit int, cursor string) (string, error) {
+	events, next, err := d.fetchUserPage(pubkey, cursor, limit, d.isPost)
+	if err != nil {
+		return "", err
 	}
-
-	return d.eventsToEnrichedJson(filtered)
+	return d.pageToJson(events, next)
 }
 
 // GetUserReplies returns Kind 1 events that ARE replies.
+// Use GetUserRepliesPage to load older replies.
 func (d *DenDenClient) GetUserReplies(pubkey string, limit int) (string, error) {
-	events, err := d.fetchUserEvents(pubkey, []int{1}, limit*2)
+	events, _, err := d.fetchUserPage(pubkey, "", limit, d.isReply)
 	if err != nil {
 		return "", err
 	}
+	return d.eventsToEnrichedJson(events)
+}
 
-	var filtered []*nostr.Event
-	for _, evt := range events {
-		if d.isReply(evt) {
-			filtered = append(filtered, evt)
-		}
-	}
-
-	if len(filtered) > limit {
-		filtered = filtered[:limit]
+// GetUserRepliesPage returns a page of Kind 1 events that are replies: {"events":[...],"nextCursor":"..."}
+func (d *DenDenClient) GetUserRepliesPage(pubkey string, limit int, cursor string) (string, error) {
+	events, next, err := d.fetchUserPage(pubkey, cursor, limit, d.isReply)
+	if err != nil {
+		return "", err
 	}
-
-	return d.eventsToEnrichedJson(filtered)
+	return d.pageToJson(events, next)
 }
 
 // GetUserMedia returns events that contain image/video URLs.
+// Use GetUserMediaPage to load older events.
 func (d *DenDenClient) GetUserMedia(pubkey string, limit int) (string, error) {
-	events, err := d.fetchUserEvents(pubkey, []int{1}, limit*2)
+	events, _, err := d.fetchUserPage(pubkey, "", limit, d.isMedia)
 	if err != nil {
 		return "", err
 	}
+	return d.eventsToEnrichedJson(events)
+}
 
-	var filtered []*nostr.Event
-	for _, evt := range events {
-		if d.hasMedia(evt.Content) {
-			filtered = append(filtered, evt)
-		}
-	}
-
-	if len(filtered) > limit {
-		filtered = filtered[:limit]
+// GetUserMediaPage returns a page of Kind 1 events with image/video URLs: {"events":[...],"nextCursor":"..."}
+func (d *DenDenClient) GetUserMediaPage(pubkey string, limit int, cursor string) (string, error) {
+	events, next, err := d.fetchUserPage(pubkey, cursor, limit, d.isMedia)
+	if err != nil {
+		return "", err
 	}
-
-	return d.eventsToEnrichedJson(filtered)
+	return d.pageToJson(events, next)
 }
 
-// Helper to fetch events
-func (d *DenDenClient) fetchUserEvents(pubkey string, kinds []int, limit int) ([]*nostr.Event, error) {
-	if d.client.GetRelay() == nil {
-		return nil, fmt.Errorf("not connected to relay")
-	}
+// fetchUserPage pages through a user's Kind 1 events, keeping the ones keep accepts
+func (d *DenDenClient) fetchUserPage(pubkey, cursor string, limit int, keep func(*nostr.Event) bool) ([]*nostr.Event, string, error) {
+	return d.fetchPage([]nostr.Filter{userFilter(pubkey, 1)}, cursor, limit, keep)
+}
 
-	filter := nostr.Filter{
+// userFilter matches events of the given kinds authored by pubkey
+func userFilter(pubkey string, kinds ...int) nostr.Filter {
+	return nostr.Filter{
 		Kinds:   kinds,
 		Authors: []string{pubkey},
-		Limit:   limit,
 	}
+}
+
+// Helper to fetch events
+func (d *DenDenClient) fetchUserEvents(pubkey string, kinds []int, limit int) ([]*nostr.Event, error) {
+	filter := userFilter(pubkey, kinds...)
+	filter.Limit = limit
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	return d.client.GetRelay().QuerySync(ctx, filter)
+	return d.queryWithStore(ctx, filter)
+}
+
+// isPost reports whether a Kind 1 event is a top-level post (not a reply)
+func (d *DenDenClient) isPost(evt *nostr.Event) bool {
+	return !d.isReply(evt)
+}
+
+// isMedia reports whether an event links an image or video
+func (d *DenDenClient) isMedia(evt *nostr.Event) bool {
+	return d.hasMedia(evt.Content)
 }
 
 // Helper to determine if an event is a reply (NIP-10)
@@ -243,7 +208,18 @@ func (d *DenDenClient) hasMedia(content string) bool {
 
 // Reusable logic to enrich and marshal events
 func (d *DenDenClient) eventsToEnrichedJson(events []*nostr.Event) (string, error) {
-	var resultEvents []map[string]interface{}
+	jsonBytes, err := json.Marshal(d.enrichEvents(events))
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal feed: %w", err)
+	}
+
+	return string(jsonBytes), nil
+}
+
+// enrichEvents converts events to the post objects the UI expects,
+// with the author's cached profile attached
+func (d *DenDenClient) enrichEvents(events []*nostr.Event) []map[string]interface{} {
+	resultEvents := make([]map[string]interface{}, 0, len(events))
 
 	for _, evt := range events {
 		enriched := map[string]interface{}{
@@ -262,6 +238,7 @@ func (d *DenDenClient) eventsToEnrichedJson(events []*nostr.Event) (string, erro
 		}
 		d.cacheMutex.RUnlock()
 
+		// Kind 6 content is the reposted event's JSON (NIP-18), parsed on the Flutter side
 		if evt.Kind == 6 {
 			enriched["repostBy"] = evt.PubKey
 		}
@@ -269,12 +246,7 @@ func (d *DenDenClient) eventsToEnrichedJson(events []*nostr.Event) (string, erro
 		resultEvents = append(resultEvents, enriched)
 	}
 
-	jsonBytes, err := json.Marshal(resultEvents)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal feed: %w", err)
-	}
-
-	return string(jsonBytes), nil
+	return resultEvents
 }
 
 // GetUserHighlights returns Kind 9802 events.
@@ -300,54 +272,30 @@ func (d *DenDenClient) GetUserReposts(pubkey string, limit int) (string, error)
 
 // --- Moved from chat.go due to gomobile export issues ---
 
-// SendDirectMessage encrypts and sends a direct message (Kind 4)
+// SendDirectMessage sends a private direct message (NIP-17 gift wrap)
+// Uses the same DM path as the CLI, so both can read each other's messages
+// Mining the gift wraps stops on CancelMining, on Close, or after miningTimeout
 func (d *DenDenClient) SendDirectMessage(receiverPubkey, content string) error {
-	pk := d.client.GetPublicKey()
-	sk := d.client.GetPrivateKey()
-
-	sharedSecret, err := nip04.ComputeSharedSecret(receiverPubkey, sk)
-	if err != nil {
-		return fmt.Errorf("failed to compute shared secret: %w", err)
-	}
-
-	encrypted, err := nip04.Encrypt(content, sharedSecret)
-	if err != nil {
-		return fmt.Errorf("failed to encrypt: %w", err)
-	}
-
-	evt := nostr.Event{
-		Kind:      nostr.KindEncryptedDirectMessage, // 4
-		Content:   encrypted,
-		CreatedAt: nostr.Now(),
-		Tags:      nostr.Tags{{"p", receiverPubkey}},
-		PubKey:    pk,
-	}
-	evt.Sign(sk)
-
-	relay := d.client.GetRelay()
-	if relay == nil {
+	if d.client.GetPool() == nil {
 		return fmt.Errorf("no connected relay")
 	}
 
-	if err := relay.Publish(context.Background(), &evt); err != nil {
-		fmt.Printf("GO: SendDirectMessage failed to publish: %v\n", err)
-		return fmt.Errorf("failed to publish: %w", err)
+	ctx, done := d.startMining()
+	dm, err := d.client.SendPrivateMessage(ctx, receiverPubkey, content)
+	done()
+	if err != nil {
+		err = miningError(err)
+		fmt.Printf("GO: SendDirectMessage failed: %v\n", err)
+		return err
 	}
-	fmt.Printf("GO: SendDirectMessage: Published event %s\n", evt.ID)
+	fmt.Printf("GO: SendDirectMessage: Published message %s\n", dm.ID)
 
 	// Optimistically add to local cache
 	d.chatMutex.Lock()
 	defer d.chatMutex.Unlock()
 
-	msg := ChatMessage{
-		ID:        evt.ID,
-		Sender:    pk,
-		Content:   content,
-		CreatedAt: int64(evt.CreatedAt),
-		IsMine:    true,
-	}
-	d.chatCache[receiverPubkey] = append(d.chatCache[receiverPubkey], msg)
-	fmt.Printf("GO: SendDirectMessage: Added to cache for %s. New count: %d\n", receiverPubkey, len(d.chatCache[receiverPubkey]))
+	d.cacheDirectMessage(dm)
+	fmt.Printf("GO: SendDirectMessage: Added to cache for %s. New count: %d\n", dm.Partner, len(d.chatCache[dm.Partner]))
 	return nil
 }
 
@@ -361,29 +309,28 @@ type Conversation struct {
 	UnreadCount   int    `json:"unread_count"`
 }
 
-// DebugFetchMessages fetches NIP-04 messages (Kind 4) and decrypts them. Returns a debug log string.
+// DebugFetchMessages fetches direct messages (Kind 4 and NIP-17 gift wraps) and decrypts them. Returns a debug log string.
 func (d *DenDenClient) DebugFetchMessages(limit int64) (string, error) {
 	var log string
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	relay := d.client.GetRelay()
-	if relay == nil {
+	pool := d.client.GetPool()
+	if pool == nil {
 		// Auto-reconnect
-		log += "Relay nil, attempting auto-connect...\n"
+		log += "Relay pool nil, attempting auto-connect...\n"
 		if err := d.ConnectToDefault(); err != nil {
 			return log + fmt.Sprintf("Auto-connect failed: %v", err), fmt.Errorf("no connected relay and auto-connect failed: %w", err)
 		}
-		relay = d.client.GetRelay()
-		if relay == nil {
-			return log + "Auto-connect seemingly succeeded but GetRelay is still nil", fmt.Errorf("still no connected relay after connect")
+		pool = d.client.GetPool()
+		if pool == nil {
+			return log + "Auto-connect seemingly succeeded but GetPool is still nil", fmt.Errorf("still no connected relay after connect")
 		}
 	}
-	log += fmt.Sprintf("Relay connected: %s\n", "yes")
+	log += fmt.Sprintf("Relays connected: %d\n", pool.Size())
 
 	pk := d.client.GetPublicKey()
-	sk := d.client.GetPrivateKey()
 	log += fmt.Sprintf("My PubKey: %s...\n", pk[:8])
 
 	// 1. Fetch Received Messages (p = me)
@@ -394,7 +341,7 @@ func (d *DenDenClient) DebugFetchMessages(limit int64) (string, error) {
 	}
 
 	log += fmt.Sprintf("Querying received messages...\n")
-	eventsReceived, err := relay.QuerySync(ctx, filterReceived)
+	eventsReceived, err := d.client.QuerySync(ctx, filterReceived)
 	if err != nil {
 		log += fmt.Sprintf("Query received error: %v\n", err)
 	} else {
@@ -409,16 +356,34 @@ func (d *DenDenClient) DebugFetchMessages(limit int64) (string, error) {
 	}
 
 	log += fmt.Sprintf("Querying sent messages...\n")
-	eventsSent, err := relay.QuerySync(ctx, filterSent)
+	eventsSent, err := d.client.QuerySync(ctx, filterSent)
 	if err != nil {
 		log += fmt.Sprintf("Query sent error: %v\n", err)
 	} else {
 		log += fmt.Sprintf("Found %d sent events\n", len(eventsSent))
 	}
 
-	totalEvents := len(eventsReceived) + len(eventsSent)
+	// 3. Fetch NIP-17 gift wraps (p = me)
+	// Our sent messages come back as self-addressed copies, so one query covers both directions
+	// Wraps have randomized timestamps, so don't rely on the relay's time ordering here
+	filterWrapped := nostr.Filter{
+		Kinds: []int{1059},
+		Tags:  nostr.TagMap{"p": []string{pk}},
+		Limit: int(limit),
+	}
+
+	log += fmt.Sprintf("Querying gift-wrapped messages...\n")
+	eventsWrapped, err := d.client.QuerySync(ctx, filterWrapped)
+	if err != nil {
+		log += fmt.Sprintf("Query gift wraps error: %v\n", err)
+	} else {
+		log += fmt.Sprintf("Found %d gift wraps\n", len(eventsWrapped))
+	}
+
+	totalEvents := len(eventsReceived) + len(eventsSent) + len(eventsWrapped)
 	// Merge events
 	allEvents := append(eventsReceived, eventsSent...)
+	allEvents = append(allEvents, eventsWrapped...)
 	log += fmt.Sprintf("Total events to process: %d\n", totalEvents)
 
 	d.chatMutex.Lock()
@@ -428,75 +393,64 @@ func (d *DenDenClient) DebugFetchMessages(limit int64) (string, error) {
 	cachedCount := 0
 
 	for _, evt := range allEvents {
-		var partner string
-		var sharedSecret []byte
-		var err error
-		isMine := false
-
-		if evt.PubKey == pk {
-			isMine = true
-			for _, tag := range evt.Tags {
-				if len(tag) >= 2 && tag[0] == "p" {
-					partner = tag[1]
-					break
-				}
-			}
-			if partner == "" {
-				continue
-			}
-		} else {
-			partner = evt.PubKey
+		decrypted, added := d.cacheChatEvent(evt)
+		if decrypted {
+			decryptedCount++
 		}
-
-		if partner != "" {
-			sharedSecret, err = nip04.ComputeSharedSecret(partner, sk)
-			if err != nil {
-				continue
-			}
-		} else {
-			continue
+		if added {
+			cachedCount++
 		}
+	}
 
-		decrypted, err := nip04.Decrypt(evt.Content, sharedSecret)
-		if err != nil {
-			continue
-		}
-		decryptedCount++
-
-		// Check duplicates in cache
-		if d.chatCache[partner] != nil {
-			exists := false
-			for _, m := range d.chatCache[partner] {
-				if m.ID == evt.ID {
-					exists = true
-					break
-				}
-			}
-			if exists {
-				continue
-			}
-		}
+	d.sortChatCache()
+
+	log += fmt.Sprintf("Decrypted: %d, New Cached: %d\n", decryptedCount, cachedCount)
+	return log, nil
+}
+
+// cacheChatEvent decrypts a Kind 4 or Kind 1059 event and adds it to the chat cache
+// Caller must hold chatMutex
+// Returns whether it could be decrypted, and whether it was newly added
+func (d *DenDenClient) cacheChatEvent(evt *nostr.Event) (bool, bool) {
+	dm, err := d.client.DecryptDirectMessage(evt)
+	if err != nil {
+		return false, false
+	}
+
+	return true, d.cacheDirectMessage(dm)
+}
 
-		msg := ChatMessage{
-			ID:        evt.ID,
-			Sender:    evt.PubKey,
-			Content:   decrypted,
-			CreatedAt: int64(evt.CreatedAt),
-			IsMine:    isMine,
+// cacheDirectMessage adds a decrypted message to the chat cache
+// Caller must hold chatMutex
+// Returns false if the message was already cached
+func (d *DenDenClient) cacheDirectMessage(dm *client.DirectMessage) bool {
+	// Check duplicates in cache (NIP-17 copies share the rumor ID)
+	for _, m := range d.chatCache[dm.Partner] {
+		if m.ID == dm.ID {
+			return false
 		}
-		d.chatCache[partner] = append(d.chatCache[partner], msg)
-		cachedCount++
 	}
 
-	// Sort messages by time for each conversation
+	msg := ChatMessage{
+		ID:        dm.ID,
+		Sender:    dm.Sender,
+		Content:   dm.Content,
+		CreatedAt: int64(dm.CreatedAt),
+		IsMine:    dm.IsMine,
+		Scheme:    string(dm.Scheme),
+	}
+	d.chatCache[dm.Partner] = append(d.chatCache[dm.Partner], msg)
+	return true
+}
+
+// sortChatCache sorts messages by time for each conversation
+// Caller must hold chatMutex
+func (d *DenDenClient) sortChatCache() {
 	for k := range d.chatCache {
 		sort.Slice(d.chatCache[k], func(i, j int) bool {
 			return d.chatCache[k][i].CreatedAt < d.chatCache[k][j].CreatedAt
 		})
 	}
-
-	log += fmt.Sprintf("Decrypted: %d, New Cached: %d\n", decryptedCount, cachedCount)
-	return log, nil
 }
 
 // GetConversationList returns a JSON list of active conversations
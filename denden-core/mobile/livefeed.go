@@ -0,0 +1,141 @@
+// Package mobile provides GoMobile-compatible wrappers for the DenDen client.
+// This file contains live feeds: subscriptions that stream posts to their own callback.
+package mobile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// liveFeed is a feed streamed to a callback by its own subscription
+// (home timeline, nearby...), independent of StartListening
+type liveFeed struct {
+	mu       sync.Mutex
+	cancel   context.CancelFunc         // Stops the subscription (nil if not streaming)
+	callback StringCallback             // Receives the posts (nil if not streaming)
+	start    func(StringCallback) error // Starts the feed again with the same settings
+}
+
+// stop cancels the subscription
+func (f *liveFeed) stop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.cancel != nil {
+		f.cancel()
+	}
+	f.cancel = nil
+	f.callback = nil
+	f.start = nil
+}
+
+// restart starts the feed again (if running), e.g. after its filters changed
+func (f *liveFeed) restart() error {
+	f.mu.Lock()
+	callback, start := f.callback, f.start
+	f.mu.Unlock()
+
+	if callback == nil || start == nil {
+		return nil
+	}
+	return start(callback)
+}
+
+// startLiveFeed subscribes to new events matching the filters and streams them
+// Each post is sent as the JSON object the paged feeds return, with "feed" set
+// to name. annotate can add fields, or return false to skip an event (nil keeps all)
+// When streamed posts are deleted later, a Kind 5 message lists them (see
+// deletionMessage), with "feed" set too. A feed that was already streaming is replaced
+//
+// Parameters:
+//   - feed: the feed's state on the client
+//   - name: value of the "feed" field
+//   - filters: what to stream (Since is set to now, history comes from the paged API)
+//   - callback: receives the posts
+//   - annotate: adds fields to a post, or returns false to drop it (can be nil)
+//   - start: starts the feed again with the same settings (for restart)
+func (d *DenDenClient) startLiveFeed(
+	feed *liveFeed,
+	name string,
+	filters []nostr.Filter,
+	callback StringCallback,
+	annotate func(post map[string]interface{}, evt *nostr.Event) bool,
+	start func(StringCallback) error,
+) error {
+	if d.client.GetPool() == nil {
+		return fmt.Errorf("not connected to relay")
+	}
+
+	since := nostr.Now()
+	for i := range filters {
+		filters[i].Since = &since
+		filters[i].Until = nil
+		filters[i].Limit = 0
+	}
+
+	ctx, cancel := context.WithCancel(d.client.GetContext())
+	eventChan, err := d.client.Subscribe(ctx, filters)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("subscription failed: %w", err)
+	}
+
+	feed.mu.Lock()
+	if feed.cancel != nil {
+		feed.cancel()
+	}
+	feed.cancel = cancel
+	feed.callback = callback
+	feed.start = start
+	feed.mu.Unlock()
+
+	go d.handleLiveFeed(ctx, eventChan, name, callback, annotate)
+
+	return nil
+}
+
+// handleLiveFeed forwards the events of a live feed, and the deletions of
+// events it forwarded, to its callback
+func (d *DenDenClient) handleLiveFeed(
+	ctx context.Context,
+	eventChan chan *nostr.Event,
+	name string,
+	callback StringCallback,
+	annotate func(post map[string]interface{}, evt *nostr.Event) bool,
+) {
+	for {
+		select {
+		case <-d.stopChan:
+			return
+
+		case <-ctx.Done():
+			return
+
+		case event, ok := <-eventChan:
+			if !ok {
+				return
+			}
+
+			var post map[string]interface{}
+			if event.Kind == nostr.KindDeletion {
+				post = deletionMessage(event)
+			} else {
+				post = d.enrichEvents([]*nostr.Event{event})[0]
+				if annotate != nil && !annotate(post, event) {
+					continue
+				}
+			}
+			post["feed"] = name
+
+			jsonBytes, err := json.Marshal(post)
+			if err != nil {
+				continue
+			}
+			callback.OnMessage(string(jsonBytes))
+		}
+	}
+}
@@ -0,0 +1,231 @@
+// Package mobile provides GoMobile-compatible wrappers for the DenDen client.
+// This file contains the nearby feed: posts tagged with a geohash ("g" tag) close to the user.
+package mobile
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"denden-core/internal/geo"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// defaultNearbyRadiusKm is used when the app passes no radius
+const defaultNearbyRadiusKm = 10
+
+// distanceBuckets are the approximate distances shown on nearby posts
+// Geohashes only locate a post to a cell, so exact distances would be misleading
+var distanceBuckets = []struct {
+	maxKm float64
+	label string
+}{
+	{1, "<1km"},
+	{5, "<5km"},
+	{10, "<10km"},
+	{25, "<25km"},
+	{50, "<50km"},
+	{100, "<100km"},
+}
+
+// maxNearbyCells bounds the geohashes listed at one precision in a nearby query
+const maxNearbyCells = 150
+
+// nearbyQuery is a nearby feed: the "g" tag values around a center cell
+type nearbyQuery struct {
+	center   geo.Cell
+	radiusKm float64
+	values   []string // Geohashes to match, at several precisions
+}
+
+// newNearbyQuery lists the "g" tag values of the cells around a geohash
+// "g" filters match exact values and most clients tag a post with a single
+// geohash (the app uses 5 characters), so every cell within radiusKm is listed
+// at the geohash's own precision. When a large radius needs more than
+// maxNearbyCells of them, they are listed at the longest precision that fits:
+// posts tagged only with a longer geohash are then found in the neighboring
+// cells alone, but PublishTextNote tags every prefix, so DenDen posts still are
+// Coarser precisions, down to the one whose cells cover radiusKm, list the cell
+// and its neighbors for posts tagged with a coarse geohash only
+func newNearbyQuery(geohash string, radiusKm int) (*nearbyQuery, error) {
+	center, err := geo.Decode(geohash)
+	if err != nil {
+		return nil, err
+	}
+	if radiusKm <= 0 {
+		radiusKm = defaultNearbyRadiusKm
+	}
+
+	lat, lon := center.Center()
+	radius := float64(radiusKm)
+	coarsest := geo.PrecisionForRadius(lat, radius, len(center.Geohash))
+
+	var values []string
+	covered := false
+	for precision := len(center.Geohash); precision >= coarsest; precision-- {
+		if !covered {
+			if cells := geo.Cover(lat, lon, radius, precision, maxNearbyCells); cells != nil {
+				values = append(values, cells...)
+				covered = true
+				continue
+			}
+		}
+
+		cell := center.Geohash[:precision]
+		neighbors, err := geo.Neighbors(cell)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, cell)
+		values = append(values, neighbors...)
+	}
+
+	return &nearbyQuery{
+		center:   center,
+		radiusKm: radius,
+		values:   values,
+	}, nil
+}
+
+// filter matches text notes tagged with any of the query's geohashes
+func (q *nearbyQuery) filter() nostr.Filter {
+	return nostr.Filter{
+		Kinds: []int{1},
+		Tags:  nostr.TagMap{"g": q.values},
+	}
+}
+
+// locate returns a post's distance from the center, from its most precise "g" tag
+// The distance is between the centers of the two cells, and posts farther than
+// radiusKm are out of range (the query matches whole cells, some of them only
+// partly in range). Returns false if the post has no valid geohash or is out of range
+func (q *nearbyQuery) locate(evt *nostr.Event) (float64, string, bool) {
+	var best geo.Cell
+	for _, tag := range evt.Tags {
+		if len(tag) < 2 || tag[0] != "g" {
+			continue
+		}
+		cell, err := geo.Decode(tag[1])
+		if err == nil && len(cell.Geohash) > len(best.Geohash) {
+			best = cell
+		}
+	}
+	if best.Geohash == "" {
+		return 0, "", false
+	}
+
+	centerLat, centerLon := q.center.Center()
+	lat, lon := best.Center()
+	distance := geo.Distance(centerLat, centerLon, lat, lon)
+
+	if distance > q.radiusKm {
+		return 0, "", false
+	}
+	return distance, best.Geohash, true
+}
+
+// geohashPrefixTags adds a "g" tag for every shorter prefix of the post's
+// geohashes, so nearby queries at any precision find it
+func geohashPrefixTags(tags nostr.Tags) nostr.Tags {
+	present := make(map[string]bool)
+	for _, tag := range tags {
+		if len(tag) >= 2 && tag[0] == "g" {
+			present[strings.ToLower(tag[1])] = true
+		}
+	}
+
+	for _, tag := range tags {
+		if len(tag) < 2 || tag[0] != "g" {
+			continue
+		}
+		cell, err := geo.Decode(tag[1])
+		if err != nil {
+			continue
+		}
+		for precision := len(cell.Geohash) - 1; precision >= 1; precision-- {
+			prefix := cell.Geohash[:precision]
+			if !present[prefix] {
+				present[prefix] = true
+				tags = append(tags, nostr.Tag{"g", prefix})
+			}
+		}
+	}
+	return tags
+}
+
+// annotate adds the distance bucket and geohash to a post, or returns false if it's out of range
+func (q *nearbyQuery) annotate(post map[string]interface{}, evt *nostr.Event) bool {
+	distance, geohash, ok := q.locate(evt)
+	if !ok {
+		return false
+	}
+	post["distance"] = distanceBucket(distance)
+	post["geohash"] = geohash
+	return true
+}
+
+// distanceBucket returns the approximate distance label for a distance in km
+func distanceBucket(km float64) string {
+	for _, bucket := range distanceBuckets {
+		if km < bucket.maxKm {
+			return bucket.label
+		}
+	}
+	return "100km+"
+}
+
+// GetNearbyFeed returns a page of posts tagged with a geohash near the given one,
+// newest first: {"events":[...],"nextCursor":"..."}
+// Each post has "distance" (e.g. "<5km") and the "geohash" it was tagged with
+// Parameters:
+//   - geohash: the user's location (e.g. the geohash5 PublishTextNote tags posts with)
+//   - radiusKm: how far to look (0 = 10km)
+//   - limit: maximum number of posts to return
+//   - cursor: nextCursor of the previous page ("" for the first page)
+func (d *DenDenClient) GetNearbyFeed(geohash string, radiusKm int, limit int, cursor string) (string, error) {
+	query, err := newNearbyQuery(geohash, radiusKm)
+	if err != nil {
+		return "", fmt.Errorf("invalid location: %w", err)
+	}
+
+	keep := func(evt *nostr.Event) bool {
+		_, _, ok := query.locate(evt)
+		return ok
+	}
+	events, next, err := d.fetchPage([]nostr.Filter{query.filter()}, cursor, limit, keep)
+	if err != nil {
+		return "", fmt.Errorf("failed to query nearby feed: %w", err)
+	}
+
+	posts := d.enrichEvents(events)
+	for i, evt := range events {
+		query.annotate(posts[i], evt)
+	}
+
+	jsonBytes, err := json.Marshal(FeedPage{Events: posts, NextCursor: next})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal feed: %w", err)
+	}
+	return string(jsonBytes), nil
+}
+
+// StartNearbyFeed streams new posts tagged with a geohash near the given one
+// Each post is sent to the callback as the same JSON object GetNearbyFeed
+// returns, with "feed":"nearby". Calling it again moves the feed to the new location
+func (d *DenDenClient) StartNearbyFeed(geohash string, radiusKm int, callback StringCallback) error {
+	query, err := newNearbyQuery(geohash, radiusKm)
+	if err != nil {
+		return fmt.Errorf("invalid location: %w", err)
+	}
+
+	restart := func(callback StringCallback) error {
+		return d.StartNearbyFeed(geohash, radiusKm, callback)
+	}
+	return d.startLiveFeed(&d.nearbyFeed, "nearby", []nostr.Filter{query.filter()}, callback, query.annotate, restart)
+}
+
+// StopNearbyFeed stops the stream started by StartNearbyFeed
+func (d *DenDenClient) StopNearbyFeed() {
+	d.nearbyFeed.stop()
+}
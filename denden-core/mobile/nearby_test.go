@@ -0,0 +1,147 @@
+package mobile
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"denden-core/internal/geo"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// Tokyo Station
+const testLat, testLon = 35.681, 139.767
+
+// offsetGeohash returns the geohash of the point km east and north of the test location
+func offsetGeohash(east, north float64, precision int) string {
+	const kmPerDegree = 6371.0 * math.Pi / 180
+	lat := testLat + north/kmPerDegree
+	lon := testLon + east/(kmPerDegree*math.Cos(testLat*math.Pi/180))
+	return geo.Encode(lat, lon, precision)
+}
+
+// geoPost returns a text note with the given tags
+func geoPost(tags ...nostr.Tag) *nostr.Event {
+	return &nostr.Event{Kind: nostr.KindTextNote, Tags: tags}
+}
+
+func TestNearbyQueryCoversRadius(t *testing.T) {
+	center := geo.Encode(testLat, testLon, 5)
+
+	tests := []struct {
+		name     string
+		radiusKm int
+		east     float64
+		north    float64
+		want     bool
+	}{
+		{"same cell", 10, 0, 0, true},
+		{"next cell", 10, 5, 0, true},
+		{"two cells away", 10, -8, 3, true},
+		{"out of range", 10, 25, 0, false},
+		{"far out of range", 10, 200, 200, false},
+		{"wide radius", 25, 0, -20, true},
+		{"wide radius, out of range", 25, 40, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := newNearbyQuery(center, tt.radiusKm)
+			if err != nil {
+				t.Fatalf("newNearbyQuery: %v", err)
+			}
+
+			// Posts from other clients carry the 5-character geohash only
+			post := geoPost(nostr.Tag{"g", offsetGeohash(tt.east, tt.north, 5)})
+			filter := query.filter()
+			_, _, kept := query.locate(post)
+			if got := filter.Matches(post) && kept; got != tt.want {
+				t.Errorf("found = %v (matched %v, kept %v), want %v", got, filter.Matches(post), kept, tt.want)
+			}
+		})
+	}
+}
+
+func TestNearbyQueryLargeRadius(t *testing.T) {
+	center := geo.Encode(testLat, testLon, 5)
+	query, err := newNearbyQuery(center, 100)
+	if err != nil {
+		t.Fatalf("newNearbyQuery: %v", err)
+	}
+	if len(query.values) > 2*maxNearbyCells {
+		t.Fatalf("query lists %d geohashes", len(query.values))
+	}
+
+	// Posts tagged with every prefix are found anywhere in range
+	near := geoPost(geohashPrefixTags(nostr.Tags{{"g", offsetGeohash(60, 30, 5)}})...)
+	if _, _, kept := query.locate(near); !query.filter().Matches(near) || !kept {
+		t.Errorf("post 67km away not found")
+	}
+
+	far := geoPost(geohashPrefixTags(nostr.Tags{{"g", offsetGeohash(150, 0, 5)}})...)
+	if _, _, kept := query.locate(far); kept {
+		t.Errorf("post 150km away kept")
+	}
+}
+
+func TestNearbyQueryDefaultRadius(t *testing.T) {
+	query, err := newNearbyQuery("xn76u", 0)
+	if err != nil {
+		t.Fatalf("newNearbyQuery: %v", err)
+	}
+	if query.radiusKm != defaultNearbyRadiusKm {
+		t.Errorf("radiusKm = %f, want %d", query.radiusKm, defaultNearbyRadiusKm)
+	}
+
+	if _, err := newNearbyQuery("not a geohash", 10); err == nil {
+		t.Error("invalid geohash accepted")
+	}
+}
+
+func TestNearbyLocateUsesMostPreciseTag(t *testing.T) {
+	query, err := newNearbyQuery(geo.Encode(testLat, testLon, 5), 10)
+	if err != nil {
+		t.Fatalf("newNearbyQuery: %v", err)
+	}
+
+	precise := offsetGeohash(3, 0, 5)
+	_, geohash, ok := query.locate(geoPost(nostr.Tag{"g", precise[:3]}, nostr.Tag{"g", precise, "Tokyo"}, nostr.Tag{"g", "nope!"}))
+	if !ok || geohash != precise {
+		t.Errorf("locate = (%s, %v), want (%s, true)", geohash, ok, precise)
+	}
+
+	if _, _, ok := query.locate(geoPost(nostr.Tag{"t", "tokyo"})); ok {
+		t.Error("post without a geohash kept")
+	}
+}
+
+func TestGeohashPrefixTags(t *testing.T) {
+	tests := []struct {
+		name string
+		tags nostr.Tags
+		want nostr.Tags
+	}{
+		{"no tags", nil, nil},
+		{"no geohash", nostr.Tags{{"t", "denden"}}, nostr.Tags{{"t", "denden"}}},
+		{
+			"geohash with a city",
+			nostr.Tags{{"g", "xn76u", "Tokyo"}},
+			nostr.Tags{{"g", "xn76u", "Tokyo"}, {"g", "xn76"}, {"g", "xn7"}, {"g", "xn"}, {"g", "x"}},
+		},
+		{
+			"prefix already present",
+			nostr.Tags{{"g", "xn7"}, {"g", "xn76u"}},
+			nostr.Tags{{"g", "xn7"}, {"g", "xn76u"}, {"g", "xn"}, {"g", "x"}, {"g", "xn76"}},
+		},
+		{"invalid geohash", nostr.Tags{{"g", "nope!"}}, nostr.Tags{{"g", "nope!"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := geohashPrefixTags(tt.tags); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
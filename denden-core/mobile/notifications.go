@@ -0,0 +1,337 @@
+// Package mobile provides GoMobile-compatible wrappers for the DenDen client.
+// This file contains notifications: mentions, replies, quotes, reactions, reposts, zaps and follows.
+package mobile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"denden-core/internal/notify"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// defaultNotificationLimit is how many events GetNotifications reads when the app passes no limit
+const defaultNotificationLimit = 100
+
+// notificationActors is how many actors of a group are sent with their profile
+const notificationActors = 3
+
+// NotificationActor is someone who caused a notification
+type NotificationActor struct {
+	Pubkey    string `json:"pubkey"`
+	Name      string `json:"name,omitempty"`
+	AvatarURL string `json:"avatarUrl,omitempty"`
+}
+
+// NotificationGroup is a group of notifications sent to Flutter
+// ("Alice and 4 others liked your post": Actors[0] is Alice, ActorCount is 5)
+type NotificationGroup struct {
+	ID         string              `json:"id"`       // Stable key: a streamed group replaces the one with the same id
+	Category   string              `json:"category"` // mention, reply, quote, reaction, repost, zap or follow
+	Target     string              `json:"target,omitempty"`
+	Actors     []NotificationActor `json:"actors"` // Most recent first (up to 3)
+	ActorCount int                 `json:"actorCount"`
+	Count      int                 `json:"count"`
+	EventIDs   []string            `json:"eventIds"`
+	Content    string              `json:"content,omitempty"` // Latest reply/mention text or reaction
+	Amount     int64               `json:"amount,omitempty"`  // Total zapped, in sats
+	Time       string              `json:"time"`
+	Unread     bool                `json:"unread"`
+	Feed       string              `json:"feed,omitempty"` // "notifications" on streamed groups
+}
+
+// notificationFilter matches the events that tag the user and can notify them
+func (d *DenDenClient) notificationFilter(limit int) nostr.Filter {
+	return nostr.Filter{
+		Kinds: notify.Kinds,
+		Tags:  nostr.TagMap{"p": {d.client.GetPublicKey()}},
+		Limit: limit,
+	}
+}
+
+// GetNotifications returns the user's recent notifications, grouped by target
+// and most recent first: JSON array of NotificationGroup
+// Reactions, reposts and zaps of the same post are one group, and so are new
+// followers; replies, quotes and mentions are one group each
+// Timeout: 5 seconds
+// Parameters:
+//   - limit: how many of the latest events to read (0 = 100)
+func (d *DenDenClient) GetNotifications(limit int) (string, error) {
+	if limit <= 0 {
+		limit = defaultNotificationLimit
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	feed, err := d.loadNotifications(ctx, limit)
+	if err != nil {
+		return "", fmt.Errorf("failed to query notifications: %w", err)
+	}
+
+	groups := feed.Groups()
+
+	var actors []string
+	for _, group := range groups {
+		actors = append(actors, group.Actors...)
+	}
+	d.fetchProfiles(ctx, actors)
+
+	return d.notificationsToJson(groups)
+}
+
+// GetCachedNotifications is GetNotifications from the local event store only
+// It returns at once, without waiting for relays, so the app can show the
+// cached notifications first; actors without a cached profile have no name yet
+// Parameters:
+//   - limit: how many of the latest events to read (0 = 100)
+func (d *DenDenClient) GetCachedNotifications(limit int) (string, error) {
+	if limit <= 0 {
+		limit = defaultNotificationLimit
+	}
+	if d.client.GetStore() == nil {
+		return "", fmt.Errorf("event store unavailable")
+	}
+
+	events, err := d.client.QueryLocal(d.notificationFilter(limit))
+	if err != nil {
+		return "", fmt.Errorf("failed to query cached notifications: %w", err)
+	}
+
+	// Zaps whose receipt signer was never looked up wait for GetNotifications
+	feed := notify.NewFeed(d.client.GetPublicKey())
+	feed.SetZapSigners(d.cachedZapSigners([]string{d.client.GetPublicKey()}))
+	for _, event := range events {
+		feed.Add(event)
+	}
+	return d.notificationsToJson(feed.Groups())
+}
+
+// notificationsToJson converts groups to a JSON array of NotificationGroup
+func (d *DenDenClient) notificationsToJson(groups []notify.Group) (string, error) {
+	readAt := d.notificationsReadAt()
+	result := make([]NotificationGroup, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, d.toNotificationGroup(group, readAt))
+	}
+
+	jsonBytes, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize notifications: %w", err)
+	}
+	return string(jsonBytes), nil
+}
+
+// loadNotifications groups the latest notification events (local store, then relays)
+// Zap receipts count only if the user's LNURL server signed them
+func (d *DenDenClient) loadNotifications(ctx context.Context, limit int) (*notify.Feed, error) {
+	events, err := d.queryWithStore(ctx, d.notificationFilter(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	feed := notify.NewFeed(d.client.GetPublicKey())
+	feed.SetZapSigners(d.resolveZapSigners(ctx, []string{d.client.GetPublicKey()}))
+	for _, event := range events {
+		feed.Add(event)
+	}
+	return feed, nil
+}
+
+// StartNotifications streams notifications as they arrive
+// Each new event is sent to the callback as its updated NotificationGroup,
+// with "feed":"notifications": a group with an id the app already shows
+// replaces it ("Alice and 5 others liked your post"). When a streamed reply,
+// reaction or repost is deleted later, a Kind 5 message lists it (see deletionMessage)
+// Recent notifications are loaded first so groups continue where GetNotifications left off.
+// SwitchAccount restarts the stream for the new account
+func (d *DenDenClient) StartNotifications(callback StringCallback) error {
+	if d.client.GetPool() == nil {
+		return fmt.Errorf("not connected to relay")
+	}
+
+	since := nostr.Now()
+	filter := d.notificationFilter(0)
+	filter.Since = &since
+
+	ctx, cancel := context.WithCancel(d.client.GetContext())
+	eventChan, err := d.client.Subscribe(ctx, []nostr.Filter{filter})
+	if err != nil {
+		cancel()
+		return fmt.Errorf("subscription failed: %w", err)
+	}
+
+	d.notifyFeed.mu.Lock()
+	if d.notifyFeed.cancel != nil {
+		d.notifyFeed.cancel()
+	}
+	d.notifyFeed.cancel = cancel
+	d.notifyFeed.callback = callback
+	d.notifyFeed.start = d.StartNotifications
+	d.notifyFeed.mu.Unlock()
+
+	go d.handleNotifications(ctx, eventChan, callback)
+
+	return nil
+}
+
+// StopNotifications stops the stream started by StartNotifications
+func (d *DenDenClient) StopNotifications() {
+	d.notifyFeed.stop()
+}
+
+// handleNotifications groups the streamed events and forwards the updated groups
+func (d *DenDenClient) handleNotifications(ctx context.Context, eventChan chan *nostr.Event, callback StringCallback) {
+	// New events wait in the channel while the history loads
+	historyCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	feed, err := d.loadNotifications(historyCtx, defaultNotificationLimit)
+	cancel()
+	if err != nil {
+		feed = notify.NewFeed(d.client.GetPublicKey())
+		feed.SetZapSigners(d.cachedZapSigners([]string{d.client.GetPublicKey()}))
+	}
+
+	for {
+		select {
+		case <-d.stopChan:
+			return
+
+		case <-ctx.Done():
+			return
+
+		case event, ok := <-eventChan:
+			if !ok {
+				return
+			}
+
+			if event.Kind == nostr.KindDeletion {
+				message := deletionMessage(event)
+				message["feed"] = "notifications"
+				if jsonBytes, err := json.Marshal(message); err == nil {
+					callback.OnMessage(string(jsonBytes))
+				}
+				continue
+			}
+
+			group, ok := feed.Add(event)
+			if !ok {
+				continue
+			}
+
+			// Unknown authors are fetched in the background (sent as Kind 0 on the main callback)
+			if profile := d.getProfileFromCache(group.Actors[0]); profile.Name == "" && profile.Picture == "" {
+				d.FetchProfile(group.Actors[0])
+			}
+
+			notification := d.toNotificationGroup(group, d.notificationsReadAt())
+			notification.Feed = "notifications"
+
+			jsonBytes, err := json.Marshal(notification)
+			if err != nil {
+				continue
+			}
+			callback.OnMessage(string(jsonBytes))
+		}
+	}
+}
+
+// toNotificationGroup converts a group to its JSON form, with the actors' cached profiles
+func (d *DenDenClient) toNotificationGroup(group notify.Group, readAt int64) NotificationGroup {
+	result := NotificationGroup{
+		ID:         group.ID,
+		Category:   group.Type,
+		Target:     group.Target,
+		Actors:     make([]NotificationActor, 0, notificationActors),
+		ActorCount: len(group.Actors),
+		Count:      group.Count,
+		EventIDs:   group.EventIDs,
+		Content:    group.Content,
+		Amount:     group.Amount,
+		Time:       group.LatestAt.Time().Format(time.RFC3339),
+		Unread:     int64(group.LatestAt) > readAt,
+	}
+
+	for i, pubkey := range group.Actors {
+		if i == notificationActors {
+			break
+		}
+		profile := d.getProfileFromCache(pubkey)
+		result.Actors = append(result.Actors, NotificationActor{
+			Pubkey:    pubkey,
+			Name:      profile.Name,
+			AvatarURL: profile.Picture,
+		})
+	}
+	return result
+}
+
+// notificationsReadKey is the setting that holds an account's read watermark
+func (d *DenDenClient) notificationsReadKey() string {
+	return "notifications.read." + d.client.GetPublicKey()
+}
+
+// MarkNotificationsRead stores the read watermark: notifications up to this time are read
+// Stored locally, per account
+// Parameters:
+//   - until: Unix time of the latest notification read (0 = now)
+func (d *DenDenClient) MarkNotificationsRead(until int64) error {
+	store := d.client.GetStore()
+	if store == nil {
+		return fmt.Errorf("event store unavailable")
+	}
+	if until <= 0 {
+		until = time.Now().Unix()
+	}
+
+	// The watermark never moves back
+	if until <= d.notificationsReadAt() {
+		return nil
+	}
+	return store.SetSetting(d.notificationsReadKey(), strconv.FormatInt(until, 10))
+}
+
+// GetNotificationsReadAt returns the read watermark (Unix time, 0 if nothing was read yet)
+func (d *DenDenClient) GetNotificationsReadAt() int64 {
+	return d.notificationsReadAt()
+}
+
+// notificationsReadAt reads the read watermark from the local store
+func (d *DenDenClient) notificationsReadAt() int64 {
+	store := d.client.GetStore()
+	if store == nil {
+		return 0
+	}
+
+	value, err := store.GetSetting(d.notificationsReadKey())
+	if err != nil || value == "" {
+		return 0
+	}
+	readAt, _ := strconv.ParseInt(value, 10, 64)
+	return readAt
+}
+
+// GetUnreadNotificationCount returns how many notification groups are newer than the
+// read watermark, counting the notifications already in the local store
+func (d *DenDenClient) GetUnreadNotificationCount() int {
+	readAt := d.notificationsReadAt()
+
+	filter := d.notificationFilter(0)
+	since := nostr.Timestamp(readAt + 1)
+	filter.Since = &since
+
+	events, err := d.client.QueryLocal(filter)
+	if err != nil {
+		return 0
+	}
+
+	feed := notify.NewFeed(d.client.GetPublicKey())
+	feed.SetZapSigners(d.cachedZapSigners([]string{d.client.GetPublicKey()}))
+	for _, event := range events {
+		feed.Add(event)
+	}
+	return len(feed.Groups())
+}
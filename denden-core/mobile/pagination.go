@@ -0,0 +1,304 @@
+// Package mobile provides GoMobile-compatible wrappers for the DenDen client.
+// This file contains cursor-based pagination for feeds (infinite scroll).
+package mobile
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// maxPageRounds bounds how many queries a filtered page (posts, replies, media)
+// may take to fill up, so a user with no matching events doesn't scan forever
+const maxPageRounds = 5
+
+// FeedPage is one page of a feed as returned to Flutter
+// Pass NextCursor to the next call to get older events; it is empty on the last page
+type FeedPage struct {
+	Events     []map[string]interface{} `json:"events"`
+	NextCursor string                   `json:"nextCursor"`
+}
+
+// feedCursor is the position after the last event of a page
+// Feeds are sorted by created_at desc, but relays don't order events of the
+// same second in any particular way, so the cursor keeps the IDs already
+// returned at its second: the next page asks for events up to that second
+// again (NIP-01 until is inclusive) and skips those IDs
+type feedCursor struct {
+	until nostr.Timestamp // created_at of the last event returned
+	seen  map[string]bool // IDs already returned at until
+}
+
+// newFeedCursor returns a cursor right after an event
+func newFeedCursor(evt *nostr.Event) *feedCursor {
+	return &feedCursor{until: evt.CreatedAt, seen: map[string]bool{evt.ID: true}}
+}
+
+// encode makes the cursor opaque to Flutter ("until:id,id,...")
+func (c *feedCursor) encode() string {
+	ids := make([]string, 0, len(c.seen))
+	for id := range c.seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	raw := strconv.FormatInt(int64(c.until), 10) + ":" + strings.Join(ids, ",")
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor parses a cursor from a previous page ("" = first page)
+func decodeCursor(cursor string) (*feedCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	until, ids, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	ts, err := strconv.ParseInt(until, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if ts < 0 {
+		return nil, fmt.Errorf("invalid cursor: negative timestamp")
+	}
+
+	cur := &feedCursor{until: nostr.Timestamp(ts), seen: make(map[string]bool)}
+	if ids == "" {
+		return cur, nil
+	}
+	for _, id := range strings.Split(ids, ",") {
+		if !nostr.IsValid32ByteHex(id) {
+			return nil, fmt.Errorf("invalid cursor: bad event id %q", id)
+		}
+		cur.seen[id] = true
+	}
+	return cur, nil
+}
+
+// isAfter reports whether an event comes after the cursor in feed order
+func (c *feedCursor) isAfter(evt *nostr.Event) bool {
+	if c == nil {
+		return true
+	}
+	if evt.CreatedAt != c.until {
+		return evt.CreatedAt < c.until
+	}
+	return !c.seen[evt.ID]
+}
+
+// advance moves the cursor past an event returned in a page
+func (c *feedCursor) advance(evt *nostr.Event) *feedCursor {
+	if c == nil || evt.CreatedAt != c.until {
+		return newFeedCursor(evt)
+	}
+	c.seen[evt.ID] = true
+	return c
+}
+
+// fetchPage returns the next page of the events matching any of the filters, newest first
+// Each query asks for events up to the cursor's second (NIP-01 until is inclusive),
+// then skips the ones already returned. keep filters events client-side
+// (nil keeps all); the cursor moves past skipped events too, so they aren't
+// scanned again on the next page
+// Events deleted by their authors (NIP-09) are left out
+//
+// Parameters:
+//   - filters: Kinds, Authors, Tags... (Limit, Since and Until are set here)
+//   - cursor: NextCursor of the previous page ("" for the first page)
+//   - limit: maximum number of events to return
+//   - keep: client-side filter (can be nil)
+//
+// Returns:
+//   - []*nostr.Event: at most limit events
+//   - string: cursor of the next page ("" if there are no older events)
+//   - error: invalid cursor or query error
+func (d *DenDenClient) fetchPage(filters []nostr.Filter, cursor string, limit int, keep func(*nostr.Event) bool) ([]*nostr.Event, string, error) {
+	return d.fetchPageFrom(false, filters, cursor, limit, keep)
+}
+
+// fetchLocalPage is fetchPage on the local event store only: it never waits
+// for a relay, so the app can show cached events right away. Cursors work
+// with both, so the next page can come from either
+func (d *DenDenClient) fetchLocalPage(filters []nostr.Filter, cursor string, limit int, keep func(*nostr.Event) bool) ([]*nostr.Event, string, error) {
+	return d.fetchPageFrom(true, filters, cursor, limit, keep)
+}
+
+// fetchPageFrom implements fetchPage and fetchLocalPage
+// The local store drops deleted events when it saves them, so only relay
+// results need the deletion lookup
+func (d *DenDenClient) fetchPageFrom(local bool, filters []nostr.Filter, cursor string, limit int, keep func(*nostr.Event) bool) ([]*nostr.Event, string, error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("limit must be positive")
+	}
+
+	cur, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Client-side filters drop events, so ask for more than a page each time
+	pageBatch := limit + 1
+	if keep != nil {
+		pageBatch = limit * 2
+	}
+
+	var page []*nostr.Event
+	lookups := newDeletionLookups()
+	for round := 0; round < maxPageRounds; round++ {
+		// The events already seen at the cursor's second come back first,
+		// so ask for that many more
+		batchSize := pageBatch
+		if cur != nil {
+			batchSize += len(cur.seen)
+		}
+
+		queries := make([]nostr.Filter, len(filters))
+		for i, filter := range filters {
+			filter.Limit = batchSize
+			filter.Since = nil
+			if cur != nil {
+				until := cur.until
+				filter.Until = &until
+			}
+			queries[i] = filter
+		}
+
+		var events []*nostr.Event
+		if local {
+			events, err = d.queryLocalAll(queries, batchSize)
+		} else {
+			events, err = d.queryAll(ctx, queries, batchSize)
+		}
+		if err != nil {
+			if round > 0 {
+				break // Return what we have, the cursor resumes from there
+			}
+			return nil, "", err
+		}
+		exhausted := len(events) < batchSize
+		if !local {
+			events = d.hideDeleted(ctx, events, lookups)
+		}
+
+		scanned := 0
+		progressed := false
+		for _, evt := range events {
+			if len(page) == limit {
+				break
+			}
+			scanned++
+			if !cur.isAfter(evt) {
+				continue
+			}
+
+			cur = cur.advance(evt)
+			progressed = true
+			if keep == nil || keep(evt) {
+				page = append(page, evt)
+			}
+		}
+
+		if len(page) == limit {
+			if exhausted && scanned == len(events) {
+				return page, "", nil
+			}
+			return page, cur.encode(), nil
+		}
+		if exhausted {
+			return page, "", nil
+		}
+
+		// Nothing new in a full batch: a relay capped the limit below the
+		// number of events seen at the cursor's second. Move on to the
+		// previous second rather than asking for the same batch again
+		if !progressed && cur != nil {
+			cur = &feedCursor{until: cur.until - 1, seen: make(map[string]bool)}
+		}
+	}
+
+	if cur == nil {
+		return page, "", nil
+	}
+	return page, cur.encode(), nil
+}
+
+// queryAll runs several filters concurrently and merges the results by time
+// If limit > 0, at most limit events are returned; one filter is enough
+// to return a result, the others' errors are ignored
+func (d *DenDenClient) queryAll(ctx context.Context, filters []nostr.Filter, limit int) ([]*nostr.Event, error) {
+	switch len(filters) {
+	case 0:
+		return nil, nil
+	case 1:
+		return d.queryWithStore(ctx, filters[0])
+	}
+
+	results := make([][]*nostr.Event, len(filters))
+	errs := make([]error, len(filters))
+
+	var wg sync.WaitGroup
+	for i, filter := range filters {
+		wg.Add(1)
+		go func(i int, filter nostr.Filter) {
+			defer wg.Done()
+			results[i], errs[i] = d.queryWithStore(ctx, filter)
+		}(i, filter)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err == nil {
+			return mergeEvents(limit, results...), nil
+		}
+	}
+	return nil, errs[0]
+}
+
+// queryLocalAll runs several filters on the local event store and merges the
+// results by time (at most limit events if limit > 0)
+func (d *DenDenClient) queryLocalAll(filters []nostr.Filter, limit int) ([]*nostr.Event, error) {
+	if d.client.GetStore() == nil {
+		return nil, fmt.Errorf("event store unavailable")
+	}
+
+	results := make([][]*nostr.Event, 0, len(filters))
+	for _, filter := range filters {
+		events, err := d.client.QueryLocal(filter)
+		if err != nil {
+			return nil, fmt.Errorf("local query failed: %w", err)
+		}
+		results = append(results, events)
+	}
+	return mergeEvents(limit, results...), nil
+}
+
+// pageToJson enriches a page of events and marshals it with its cursor
+func (d *DenDenClient) pageToJson(events []*nostr.Event, nextCursor string) (string, error) {
+	jsonBytes, err := json.Marshal(FeedPage{
+		Events:     d.enrichEvents(events),
+		NextCursor: nextCursor,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal feed: %w", err)
+	}
+
+	return string(jsonBytes), nil
+}
@@ -0,0 +1,269 @@
+package mobile
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// newTestClient creates a client with a fresh identity and event store, not connected to any relay
+func newTestClient(t *testing.T) *DenDenClient {
+	t.Helper()
+
+	d, err := NewDenDenClient(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDenDenClient: %v", err)
+	}
+	if d.client.GetStore() == nil {
+		t.Fatal("event store unavailable")
+	}
+	t.Cleanup(func() { d.Close() })
+	return d
+}
+
+// saveNotes stores signed Kind 1 notes, one per timestamp
+func saveNotes(t *testing.T, d *DenDenClient, sk string, timestamps ...nostr.Timestamp) []*nostr.Event {
+	t.Helper()
+
+	pubKey, _ := nostr.GetPublicKey(sk)
+	events := make([]*nostr.Event, 0, len(timestamps))
+	for i, ts := range timestamps {
+		event := &nostr.Event{
+			PubKey:    pubKey,
+			CreatedAt: ts,
+			Kind:      nostr.KindTextNote,
+			Content:   fmt.Sprintf("note %d", i),
+		}
+		if err := event.Sign(sk); err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+		if _, err := d.client.GetStore().SaveEvent(event); err != nil {
+			t.Fatalf("SaveEvent: %v", err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// eventID returns a valid event ID made of one repeated hex digit
+func eventID(digit string) string {
+	return strings.Repeat(digit, 64)
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		cur  *feedCursor
+	}{
+		{"one event", &feedCursor{until: 1700000000, seen: map[string]bool{eventID("a"): true}}},
+		{"several events at the same second", &feedCursor{until: 1700000000, seen: map[string]bool{
+			eventID("c"): true, eventID("a"): true, eventID("b"): true,
+		}}},
+		{"no events seen", &feedCursor{until: 42, seen: map[string]bool{}}},
+		{"zero timestamp", &feedCursor{until: 0, seen: map[string]bool{eventID("0"): true}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := tt.cur.encode()
+			if strings.ContainsAny(encoded, "+/=") {
+				t.Errorf("cursor %q isn't URL-safe", encoded)
+			}
+
+			got, err := decodeCursor(encoded)
+			if err != nil {
+				t.Fatalf("decodeCursor: %v", err)
+			}
+			if got.until != tt.cur.until || len(got.seen) != len(tt.cur.seen) {
+				t.Fatalf("decoded %+v, want %+v", got, tt.cur)
+			}
+			for id := range tt.cur.seen {
+				if !got.seen[id] {
+					t.Errorf("decoded cursor lost %s", id)
+				}
+			}
+
+			// The same set of IDs always encodes the same way
+			if again := got.encode(); again != encoded {
+				t.Errorf("re-encoded %q, want %q", again, encoded)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorEmpty(t *testing.T) {
+	cur, err := decodeCursor("")
+	if err != nil || cur != nil {
+		t.Fatalf(`decodeCursor("") = %+v, %v; want nil, nil`, cur, err)
+	}
+	if !cur.isAfter(&nostr.Event{CreatedAt: 1}) {
+		t.Error("the first page cursor skips events")
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "!!!"},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte("1:"))},
+		{"no separator", encode("1700000000")},
+		{"timestamp not a number", encode("yesterday:" + eventID("a"))},
+		{"negative timestamp", encode("-1:" + eventID("a"))},
+		{"timestamp overflow", encode("99999999999999999999:" + eventID("a"))},
+		{"short event id", encode("1700000000:abc")},
+		{"event id not hex", encode("1700000000:" + strings.Repeat("z", 64))},
+		{"empty id in the list", encode("1700000000:" + eventID("a") + ",")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if cur, err := decodeCursor(tt.cursor); err == nil {
+				t.Fatalf("decodeCursor accepted %q: %+v", tt.cursor, cur)
+			}
+		})
+	}
+}
+
+func TestCursorIsAfter(t *testing.T) {
+	cur := &feedCursor{until: 100, seen: map[string]bool{eventID("5"): true}}
+
+	tests := []struct {
+		name  string
+		event *nostr.Event
+		want  bool
+	}{
+		{"older", &nostr.Event{ID: eventID("f"), CreatedAt: 99}, true},
+		{"newer", &nostr.Event{ID: eventID("f"), CreatedAt: 101}, false},
+		{"seen at the same second", &nostr.Event{ID: eventID("5"), CreatedAt: 100}, false},
+		{"unseen at the same second, lower ID", &nostr.Event{ID: eventID("1"), CreatedAt: 100}, true},
+		{"unseen at the same second, higher ID", &nostr.Event{ID: eventID("9"), CreatedAt: 100}, true},
+	}
+
+	for _, tt := range tests {
+		if got := cur.isAfter(tt.event); got != tt.want {
+			t.Errorf("%s: isAfter = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFetchPageSameSecond(t *testing.T) {
+	d := newTestClient(t)
+	sk := nostr.GeneratePrivateKey()
+
+	// A burst of notes in one second, more than fit in a page, around older and newer ones
+	var timestamps []nostr.Timestamp
+	timestamps = append(timestamps, 1700000005)
+	for i := 0; i < 23; i++ {
+		timestamps = append(timestamps, 1700000000)
+	}
+	timestamps = append(timestamps, 1699999999, 1699999998)
+	saved := saveNotes(t, d, sk, timestamps...)
+
+	pubKey, _ := nostr.GetPublicKey(sk)
+	filters := []nostr.Filter{{Kinds: []int{nostr.KindTextNote}, Authors: []string{pubKey}}}
+
+	for _, limit := range []int{1, 4, 10, 30} {
+		t.Run(fmt.Sprintf("limit %d", limit), func(t *testing.T) {
+			seen := make(map[string]bool)
+			var last nostr.Timestamp = 1 << 40
+			cursor := ""
+			for pages := 0; ; pages++ {
+				if pages > len(saved)+1 {
+					t.Fatal("pagination doesn't end")
+				}
+
+				page, next, err := d.fetchLocalPage(filters, cursor, limit, nil)
+				if err != nil {
+					t.Fatalf("fetchLocalPage: %v", err)
+				}
+				if len(page) > limit {
+					t.Fatalf("page has %d events, limit is %d", len(page), limit)
+				}
+				for _, evt := range page {
+					if seen[evt.ID] {
+						t.Fatalf("event %s returned twice", evt.ID)
+					}
+					if evt.CreatedAt > last {
+						t.Fatalf("event at %d after one at %d", evt.CreatedAt, last)
+					}
+					seen[evt.ID] = true
+					last = evt.CreatedAt
+				}
+
+				if next == "" {
+					break
+				}
+				cursor = next
+			}
+
+			if len(seen) != len(saved) {
+				t.Fatalf("paged through %d events, want %d", len(seen), len(saved))
+			}
+		})
+	}
+}
+
+func TestFetchPageKeep(t *testing.T) {
+	d := newTestClient(t)
+	sk := nostr.GeneratePrivateKey()
+
+	var timestamps []nostr.Timestamp
+	for i := 0; i < 12; i++ {
+		timestamps = append(timestamps, 1700000000)
+	}
+	saved := saveNotes(t, d, sk, timestamps...)
+
+	// Keep every third note; the others are skipped without being returned again
+	wanted := make(map[string]bool)
+	for i, evt := range saved {
+		if i%3 == 0 {
+			wanted[evt.ID] = true
+		}
+	}
+	keep := func(evt *nostr.Event) bool { return wanted[evt.ID] }
+
+	pubKey, _ := nostr.GetPublicKey(sk)
+	filters := []nostr.Filter{{Kinds: []int{nostr.KindTextNote}, Authors: []string{pubKey}}}
+
+	got := make(map[string]bool)
+	cursor := ""
+	for pages := 0; pages <= len(saved); pages++ {
+		page, next, err := d.fetchLocalPage(filters, cursor, 2, keep)
+		if err != nil {
+			t.Fatalf("fetchLocalPage: %v", err)
+		}
+		for _, evt := range page {
+			if !wanted[evt.ID] || got[evt.ID] {
+				t.Fatalf("unexpected event %s", evt.ID)
+			}
+			got[evt.ID] = true
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	if len(got) != len(wanted) {
+		t.Fatalf("got %d events, want %d", len(got), len(wanted))
+	}
+}
+
+func TestFetchPageInvalidArguments(t *testing.T) {
+	d := newTestClient(t)
+	filters := []nostr.Filter{{Kinds: []int{nostr.KindTextNote}}}
+
+	if _, _, err := d.fetchLocalPage(filters, "", 0, nil); err == nil {
+		t.Error("limit 0 accepted")
+	}
+	if _, _, err := d.fetchLocalPage(filters, "not a cursor!", 10, nil); err == nil {
+		t.Error("invalid cursor accepted")
+	}
+}
@@ -0,0 +1,176 @@
+// Package mobile provides GoMobile-compatible wrappers for the DenDen client.
+// This file contains the publish pipeline with cancellable proof-of-work mining,
+// the PoW policy and mining estimates for sent events, and the PoW required
+// from received events.
+package mobile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"denden-core/internal/pow"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// miningTimeout bounds the PoW for a single event
+const miningTimeout = 2 * time.Minute
+
+// publishEvent sends an event through the client's publish pipeline
+// (mine → sign → publish → store), forwarding mining progress to Flutter
+// Mining stops on CancelMining, on Close, or after miningTimeout
+func (d *DenDenClient) publishEvent(event *nostr.Event) error {
+	ctx, done := d.startMining()
+	defer done()
+
+	err := d.client.PublishEvent(ctx, event, func(p pow.Progress) {
+		d.onMiningProgress(event.Kind, p)
+	})
+	return miningError(err)
+}
+
+// startMining returns the context to mine with: canceled by CancelMining, by
+// Close, or after miningTimeout. Call done once the event is sent
+func (d *DenDenClient) startMining() (ctx context.Context, done func()) {
+	ctx, cancel := context.WithTimeout(d.client.GetContext(), miningTimeout)
+
+	d.miningMutex.Lock()
+	d.miningID++
+	id := d.miningID
+	d.miningCancels[id] = cancel
+	d.miningMutex.Unlock()
+
+	return ctx, func() {
+		d.miningMutex.Lock()
+		delete(d.miningCancels, id)
+		d.miningMutex.Unlock()
+		cancel()
+	}
+}
+
+// miningError tells canceled and timed-out mining apart from other send errors
+func miningError(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return fmt.Errorf("mining canceled: %w", err)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("mining timed out: %w", err)
+	}
+	return err
+}
+
+// CancelMining aborts every PoW in progress; the publish calls waiting on it return an error
+func (d *DenDenClient) CancelMining() {
+	d.miningMutex.Lock()
+	defer d.miningMutex.Unlock()
+
+	for _, cancel := range d.miningCancels {
+		cancel()
+	}
+}
+
+// IsMining reports whether a PoW is in progress
+func (d *DenDenClient) IsMining() bool {
+	d.miningMutex.Lock()
+	defer d.miningMutex.Unlock()
+
+	return len(d.miningCancels) > 0
+}
+
+// onMiningProgress notifies the mobile callback about a running PoW
+// Message format: {"type":"pow_progress","kind":6,"attempts":123456,"hashRate":250000,"best":17,"target":20,"elapsedMs":500}
+func (d *DenDenClient) onMiningProgress(kind int, p pow.Progress) {
+	if d.callback == nil {
+		return
+	}
+
+	d.callback.OnMessage(fmt.Sprintf(
+		`{"type":"pow_progress","kind":%d,"attempts":%d,"hashRate":%.0f,"best":%d,"target":%d,"elapsedMs":%d}`,
+		kind,
+		p.Attempts,
+		p.HashRate,
+		p.BestDifficulty,
+		p.Target,
+		p.Elapsed.Milliseconds(),
+	))
+}
+
+// SetMinDifficulty sets the minimum PoW received events of a kind must have (0 accepts any)
+// Events below it are dropped before they reach the callback, feeds or the local store
+func (d *DenDenClient) SetMinDifficulty(kind, difficulty int) {
+	d.client.GetIngest().SetMinDifficulty(kind, difficulty)
+}
+
+// GetMinDifficulty returns the minimum PoW required for received events of a kind
+func (d *DenDenClient) GetMinDifficulty(kind int) int {
+	return d.client.GetIngest().MinDifficulty(kind)
+}
+
+// SetStrictPoW turns on the recommended minimum PoW for received public posts
+// and reposts (Kinds 1, 6 and 16). Off by default: most clients don't mine, so
+// it hides most of the network. DMs never require PoW unless SetMinDifficulty asks for it
+func (d *DenDenClient) SetStrictPoW(enabled bool) {
+	d.client.GetIngest().SetStrict(enabled)
+}
+
+// IsStrictPoW reports whether received public posts must carry the recommended PoW
+func (d *DenDenClient) IsStrictPoW() bool {
+	return d.client.GetIngest().IsStrict()
+}
+
+// SetPublishDifficulty overrides the PoW mined into sent events of a kind (0 disables it)
+// Use it when a relay requires more (or less) than the recommended difficulty
+func (d *DenDenClient) SetPublishDifficulty(kind, difficulty int) {
+	d.client.GetPublishPolicy().Set(kind, difficulty)
+}
+
+// ResetPublishDifficulty goes back to the recommended PoW for sent events of a kind
+func (d *DenDenClient) ResetPublishDifficulty(kind int) {
+	d.client.GetPublishPolicy().Reset(kind)
+}
+
+// GetPublishDifficulty returns the PoW mined into sent events of a kind
+func (d *DenDenClient) GetPublishDifficulty(kind int) int {
+	return d.client.GetPublishPolicy().Difficulty(kind)
+}
+
+// PowEstimate is the PoW a sent event of a kind will need, as returned to Flutter
+type PowEstimate struct {
+	Kind       int            `json:"kind"`
+	Difficulty int            `json:"difficulty"` // Bits from the publish policy
+	EstimateMs int64          `json:"estimateMs"` // Expected mining time on this device (average)
+	HashRate   float64        `json:"hashRate"`   // Attempts per second on this device
+	Relays     map[string]int `json:"relays"`     // NIP-11 min_pow_difficulty per relay (-1 = unknown)
+}
+
+// EstimatePowJSON estimates how long publishing an event of a kind will take
+// so the app can show "this will take ~4s" before the user sends it
+// The device is benchmarked once, the first call takes about half a second
+// Returns JSON: {"kind":1,"difficulty":20,"estimateMs":4000,"hashRate":262144,"relays":{"wss://...":16}}
+func (d *DenDenClient) EstimatePowJSON(kind int) (string, error) {
+	ctx, cancel := context.WithTimeout(d.client.GetContext(), 10*time.Second)
+	defer cancel()
+
+	difficulty, estimate, err := d.client.EstimatePublish(ctx, kind)
+	if err != nil {
+		return "", err
+	}
+
+	advisor := d.client.GetPowAdvisor()
+	hashRate, _ := advisor.HashRate(ctx) // Already measured by EstimatePublish
+
+	jsonBytes, err := json.Marshal(PowEstimate{
+		Kind:       kind,
+		Difficulty: difficulty,
+		EstimateMs: estimate.Milliseconds(),
+		HashRate:   hashRate,
+		Relays:     advisor.RelayRequirements(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal estimate: %w", err)
+	}
+	return string(jsonBytes), nil
+}
@@ -25,7 +25,7 @@ func (d *DenDenClient) getProfileFromCache(pubkey string) Profile {
 // FetchProfile sends a request to fetch metadata for the given pubkey.
 // It updates the cache and notifies the frontend via the callback.
 func (d *DenDenClient) FetchProfile(pubkey string) {
-	if d.client.GetRelay() == nil {
+	if d.client.GetPool() == nil {
 		return
 	}
 
@@ -39,38 +39,64 @@ func (d *DenDenClient) FetchProfile(pubkey string) {
 			Limit:   1,
 		}
 
-		// Use the underlying go-nostr Relay to get access to EndOfStoredEvents
-		sub, err := d.client.GetRelay().Relay.Subscribe(ctx, []nostr.Filter{filter})
-		if err != nil {
+		// Query every relay in the pool; QuerySync returns once all of them sent EOSE
+		// Kind 0 is replaceable, and results are sorted newest first
+		events, err := d.client.QuerySync(ctx, filter)
+		if err != nil || len(events) == 0 {
 			return
 		}
+		ev := events[0]
 
-		// We only expect one event (replaceable)
-		select {
-		case ev := <-sub.Events:
-			if ev == nil {
-				return
-			}
-			// Update cache
-			d.cacheProfile(ev.PubKey, ev.Content)
+		// Update cache
+		d.cacheProfile(ev.PubKey, ev.Content)
 
-			// Notify Flutter
-			if d.callback != nil {
-				// Construct JSON matching what Flutter HomeFeed expects
-				// Flutter checks for "kind":0 and uses "content" (stringified JSON) and "pubkey"
-				msg := fmt.Sprintf(`{"kind":0,"pubkey":"%s","content":"%s"}`, ev.PubKey, escapeJSON(ev.Content))
-				d.callback.OnMessage(msg)
-			}
-		case <-ctx.Done():
-			// Timeout
-		case <-sub.EndOfStoredEvents:
-			// No profile found
+		// Notify Flutter
+		if d.callback != nil {
+			// Construct JSON matching what Flutter HomeFeed expects
+			// Flutter checks for "kind":0 and uses "content" (stringified JSON) and "pubkey"
+			msg := fmt.Sprintf(`{"kind":0,"pubkey":"%s","content":"%s"}`, ev.PubKey, escapeJSON(ev.Content))
+			d.callback.OnMessage(msg)
 		}
-
-		sub.Unsub()
 	}()
 }
 
+// fetchProfiles loads the profiles (Kind 0) of the given authors that aren't
+// cached yet, in one query, and caches them. Unlike FetchProfile it waits for
+// the results and doesn't notify Flutter
+func (d *DenDenClient) fetchProfiles(ctx context.Context, pubkeys []string) {
+	var uncached []string
+	seen := make(map[string]bool)
+
+	d.cacheMutex.RLock()
+	for _, pubkey := range pubkeys {
+		if _, ok := d.profileCache[pubkey]; !ok && !seen[pubkey] {
+			seen[pubkey] = true
+			uncached = append(uncached, pubkey)
+		}
+	}
+	d.cacheMutex.RUnlock()
+
+	if len(uncached) == 0 {
+		return
+	}
+
+	events, err := d.queryWithStore(ctx, nostr.Filter{
+		Kinds:   []int{0},
+		Authors: uncached,
+	})
+	if err != nil {
+		return
+	}
+
+	// Kind 0 is replaceable: results are newest first, keep the first per author
+	for _, ev := range events {
+		if seen[ev.PubKey] {
+			delete(seen, ev.PubKey)
+			d.cacheProfile(ev.PubKey, ev.Content)
+		}
+	}
+}
+
 // GetProfile returns a profile as JSON string
 // This allows Flutter to query profiles manually
 func (d *DenDenClient) GetProfile(pubkey string) string {
@@ -3,7 +3,6 @@
 package mobile
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 
@@ -12,8 +11,9 @@ import (
 
 // PublishTextNote publishes a public text note (Kind 1)
 // tagsJSON is optional - a JSON string like [["g","geohash","City"]]
+// A "g" tag gets one more "g" tag per shorter prefix (["g","xn76u"] adds "xn76", "xn7"...)
 func (d *DenDenClient) PublishTextNote(content string, tagsJSON string) error {
-	if d.client.GetRelay() == nil {
+	if d.client.GetPool() == nil {
 		return fmt.Errorf("not connected to relay")
 	}
 
@@ -32,25 +32,16 @@ func (d *DenDenClient) PublishTextNote(content string, tagsJSON string) error {
 		}
 	}
 
-	// 1. Construct the event
+	// 1. Construct the event, tagged with every prefix of its geohash for the nearby feed
 	ev := nostr.Event{
-		PubKey:    d.client.GetIdentity().PublicKey,
-		CreatedAt: nostr.Now(),
-		Kind:      1, // Kind 1 = Short Text Note
-		Tags:      tags,
-		Content:   content,
+		Kind:    1, // Kind 1 = Short Text Note
+		Tags:    geohashPrefixTags(tags),
+		Content: content,
 	}
 
-	// 2. Sign the event
-	err := ev.Sign(d.client.GetIdentity().PrivateKey)
-	if err != nil {
-		return fmt.Errorf("failed to sign event: %w", err)
-	}
-
-	// 3. Publish to the currently connected relay
-	err = d.client.GetRelay().Publish(context.Background(), &ev)
-	if err != nil {
-		return fmt.Errorf("failed to publish event: %w", err)
+	// 2. Mine, sign and publish to the connected relays
+	if err := d.publishEvent(&ev); err != nil {
+		return fmt.Errorf("failed to publish note: %w", err)
 	}
 
 	return nil
@@ -59,7 +50,7 @@ func (d *DenDenClient) PublishTextNote(content string, tagsJSON string) error {
 // PublishMetadata publishes user metadata (Kind 0) to the network
 // Accepts a JSON string containing name, about, picture, banner, website
 func (d *DenDenClient) PublishMetadata(metadataJson string) error {
-	if d.client.GetRelay() == nil {
+	if d.client.GetPool() == nil {
 		return fmt.Errorf("not connected to relay")
 	}
 
@@ -77,26 +68,17 @@ func (d *DenDenClient) PublishMetadata(metadataJson string) error {
 
 	// 3. Construct the event
 	ev := nostr.Event{
-		PubKey:    d.client.GetIdentity().PublicKey,
-		CreatedAt: nostr.Now(),
-		Kind:      0, // Kind 0 = Metadata
-		Tags:      nil,
-		Content:   string(contentBytes),
+		Kind:    0, // Kind 0 = Metadata
+		Tags:    nil,
+		Content: string(contentBytes),
 	}
 
-	// 4. Sign the event
-	err = ev.Sign(d.client.GetIdentity().PrivateKey)
-	if err != nil {
-		return fmt.Errorf("failed to sign event: %w", err)
-	}
-
-	// 5. Publish to the relay
-	err = d.client.GetRelay().Publish(context.Background(), &ev)
-	if err != nil {
+	// 4. Mine, sign and publish
+	if err := d.publishEvent(&ev); err != nil {
 		return fmt.Errorf("failed to publish metadata: %w", err)
 	}
 
-	// 6. Update local cache immediately so UI reflects changes
+	// 5. Update local cache immediately so UI reflects changes
 	d.cacheMutex.Lock()
 	d.profileCache[d.client.GetIdentity().PublicKey] = metadata
 	d.cacheMutex.Unlock()
@@ -4,9 +4,17 @@ package mobile
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"denden-core/internal/stats"
+	"denden-core/internal/thread"
+	"denden-core/internal/zap"
+
 	"github.com/nbd-wtf/go-nostr"
 )
 
@@ -18,104 +26,174 @@ type LikeResult struct {
 	PostID      string // The post that was liked/unliked
 }
 
+// ReactionResult represents the result of a React call
+// GoMobile will convert this to a Swift/Kotlin class
+type ReactionResult struct {
+	IsReacted       bool   // true = just reacted, false = reaction just removed
+	ReactionEventID string // The event ID of the reaction (empty if removed)
+	PostID          string // The post that was reacted to
+	Content         string // The reaction ("+", "-", an emoji or a :shortcode:)
+}
+
+// reactionSet is the user's reactions to one post (content -> reaction event ID)
+type reactionSet map[string]string
+
 // ToggleLike toggles the like state of a post
 // If not liked -> sends Kind 7 (like) and returns IsLiked=true
 // If already liked -> sends Kind 5 (delete) and returns IsLiked=false
 // Go manages the like state internally, Flutter doesn't need to track IDs
+// postId: the post's ID or full JSON (see React)
 func (d *DenDenClient) ToggleLike(postId string) (*LikeResult, error) {
-	if d.client.GetRelay() == nil {
+	result, err := d.React(postId, stats.Like, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &LikeResult{
+		IsLiked:     result.IsReacted,
+		LikeEventID: result.ReactionEventID,
+		PostID:      result.PostID,
+	}, nil
+}
+
+// React toggles a reaction (Kind 7, NIP-25) to a post
+// Reacting again with the same content removes the reaction (Kind 5). Each content
+// is tracked on its own, so a post can be liked and 🔥'd at the same time
+// Parameters:
+//   - postEventJson: the full JSON of the post (a bare event ID is also accepted and looked up)
+//   - content: "+" (like), "-" (dislike), an emoji, or a custom emoji as :shortcode: (NIP-30)
+//   - emojiUrl: image URL of a custom emoji ("" for any other reaction)
+func (d *DenDenClient) React(postEventJson, content, emojiUrl string) (*ReactionResult, error) {
+	if d.client.GetPool() == nil {
 		return nil, fmt.Errorf("not connected to relay")
 	}
+	if content == "" {
+		return nil, fmt.Errorf("reaction can't be empty")
+	}
+
+	shortcode, isCustom := customEmojiShortcode(content)
+	if isCustom && emojiUrl == "" {
+		return nil, fmt.Errorf("custom emoji %s needs an image url", content)
+	}
 
-	// Check if already liked
+	post, err := d.resolveEvent(postEventJson)
+	if err != nil {
+		return nil, err
+	}
+
+	// Already reacted with this content -> remove the reaction
 	d.likeMutex.RLock()
-	existingLikeId, isLiked := d.likeCache[postId]
+	existingId, reacted := d.likeCache[post.ID][content]
 	d.likeMutex.RUnlock()
 
-	if isLiked {
-		// Already liked -> Unlike (send Kind 5 deletion)
-		err := d.sendUnlike(existingLikeId)
-		if err != nil {
+	if reacted {
+		if err := d.deleteReaction(d.sameReactions(post.ID, content, existingId)...); err != nil {
 			return nil, err
 		}
 
-		// Remove from cache
 		d.likeMutex.Lock()
-		delete(d.likeCache, postId)
+		delete(d.likeCache[post.ID], content)
+		if len(d.likeCache[post.ID]) == 0 {
+			delete(d.likeCache, post.ID)
+		}
 		d.likeMutex.Unlock()
 
-		return &LikeResult{
-			IsLiked:     false,
-			LikeEventID: "",
-			PostID:      postId,
+		return &ReactionResult{
+			IsReacted: false,
+			PostID:    post.ID,
+			Content:   content,
 		}, nil
 	}
 
-	// Not liked -> Like (send Kind 7 reaction)
-	likeEventId, err := d.sendLike(postId)
-	if err != nil {
-		return nil, err
+	ev := nostr.Event{
+		Kind:    7, // Kind 7 = Reaction
+		Tags:    reactionTags(post, d.relayHint()),
+		Content: content,
+	}
+	if isCustom {
+		ev.Tags = append(ev.Tags, nostr.Tag{"emoji", shortcode, emojiUrl})
+	}
+
+	if err := d.publishEvent(&ev); err != nil {
+		return nil, fmt.Errorf("failed to publish reaction: %w", err)
 	}
 
-	// Store in cache
 	d.likeMutex.Lock()
-	d.likeCache[postId] = likeEventId
+	if d.likeCache[post.ID] == nil {
+		d.likeCache[post.ID] = make(reactionSet)
+	}
+	d.likeCache[post.ID][content] = ev.ID
 	d.likeMutex.Unlock()
 
-	return &LikeResult{
-		IsLiked:     true,
-		LikeEventID: likeEventId,
-		PostID:      postId,
+	return &ReactionResult{
+		IsReacted:       true,
+		ReactionEventID: ev.ID,
+		PostID:          post.ID,
+		Content:         content,
 	}, nil
 }
 
-// sendLike sends a Kind 7 like event and returns the event ID
-func (d *DenDenClient) sendLike(postId string) (string, error) {
-	ev := nostr.Event{
-		PubKey:    d.client.GetIdentity().PublicKey,
-		CreatedAt: nostr.Now(),
-		Kind:      7, // Kind 7 = Reaction
-		Tags: nostr.Tags{
-			{"e", postId},
-		},
-		Content: "+",
+// reactionTags builds the tags of a reaction to post (NIP-25):
+// the post with its author ("e"), the author ("p") and the post's kind ("k")
+func reactionTags(post *nostr.Event, relayHint string) nostr.Tags {
+	return nostr.Tags{
+		{"e", post.ID, relayHint, post.PubKey},
+		{"p", post.PubKey, relayHint},
+		{"k", strconv.Itoa(post.Kind)},
 	}
+}
 
-	err := ev.Sign(d.client.GetIdentity().PrivateKey)
-	if err != nil {
-		return "", fmt.Errorf("failed to sign like event: %w", err)
+// customEmojiShortcode returns the shortcode of a custom emoji reaction (":soapbox:" -> "soapbox")
+// NIP-30 shortcodes are letters, digits, underscores and hyphens
+func customEmojiShortcode(content string) (string, bool) {
+	if len(content) < 3 || content[0] != ':' || content[len(content)-1] != ':' {
+		return "", false
 	}
 
-	err = d.client.GetRelay().Publish(context.Background(), &ev)
-	if err != nil {
-		return "", fmt.Errorf("failed to publish like: %w", err)
+	shortcode := content[1 : len(content)-1]
+	for _, r := range shortcode {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return "", false
+		}
 	}
-
-	return ev.ID, nil
+	return shortcode, true
 }
 
-// sendUnlike sends a Kind 5 deletion event
-func (d *DenDenClient) sendUnlike(likeEventId string) error {
-	ev := nostr.Event{
-		PubKey:    d.client.GetIdentity().PublicKey,
-		CreatedAt: nostr.Now(),
-		Kind:      5, // Kind 5 = Deletion
-		Tags: nostr.Tags{
-			{"e", likeEventId},
-		},
-		Content: "unlike",
+// sameReactions returns the IDs of all the user's reactions to a post with the
+// given content in the local store (older clients could react twice), including knownId
+func (d *DenDenClient) sameReactions(postId, content, knownId string) []string {
+	ids := []string{knownId}
+
+	reactions, _ := d.client.QueryLocal(nostr.Filter{
+		Kinds:   []int{7},
+		Authors: []string{d.client.GetPublicKey()},
+		Tags:    nostr.TagMap{"e": {postId}},
+	})
+	for _, reaction := range reactions {
+		same := reaction.Content == content || (reaction.Content == "" && content == stats.Like)
+		if same && reaction.ID != knownId && reactionTarget(reaction) == postId {
+			ids = append(ids, reaction.ID)
+		}
 	}
+	return ids
+}
 
-	err := ev.Sign(d.client.GetIdentity().PrivateKey)
-	if err != nil {
-		return fmt.Errorf("failed to sign unlike event: %w", err)
+// reactionTarget returns the event a reaction is about (NIP-25: the last "e" tag)
+func reactionTarget(reaction *nostr.Event) string {
+	target := ""
+	for _, tag := range reaction.Tags {
+		if len(tag) >= 2 && tag[0] == "e" {
+			target = tag[1]
+		}
 	}
+	return target
+}
 
-	err = d.client.GetRelay().Publish(context.Background(), &ev)
-	if err != nil {
+// deleteReaction sends a Kind 5 deletion event for some of the user's reactions
+func (d *DenDenClient) deleteReaction(reactionEventIds ...string) error {
+	if _, err := d.deleteEvents(reactionEventIds, "unlike"); err != nil {
 		return fmt.Errorf("failed to publish unlike: %w", err)
 	}
-
 	return nil
 }
 
@@ -123,10 +201,131 @@ func (d *DenDenClient) sendUnlike(likeEventId string) error {
 func (d *DenDenClient) IsPostLiked(postId string) bool {
 	d.likeMutex.RLock()
 	defer d.likeMutex.RUnlock()
-	_, exists := d.likeCache[postId]
+	_, exists := d.likeCache[postId][stats.Like]
 	return exists
 }
 
+// GetMyReactions returns the user's reactions to a post (from Go cache)
+// Returns: JSON array of reaction contents, e.g. ["+","🔥"]
+func (d *DenDenClient) GetMyReactions(postId string) string {
+	d.likeMutex.RLock()
+	contents := make([]string, 0, len(d.likeCache[postId]))
+	for content := range d.likeCache[postId] {
+		contents = append(contents, content)
+	}
+	d.likeMutex.RUnlock()
+
+	sort.Strings(contents)
+	jsonBytes, _ := json.Marshal(contents)
+	return string(jsonBytes)
+}
+
+// reactionHistoryLimit bounds the user's reactions and deletions fetched from the relays
+const reactionHistoryLimit = 1000
+
+// loadReactions rebuilds the like/reaction state from the user's own
+// Kind 7 and Kind 5 events in the local event store
+func (d *DenDenClient) loadReactions() {
+	me := d.client.GetPublicKey()
+	events, err := d.client.QueryLocal(nostr.Filter{
+		Kinds:   []int{7, 5},
+		Authors: []string{me},
+	})
+	if err != nil {
+		return
+	}
+
+	reactions := buildReactionCache(me, events)
+
+	// The account may have changed while the store was read
+	if d.client.GetPublicKey() != me {
+		return
+	}
+	d.likeMutex.Lock()
+	d.likeCache = reactions
+	d.likeMutex.Unlock()
+}
+
+// syncReactions fetches the user's reactions and deletions from the relays
+// (reactions made on other devices), then rebuilds the state from the store
+// Runs after connecting and after switching accounts
+func (d *DenDenClient) syncReactions() {
+	if d.client.GetPool() == nil || d.client.GetStore() == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	me := d.client.GetPublicKey()
+	filters := []nostr.Filter{
+		{Kinds: []int{7}, Authors: []string{me}, Limit: reactionHistoryLimit},
+		{Kinds: []int{5}, Authors: []string{me}, Limit: reactionHistoryLimit},
+	}
+
+	// The relays' answers are saved to the store as they come in
+	if _, err := d.queryAll(ctx, filters, 0); err != nil {
+		fmt.Printf("GO: Failed to sync reactions: %v\n", err)
+		return
+	}
+	d.loadReactions()
+}
+
+// buildReactionCache returns the user's current reactions (postId -> content -> reaction event ID)
+// from their Kind 7 and Kind 5 events. A reaction only counts as removed if
+// a Kind 5 by the user references that reaction event; if the user reacted
+// with the same content twice, the latest reaction is kept
+func buildReactionCache(me string, events []*nostr.Event) map[string]reactionSet {
+	deleted := make(map[string]bool)
+	var reactions []*nostr.Event
+	for _, evt := range events {
+		if evt.PubKey != me {
+			continue
+		}
+
+		switch evt.Kind {
+		case 5:
+			for _, tag := range evt.Tags {
+				if len(tag) >= 2 && tag[0] == "e" {
+					deleted[tag[1]] = true
+				}
+			}
+		case 7:
+			reactions = append(reactions, evt)
+		}
+	}
+
+	// Oldest first, so later reactions win
+	sort.Slice(reactions, func(i, j int) bool {
+		if reactions[i].CreatedAt != reactions[j].CreatedAt {
+			return reactions[i].CreatedAt < reactions[j].CreatedAt
+		}
+		return reactions[i].ID < reactions[j].ID
+	})
+
+	cache := make(map[string]reactionSet)
+	for _, reaction := range reactions {
+		if deleted[reaction.ID] {
+			continue
+		}
+
+		postId := reactionTarget(reaction)
+		if postId == "" {
+			continue
+		}
+
+		content := reaction.Content
+		if content == "" {
+			content = stats.Like // An empty reaction is a like
+		}
+		if cache[postId] == nil {
+			cache[postId] = make(reactionSet)
+		}
+		cache[postId][content] = reaction.ID
+	}
+	return cache
+}
+
 // LikePost is kept for backward compatibility, but ToggleLike is preferred
 // Deprecated: Use ToggleLike instead
 func (d *DenDenClient) LikePost(eventId string) error {
@@ -134,103 +333,187 @@ func (d *DenDenClient) LikePost(eventId string) error {
 	return err
 }
 
-// ReplyPost sends a reply (Kind 1 with e tag) to the specified event
-// The reply is a regular text note with an 'e' tag referencing the parent
-func (d *DenDenClient) ReplyPost(eventId string, content string) error {
-	if d.client.GetRelay() == nil {
+// ReplyPost sends a reply (Kind 1) to the given event, tagged per NIP-10
+// The parent's root is tagged "root" and the parent "reply", with relay hints, and
+// the parent's author and the thread participants get "p" tags so they're notified
+// parentEventJson: the full JSON of the event being replied to
+// (a bare event ID is also accepted and looked up, for older callers)
+func (d *DenDenClient) ReplyPost(parentEventJson string, content string) error {
+	if d.client.GetPool() == nil {
 		return fmt.Errorf("not connected to relay")
 	}
 
-	ev := nostr.Event{
-		PubKey:    d.client.GetIdentity().PublicKey,
-		CreatedAt: nostr.Now(),
-		Kind:      1, // Kind 1 = Text Note
-		Tags: nostr.Tags{
-			{"e", eventId, "", "reply"},
-		},
-		Content: content,
+	parent, err := d.resolveEvent(parentEventJson)
+	if err != nil {
+		return err
 	}
 
-	err := ev.Sign(d.client.GetIdentity().PrivateKey)
-	if err != nil {
-		return fmt.Errorf("failed to sign reply event: %w", err)
+	ev := nostr.Event{
+		Kind:    1, // Kind 1 = Text Note
+		Tags:    thread.ReplyTags(parent, d.relayHint(), d.client.GetPublicKey()),
+		Content: content,
 	}
 
-	err = d.client.GetRelay().Publish(context.Background(), &ev)
-	if err != nil {
+	if err := d.publishEvent(&ev); err != nil {
 		return fmt.Errorf("failed to publish reply: %w", err)
 	}
 
 	return nil
 }
 
+// resolveEvent reads an event from its JSON, or looks it up by ID
+func (d *DenDenClient) resolveEvent(eventJson string) (*nostr.Event, error) {
+	trimmed := strings.TrimSpace(eventJson)
+	if !strings.HasPrefix(trimmed, "{") {
+		event, err := d.lookupEvent(trimmed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find event: %w", err)
+		}
+		return event, nil
+	}
+
+	var event nostr.Event
+	if err := json.Unmarshal([]byte(trimmed), &event); err != nil {
+		return nil, fmt.Errorf("invalid event json: %w", err)
+	}
+	if event.ID == "" || event.PubKey == "" {
+		return nil, fmt.Errorf("event json needs id and pubkey")
+	}
+	return &event, nil
+}
+
 // PostStats represents statistics for a post
 // GoMobile will convert this to a Swift/Kotlin class
+// Replies, reactions, reposts and quotes count once per author; zaps count every receipt
 type PostStats struct {
-	PostID      string // The post ID
-	LikeCount   int    // Number of likes (Kind 7 reactions)
-	ReplyCount  int    // Number of replies (reserved for future)
-	IsLikedByMe bool   // Whether the current user has liked this post
+	PostID         string `json:"postId"`         // The post ID
+	LikeCount      int    `json:"likeCount"`      // Number of likes ("+" reactions)
+	DislikeCount   int    `json:"dislikeCount"`   // Number of dislikes ("-" reactions)
+	ReactionCount  int    `json:"reactionCount"`  // Number of reactions of any kind
+	Reactions      string `json:"-"`              // JSON object of reactions per content, e.g. {"+":3,"🔥":1}
+	EmojiURLs      string `json:"-"`              // JSON object of custom emoji images, e.g. {":soapbox:":"https://..."}
+	ReplyCount     int    `json:"replyCount"`     // Number of authors who replied directly (Kind 1, NIP-10)
+	RepostCount    int    `json:"repostCount"`    // Number of reposts (Kind 6 and 16)
+	QuoteCount     int    `json:"quoteCount"`     // Number of quotes ("q" tag)
+	ZapCount       int    `json:"zapCount"`       // Number of zaps (Kind 9735 receipts signed by the author's LNURL server)
+	ZapSats        int64  `json:"zapSats"`        // Total zapped, in sats (from the receipts' invoices)
+	IsLikedByMe    bool   `json:"isLikedByMe"`    // Whether the current user has liked this post
+	IsRepostedByMe bool   `json:"isRepostedByMe"` // Whether the current user has reposted this post
+}
+
+// postStatsJSON is PostStats with the reaction breakdown as objects, for GetPostStatsBatch
+type postStatsJSON struct {
+	*PostStats
+	Reactions map[string]int    `json:"reactions"`
+	EmojiURLs map[string]string `json:"emojiUrls,omitempty"`
 }
 
-// GetPostStats queries the relay for post statistics (likes, replies)
-// Uses a 3-second timeout for performance
+// statsEventsPerPost bounds the events a stats query asks for per post,
+// so relays answer with EOSE instead of streaming a popular post's whole history
+const statsEventsPerPost = 500
+
+// GetPostStats queries the post statistics (likes, reactions, replies, reposts, quotes, zaps)
+// Answers from the local store and the relays; use GetPostStatsBatch for a feed page
+// Timeout: 5 seconds
 func (d *DenDenClient) GetPostStats(postId string) (*PostStats, error) {
-	if d.client.GetRelay() == nil {
-		return nil, fmt.Errorf("not connected to relay")
+	all, _, err := d.queryPostStats([]string{postId})
+	if err != nil {
+		return nil, err
+	}
+	return all[0], nil
+}
+
+// GetPostStatsBatch queries the statistics of many posts in one round trip
+// Parameters:
+//   - postIdsJson: JSON array of post IDs, e.g. ["id1","id2"]
+//
+// Returns: JSON object of post ID -> stats, each with "reactions" (and the custom
+// emoji images, "emojiUrls") as objects
+// (e.g. {"id1":{"likeCount":3,"reactions":{"+":3,"🔥":1},...}})
+func (d *DenDenClient) GetPostStatsBatch(postIdsJson string) (string, error) {
+	var postIds []string
+	if err := json.Unmarshal([]byte(postIdsJson), &postIds); err != nil {
+		return "", fmt.Errorf("invalid post ids json: %w", err)
+	}
+
+	all, posts, err := d.queryPostStats(postIds)
+	if err != nil {
+		return "", err
+	}
+
+	result := make(map[string]postStatsJSON, len(all))
+	for i, postStats := range all {
+		result[postStats.PostID] = postStatsJSON{
+			PostStats: postStats,
+			Reactions: posts[i].Reactions,
+			EmojiURLs: posts[i].EmojiURLs,
+		}
 	}
 
-	// Create context with 3-second timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	jsonBytes, err := json.Marshal(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize stats: %w", err)
+	}
+	return string(jsonBytes), nil
+}
+
+// queryPostStats counts the engagement on posts with one query per
+// homeAuthorsPerFilter posts (plus one for quotes), run concurrently
+// Returns the stats and the raw counts of each post, in postIds order
+func (d *DenDenClient) queryPostStats(postIds []string) ([]*PostStats, []*stats.Post, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	// Query Kind 7 (reactions) for this post
-	filters := []nostr.Filter{
-		{
-			Kinds: []int{7},
-			Tags:  map[string][]string{"e": {postId}},
-		},
+	var filters []nostr.Filter
+	for start := 0; start < len(postIds); start += homeAuthorsPerFilter {
+		chunk := postIds[start:min(start+homeAuthorsPerFilter, len(postIds))]
+		limit := len(chunk) * statsEventsPerPost
+		filters = append(filters,
+			nostr.Filter{Kinds: stats.Kinds, Tags: nostr.TagMap{"e": chunk}, Limit: limit},
+			nostr.Filter{Kinds: []int{1}, Tags: nostr.TagMap{"q": chunk}, Limit: limit},
+		)
 	}
 
-	eventChan, err := d.client.GetRelay().Subscribe(ctx, filters)
+	events, err := d.queryAll(ctx, filters, 0)
 	if err != nil {
-		return nil, fmt.Errorf("failed to subscribe for stats: %w", err)
-	}
-
-	likeCount := 0
-	isLikedByMe := false
-	myPubkey := d.client.GetIdentity().PublicKey
-
-	// Collect events until timeout or channel closes
-	for {
-		select {
-		case <-ctx.Done():
-			// Timeout reached, return what we have
-			return &PostStats{
-				PostID:      postId,
-				LikeCount:   likeCount,
-				ReplyCount:  0,
-				IsLikedByMe: isLikedByMe,
-			}, nil
-
-		case event, ok := <-eventChan:
-			if !ok {
-				// Channel closed (EOSE received)
-				return &PostStats{
-					PostID:      postId,
-					LikeCount:   likeCount,
-					ReplyCount:  0,
-					IsLikedByMe: isLikedByMe,
-				}, nil
-			}
+		return nil, nil, fmt.Errorf("failed to query stats: %w", err)
+	}
 
-			// Count this like
-			if event.Kind == 7 && event.Content == "+" {
-				likeCount++
-				if event.PubKey == myPubkey {
-					isLikedByMe = true
-				}
-			}
+	// Zaps count only if the zapped author's LNURL server signed the receipt
+	counter := stats.NewCounter(d.client.GetPublicKey(), postIds)
+	counter.SetZapSigners(d.resolveZapSigners(ctx, zap.Recipients(events)))
+	for _, event := range events {
+		counter.Add(event)
+	}
+
+	all := make([]*PostStats, 0, len(postIds))
+	posts := make([]*stats.Post, 0, len(postIds))
+	for _, postId := range postIds {
+		post := counter.Post(postId)
+
+		reactionCount := 0
+		for _, count := range post.Reactions {
+			reactionCount += count
 		}
+		reactionsJson, _ := json.Marshal(post.Reactions)
+		emojiJson, _ := json.Marshal(post.EmojiURLs)
+
+		all = append(all, &PostStats{
+			PostID:         postId,
+			LikeCount:      post.Likes(),
+			DislikeCount:   post.Reactions[stats.Dislike],
+			ReactionCount:  reactionCount,
+			Reactions:      string(reactionsJson),
+			EmojiURLs:      string(emojiJson),
+			ReplyCount:     post.Replies,
+			RepostCount:    post.Reposts,
+			QuoteCount:     post.Quotes,
+			ZapCount:       post.Zaps,
+			ZapSats:        post.ZapSats,
+			IsLikedByMe:    post.LikedByMe || d.IsPostLiked(postId),
+			IsRepostedByMe: post.RepostedByMe,
+		})
+		posts = append(posts, post)
 	}
+	return all, posts, nil
 }
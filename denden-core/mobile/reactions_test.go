@@ -0,0 +1,108 @@
+package mobile
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// reactionEvent returns a Kind 7 or Kind 5 event with a fake ID built from n
+func reactionEvent(n int, author string, kind int, createdAt nostr.Timestamp, content string, tags ...nostr.Tag) *nostr.Event {
+	return &nostr.Event{
+		ID:        fmt.Sprintf("%064x", n),
+		PubKey:    author,
+		CreatedAt: createdAt,
+		Kind:      kind,
+		Tags:      tags,
+		Content:   content,
+	}
+}
+
+func TestBuildReactionCache(t *testing.T) {
+	me := strings.Repeat("1", 64)
+	bob := strings.Repeat("b", 64)
+	postA := strings.Repeat("a", 64)
+	postC := strings.Repeat("c", 64)
+	like := reactionEvent(1, me, 7, 100, "+", nostr.Tag{"e", postA})
+
+	tests := []struct {
+		name   string
+		events []*nostr.Event
+		want   map[string]reactionSet
+	}{
+		{
+			name:   "a like",
+			events: []*nostr.Event{like},
+			want:   map[string]reactionSet{postA: {"+": like.ID}},
+		},
+		{
+			name:   "empty content is a like",
+			events: []*nostr.Event{reactionEvent(2, me, 7, 100, "", nostr.Tag{"e", postA})},
+			want:   map[string]reactionSet{postA: {"+": fmt.Sprintf("%064x", 2)}},
+		},
+		{
+			name: "deleted by the user",
+			events: []*nostr.Event{
+				like,
+				reactionEvent(3, me, 5, 200, "", nostr.Tag{"e", like.ID}),
+			},
+			want: map[string]reactionSet{},
+		},
+		{
+			name: "a deletion of another event",
+			events: []*nostr.Event{
+				like,
+				reactionEvent(3, me, 5, 200, "", nostr.Tag{"e", postA}), // The post, not the reaction
+				reactionEvent(4, me, 5, 200, "", nostr.Tag{"e", fmt.Sprintf("%064x", 99)}),
+			},
+			want: map[string]reactionSet{postA: {"+": like.ID}},
+		},
+		{
+			name: "someone else's deletion",
+			events: []*nostr.Event{
+				like,
+				reactionEvent(3, bob, 5, 200, "", nostr.Tag{"e", like.ID}),
+			},
+			want: map[string]reactionSet{postA: {"+": like.ID}},
+		},
+		{
+			name: "same content twice, the latest wins",
+			events: []*nostr.Event{
+				reactionEvent(5, me, 7, 300, "🔥", nostr.Tag{"e", postA}),
+				reactionEvent(6, me, 7, 100, "🔥", nostr.Tag{"e", postA}),
+				reactionEvent(7, me, 7, 200, "", nostr.Tag{"e", postA}),
+				reactionEvent(8, me, 7, 250, "+", nostr.Tag{"e", postA}),
+			},
+			want: map[string]reactionSet{postA: {"🔥": fmt.Sprintf("%064x", 5), "+": fmt.Sprintf("%064x", 8)}},
+		},
+		{
+			name: "the older of two reactions deleted",
+			events: []*nostr.Event{
+				reactionEvent(5, me, 7, 100, "+", nostr.Tag{"e", postA}),
+				reactionEvent(6, me, 7, 200, "+", nostr.Tag{"e", postA}),
+				reactionEvent(7, me, 5, 300, "", nostr.Tag{"e", fmt.Sprintf("%064x", 5)}),
+			},
+			want: map[string]reactionSet{postA: {"+": fmt.Sprintf("%064x", 6)}},
+		},
+		{
+			name: "target is the last e tag, other authors' reactions ignored",
+			events: []*nostr.Event{
+				reactionEvent(9, me, 7, 100, "+", nostr.Tag{"e", postA}, nostr.Tag{"e", postC}),
+				reactionEvent(10, bob, 7, 100, "+", nostr.Tag{"e", postA}),
+				reactionEvent(11, me, 7, 100, "+"), // No target
+			},
+			want: map[string]reactionSet{postC: {"+": fmt.Sprintf("%064x", 9)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildReactionCache(me, tt.events); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildReactionCache() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
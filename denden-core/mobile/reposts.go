@@ -1,12 +1,8 @@
 package mobile
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
-	"time"
-
-	"denden-core/internal/pow"
 
 	"github.com/nbd-wtf/go-nostr"
 )
@@ -14,7 +10,7 @@ import (
 // Repost publishes a repost (Kind 6) of an existing event
 // originalEventJson: The full JSON string of the event being reposted (NIP-18 requirement)
 func (d *DenDenClient) Repost(originalEventJson string) (string, error) {
-	if d.client.GetRelay() == nil {
+	if d.client.GetPool() == nil {
 		return "", fmt.Errorf("not connected to relay")
 	}
 
@@ -26,32 +22,16 @@ func (d *DenDenClient) Repost(originalEventJson string) (string, error) {
 
 	// Create Kind 6 event
 	event := &nostr.Event{
-		PubKey:    d.client.GetIdentity().PublicKey,
-		CreatedAt: nostr.Timestamp(time.Now().Unix()),
-		Kind:      6, // Kind 6 = Repost
+		Kind: 6, // Kind 6 = Repost
 		Tags: nostr.Tags{
-			{"e", originalEvent.ID, d.client.GetRelay().GetURL()},
+			{"e", originalEvent.ID, d.relayHint()},
 			{"p", originalEvent.PubKey},
 		},
 		Content: originalEventJson, // NIP-18: Content should be the stringified JSON of the reposted event
 	}
 
-	// Mine and Sign
-	difficulty := pow.GetDifficultyRecommendation("public")
-	_, _, _, err := pow.MineEvent(event, difficulty)
-	if err != nil {
-		return "", fmt.Errorf("failed to mine event: %w", err)
-	}
-
-	if err := event.Sign(d.client.GetIdentity().PrivateKey); err != nil {
-		return "", fmt.Errorf("failed to sign event: %w", err)
-	}
-
-	// Publish
-	ctx, cancel := context.WithTimeout(d.client.GetContext(), 10*time.Second)
-	defer cancel()
-
-	if err := d.client.GetRelay().Publish(ctx, event); err != nil {
+	// Mine, sign and publish
+	if err := d.publishEvent(event); err != nil {
 		return "", fmt.Errorf("failed to publish repost: %w", err)
 	}
 
@@ -63,38 +43,22 @@ func (d *DenDenClient) Repost(originalEventJson string) (string, error) {
 // quotedEventId: The ID of the event being quoted
 // authorPubkey: The pubkey of the author of the quoted event
 func (d *DenDenClient) QuotePost(content string, quotedEventId string, authorPubkey string) (string, error) {
-	if d.client.GetRelay() == nil {
+	if d.client.GetPool() == nil {
 		return "", fmt.Errorf("not connected to relay")
 	}
 
 	// Create Kind 1 event
 	event := &nostr.Event{
-		PubKey:    d.client.GetIdentity().PublicKey,
-		CreatedAt: nostr.Timestamp(time.Now().Unix()),
-		Kind:      1, // Kind 1 = Text Note
+		Kind: 1, // Kind 1 = Text Note
 		Tags: nostr.Tags{
-			{"q", quotedEventId, d.client.GetRelay().GetURL()}, // 'q' tag for quote
-			{"p", authorPubkey}, // 'p' tag for notification
+			{"q", quotedEventId, d.relayHint()}, // 'q' tag for quote
+			{"p", authorPubkey},                 // 'p' tag for notification
 		},
 		Content: content,
 	}
 
-	// Mine and Sign
-	difficulty := pow.GetDifficultyRecommendation("public")
-	_, _, _, err := pow.MineEvent(event, difficulty)
-	if err != nil {
-		return "", fmt.Errorf("failed to mine event: %w", err)
-	}
-
-	if err := event.Sign(d.client.GetIdentity().PrivateKey); err != nil {
-		return "", fmt.Errorf("failed to sign event: %w", err)
-	}
-
-	// Publish
-	ctx, cancel := context.WithTimeout(d.client.GetContext(), 10*time.Second)
-	defer cancel()
-
-	if err := d.client.GetRelay().Publish(ctx, event); err != nil {
+	// Mine, sign and publish
+	if err := d.publishEvent(event); err != nil {
 		return "", fmt.Errorf("failed to publish quote: %w", err)
 	}
 
@@ -12,6 +12,17 @@ import (
 // Output: Tree structure where each comment has children
 // GetFollowing returns the list of pubkeys that the given user follows (from Kind 3)
 func (d *DenDenClient) GetFollowing(pubkey string) string {
+	following := d.followingList(pubkey)
+	if len(following) == 0 {
+		return "[]"
+	}
+
+	jsonBytes, _ := json.Marshal(following)
+	return string(jsonBytes)
+}
+
+// followingList resolves the pubkeys a user follows from their latest Kind 3 contact list
+func (d *DenDenClient) followingList(pubkey string) []string {
 	// Query for Kind 3 Contact List
 	filter := nostr.Filter{
 		Kinds:   []int{3},
@@ -24,15 +35,28 @@ func (d *DenDenClient) GetFollowing(pubkey string) string {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if d.client.GetRelay() == nil {
-		return "[]"
+	events, err := d.queryWithStore(ctx, filter)
+	if err != nil {
+		return nil
 	}
+	return contactListPubkeys(events)
+}
 
-	events, err := d.client.GetRelay().QuerySync(ctx, filter)
-	if err != nil || len(events) == 0 {
-		return "[]"
+// localFollowingList is followingList from the local store only
+func (d *DenDenClient) localFollowingList(pubkey string) []string {
+	events, err := d.client.QueryLocal(nostr.Filter{
+		Kinds:   []int{3},
+		Authors: []string{pubkey},
+		Limit:   1,
+	})
+	if err != nil {
+		return nil
 	}
+	return contactListPubkeys(events)
+}
 
+// contactListPubkeys returns the "p" tags of the latest of some contact lists (Kind 3)
+func contactListPubkeys(events []*nostr.Event) []string {
 	// Determine the latest event
 	var latest *nostr.Event
 	for _, evt := range events {
@@ -40,9 +64,8 @@ func (d *DenDenClient) GetFollowing(pubkey string) string {
 			latest = evt
 		}
 	}
-
 	if latest == nil {
-		return "[]"
+		return nil
 	}
 
 	// Extract 'p' tags
@@ -53,8 +76,7 @@ func (d *DenDenClient) GetFollowing(pubkey string) string {
 		}
 	}
 
-	jsonBytes, _ := json.Marshal(following)
-	return string(jsonBytes)
+	return following
 }
 
 // GetFollowers returns the list of pubkeys that follow the given user (reverse lookup)
@@ -70,11 +92,11 @@ func (d *DenDenClient) GetFollowers(pubkey string) string {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if d.client.GetRelay() == nil {
+	if d.client.GetPool() == nil {
 		return "[]"
 	}
 
-	events, err := d.client.GetRelay().QuerySync(ctx, filter)
+	events, err := d.client.QuerySync(ctx, filter)
 	if err != nil {
 		return "[]"
 	}
@@ -95,7 +117,7 @@ func (d *DenDenClient) GetFollowers(pubkey string) string {
 
 // Follow adds a pubkey to the current user's contact list (Kind 3)
 func (d *DenDenClient) Follow(pubkeyToFollow string) (string, error) {
-	if d.client.GetRelay() == nil {
+	if d.client.GetPool() == nil {
 		return "", fmt.Errorf("not connected to relay")
 	}
 
@@ -110,7 +132,7 @@ func (d *DenDenClient) Follow(pubkeyToFollow string) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	events, _ := d.client.GetRelay().QuerySync(ctx, filter)
+	events, _ := d.client.QuerySync(ctx, filter)
 
 	var currentEvent *nostr.Event
 	if len(events) > 0 {
@@ -147,25 +169,25 @@ func (d *DenDenClient) Follow(pubkeyToFollow string) (string, error) {
 	// Add new follow
 	newTags = append(newTags, nostr.Tag{"p", pubkeyToFollow})
 
-	// 3. Create and Sign new Event
+	// 3. Create new Event
 	evt := &nostr.Event{
-		Kind:      3,
-		PubKey:    myPubkey,
-		CreatedAt: nostr.Timestamp(time.Now().Unix()),
-		Tags:      newTags,
-		Content:   "",
+		Kind:    3,
+		Tags:    newTags,
+		Content: "",
 	}
 
 	if currentEvent != nil {
 		evt.Content = currentEvent.Content // Preserve relay map if exists
 	}
 
-	evt.Sign(d.client.GetIdentity().PrivateKey)
+	// 4. Mine, sign and publish
+	if err := d.publishEvent(evt); err != nil {
+		return "", fmt.Errorf("failed to publish contact list: %w", err)
+	}
 
-	// 4. Publish
-	err := d.client.GetRelay().Publish(ctx, evt)
-	if err != nil {
-		return "", err
+	// The home timeline follows the new contact list
+	if err := d.restartHomeTimeline(); err != nil {
+		fmt.Printf("GO: Failed to restart home timeline: %v\n", err)
 	}
 
 	return "ok", nil
@@ -173,7 +195,7 @@ func (d *DenDenClient) Follow(pubkeyToFollow string) (string, error) {
 
 // Unfollow removes a pubkey from the current user's contact list
 func (d *DenDenClient) Unfollow(pubkeyToUnfollow string) (string, error) {
-	if d.client.GetRelay() == nil {
+	if d.client.GetPool() == nil {
 		return "", fmt.Errorf("not connected to relay")
 	}
 
@@ -187,7 +209,7 @@ func (d *DenDenClient) Unfollow(pubkeyToUnfollow string) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	events, _ := d.client.GetRelay().QuerySync(ctx, filter)
+	events, _ := d.client.QuerySync(ctx, filter)
 
 	var currentEvent *nostr.Event
 	if len(events) > 0 {
@@ -218,21 +240,21 @@ func (d *DenDenClient) Unfollow(pubkeyToUnfollow string) (string, error) {
 		return "not_following", nil
 	}
 
-	// Create and Sign
+	// Create new Event
 	evt := &nostr.Event{
-		Kind:      3,
-		PubKey:    myPubkey,
-		CreatedAt: nostr.Timestamp(time.Now().Unix()),
-		Tags:      newTags,
-		Content:   currentEvent.Content,
+		Kind:    3,
+		Tags:    newTags,
+		Content: currentEvent.Content,
 	}
 
-	evt.Sign(d.client.GetIdentity().PrivateKey)
+	// Mine, sign and publish
+	if err := d.publishEvent(evt); err != nil {
+		return "", fmt.Errorf("failed to publish contact list: %w", err)
+	}
 
-	// Publish
-	err := d.client.GetRelay().Publish(ctx, evt)
-	if err != nil {
-		return "", err
+	// The home timeline follows the new contact list
+	if err := d.restartHomeTimeline(); err != nil {
+		fmt.Printf("GO: Failed to restart home timeline: %v\n", err)
 	}
 
 	return "ok", nil
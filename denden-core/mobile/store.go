@@ -0,0 +1,131 @@
+// Package mobile provides GoMobile-compatible wrappers for the DenDen client.
+// This file contains local event store helpers (offline-first queries and cache warm-up).
+package mobile
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"denden-core/internal/identity"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// migrateLegacyIdentity moves an identity that older versions wrote to denden.db
+// (the path was passed to client.NewClient as the identity file) to identity.json
+// so denden.db can be used for the SQLite event store
+func migrateLegacyIdentity(dbPath, identityPath string) error {
+	if _, err := os.Stat(identityPath); err == nil {
+		return nil // Already migrated
+	}
+
+	legacy, err := identity.LoadIdentity(dbPath)
+	if err != nil || legacy.PrivateKey == "" {
+		return nil // Missing, or already a SQLite database
+	}
+
+	if err := os.Rename(dbPath, identityPath); err != nil {
+		return fmt.Errorf("failed to migrate identity file: %w", err)
+	}
+
+	fmt.Printf("GO: Migrated identity from %s to %s\n", dbPath, identityPath)
+	return nil
+}
+
+// loadFromStore warms the in-memory caches from the local event store
+// so the app has profiles, chats and like state to show before any relay answers
+func (d *DenDenClient) loadFromStore() {
+	if d.client.GetStore() == nil {
+		return
+	}
+
+	// Profiles (Kind 0)
+	profiles, err := d.client.QueryLocal(nostr.Filter{Kinds: []int{0}})
+	if err == nil {
+		for _, evt := range profiles {
+			d.cacheProfile(evt.PubKey, evt.Content)
+		}
+	}
+
+	// Direct messages: Kind 4 (received and sent) and NIP-17 gift wraps
+	// (received, plus self-addressed copies of sent ones)
+	pk := d.client.GetPublicKey()
+	received, _ := d.client.QueryLocal(nostr.Filter{
+		Kinds: []int{nostr.KindEncryptedDirectMessage, 1059},
+		Tags:  nostr.TagMap{"p": []string{pk}},
+	})
+	sent, _ := d.client.QueryLocal(nostr.Filter{
+		Kinds:   []int{nostr.KindEncryptedDirectMessage},
+		Authors: []string{pk},
+	})
+
+	d.chatMutex.Lock()
+	for _, evt := range append(received, sent...) {
+		d.cacheChatEvent(evt)
+	}
+	d.sortChatCache()
+	d.chatMutex.Unlock()
+
+	// The user's reactions (Kind 7) minus the ones they deleted (Kind 5)
+	d.loadReactions()
+}
+
+// queryWithStore answers a filter from the local event store first,
+// then tops up from the relays (which also saves the new events locally)
+// If the relays are unreachable, the local results are returned on their own
+// It waits for the relays; the GetCached* variants of feeds and notifications
+// read the store only, for showing something before the relays answer
+func (d *DenDenClient) queryWithStore(ctx context.Context, filter nostr.Filter) ([]*nostr.Event, error) {
+	local, err := d.client.QueryLocal(filter)
+	if err != nil {
+		fmt.Printf("GO: Local query failed: %v\n", err)
+	}
+
+	if d.client.GetPool() == nil {
+		if d.client.GetStore() == nil {
+			return nil, fmt.Errorf("not connected to relay")
+		}
+		return local, nil
+	}
+
+	remote, err := d.client.QuerySync(ctx, filter)
+	if err != nil {
+		if len(local) > 0 {
+			return local, nil
+		}
+		return nil, err
+	}
+
+	return mergeEvents(filter.Limit, local, remote), nil
+}
+
+// mergeEvents merges event lists, de-duplicated by ID and sorted newest first
+// If limit > 0, at most limit events are returned
+func mergeEvents(limit int, lists ...[]*nostr.Event) []*nostr.Event {
+	seen := make(map[string]bool)
+	var merged []*nostr.Event
+
+	for _, list := range lists {
+		for _, evt := range list {
+			if seen[evt.ID] {
+				continue
+			}
+			seen[evt.ID] = true
+			merged = append(merged, evt)
+		}
+	}
+
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].CreatedAt != merged[j].CreatedAt {
+			return merged[i].CreatedAt > merged[j].CreatedAt
+		}
+		return merged[i].ID < merged[j].ID
+	})
+
+	if limit > 0 && len(merged) > limit {
+		merged = merged[:limit]
+	}
+	return merged
+}
@@ -6,8 +6,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"time"
 
+	"denden-core/internal/store"
+	"denden-core/internal/thread"
+
 	"github.com/nbd-wtf/go-nostr"
 )
 
@@ -23,21 +27,91 @@ type ThreadEvent struct {
 	Tags      [][]string `json:"tags,omitempty"`
 }
 
+// ThreadNode is a post in a thread tree, with its replies below it
+// Placeholders stand in for posts that couldn't be found (Missing, no content)
+type ThreadNode struct {
+	ThreadEvent
+	Missing     bool          `json:"missing,omitempty"` // Placeholder for a post that couldn't be found
+	Depth       int           `json:"depth"`             // 0 for the root
+	Descendants int           `json:"descendants"`       // Replies below, at any depth
+	Score       int           `json:"score,omitempty"`   // Reaction score (sortBy "score" only)
+	Children    []*ThreadNode `json:"children"`
+}
+
 // ThreadResult represents the result of a thread query
 type ThreadResult struct {
 	RootID string // Root event ID
-	Count  int    // Total number of events
-	JSON   string // JSON array of ThreadEvent
+	Count  int    // Number of replies in the tree (placeholders excluded)
+	JSON   string // JSON ThreadNode: the root, with the replies nested below
 }
 
-// GetPostThread retrieves all comments under a root post
-// Uses NIP-10: all replies include root ID in 'e' tag, so one query gets entire tree
-// Timeout: 5 seconds
-func (d *DenDenClient) GetPostThread(rootEventId string) (*ThreadResult, error) {
-	if d.client.GetRelay() == nil {
+// maxParentRounds bounds how many times GetPostThread fetches missing parents
+// (each round can only reveal parents one level further up)
+const maxParentRounds = 3
+
+// GetPostThread retrieves a post and all comments under it as a tree
+// Uses NIP-10: all replies include root ID in 'e' tag, so one query gets entire tree.
+// The root and any intermediate parents missing from the results are fetched too;
+// replies whose parent can't be found (or was deleted) hang below a placeholder (missing: true)
+// Timeout: 5 seconds for the replies, then a few quick lookups
+// Parameters:
+//   - rootEventId: the thread's root post
+//   - sortBy: sibling order, "time" (oldest first, default) or "score" (most reactions first)
+func (d *DenDenClient) GetPostThread(rootEventId string, sortBy string) (*ThreadResult, error) {
+	if d.client.GetPool() == nil && d.client.GetStore() == nil {
 		return nil, fmt.Errorf("not connected to relay")
 	}
 
+	events, err := d.collectThreadReplies(rootEventId)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// The root itself
+	if root, err := d.lookupEvent(rootEventId); err == nil {
+		events = append(events, root)
+	}
+
+	// Parents of replies-to-replies that the #e query didn't return
+	for round := 0; round < maxParentRounds; round++ {
+		missing := thread.MissingParents(rootEventId, events)
+		if len(missing) == 0 {
+			break
+		}
+		parents, err := d.queryWithStore(ctx, nostr.Filter{IDs: missing})
+		if err != nil || len(parents) == 0 {
+			break
+		}
+		events = append(events, parents...)
+	}
+
+	// Deleted replies drop out; their replies hang below a placeholder
+	events = d.hideDeleted(ctx, events, nil)
+
+	tree := thread.Build(rootEventId, events)
+	if sortBy == "score" {
+		tree.Sort(thread.SortByScore, d.reactionScores(ctx, tree.IDs()))
+	}
+
+	node := d.toThreadNode(tree)
+	jsonBytes, err := json.Marshal(node)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize thread: %w", err)
+	}
+
+	return &ThreadResult{
+		RootID: rootEventId,
+		Count:  countReplies(node),
+		JSON:   string(jsonBytes),
+	}, nil
+}
+
+// collectThreadReplies gathers the Kind 1 events that reference a root,
+// from the local store and then the relays (until the 5 second timeout)
+func (d *DenDenClient) collectThreadReplies(rootEventId string) ([]*nostr.Event, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -50,78 +124,259 @@ func (d *DenDenClient) GetPostThread(rootEventId string) (*ThreadResult, error)
 		},
 	}
 
-	eventChan, err := d.client.GetRelay().Subscribe(ctx, filters)
+	var events []*nostr.Event
+	seen := make(map[string]bool)
+
+	// Start with what the local store already has
+	local, _ := d.client.QueryLocal(filters[0])
+	for _, event := range local {
+		seen[event.ID] = true
+		events = append(events, event)
+	}
+
+	// Offline: answer from the store only
+	if d.client.GetPool() == nil {
+		return events, nil
+	}
+
+	eventChan, err := d.client.Subscribe(ctx, filters)
 	if err != nil {
+		if len(events) > 0 {
+			return events, nil
+		}
 		return nil, fmt.Errorf("failed to subscribe for thread: %w", err)
 	}
 
-	var events []ThreadEvent
-
 	for {
 		select {
 		case <-ctx.Done():
-			return d.buildThreadResult(rootEventId, events)
+			return events, nil
 
 		case event, ok := <-eventChan:
 			if !ok {
-				return d.buildThreadResult(rootEventId, events)
+				return events, nil
 			}
 
-			if event.Kind == 1 {
-				te := d.parseThreadEvent(event)
-				events = append(events, te)
+			switch {
+			case event.Kind == 1 && !seen[event.ID]:
+				seen[event.ID] = true
+				events = append(events, event)
+			case event.Kind == nostr.KindDeletion:
+				events = slices.DeleteFunc(events, func(evt *nostr.Event) bool {
+					return store.Deletes(event, evt)
+				})
 			}
 		}
 	}
 }
 
-// GetNotifications retrieves mentions/replies to the current user
-// Filter: Kind 1 with #p tag = my pubkey
-// Timeout: 5 seconds
-func (d *DenDenClient) GetNotifications(limit int) (string, error) {
-	if d.client.GetRelay() == nil {
-		return "", fmt.Errorf("not connected to relay")
+// reactionScores counts the reactions (Kind 7) to each event: +1 per like, -1 per "-" (NIP-25)
+// Events are queried in chunks of homeAuthorsPerFilter IDs
+func (d *DenDenClient) reactionScores(ctx context.Context, eventIDs []string) map[string]int {
+	var filters []nostr.Filter
+	for start := 0; start < len(eventIDs); start += homeAuthorsPerFilter {
+		end := min(start+homeAuthorsPerFilter, len(eventIDs))
+		filters = append(filters, nostr.Filter{
+			Kinds: []int{7},
+			Tags:  nostr.TagMap{"e": eventIDs[start:end]},
+		})
 	}
 
-	if limit <= 0 {
-		limit = 20
+	reactions, err := d.queryAll(ctx, filters, 0)
+	if err != nil {
+		return nil
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	scores := make(map[string]int)
+	seen := make(map[string]bool) // One reaction per author and event
+	for _, reaction := range reactions {
+		// NIP-25: the reacted-to event is the last "e" tag
+		var target string
+		for _, tag := range reaction.Tags {
+			if len(tag) >= 2 && tag[0] == "e" {
+				target = tag[1]
+			}
+		}
+		key := reaction.PubKey + ":" + target
+		if target == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if reaction.Content == "-" {
+			scores[target]--
+		} else {
+			scores[target]++
+		}
+	}
+	return scores
+}
 
-	myPubkey := d.client.GetIdentity().PublicKey
+// toThreadNode converts a thread tree to its JSON form
+func (d *DenDenClient) toThreadNode(node *thread.Node) *ThreadNode {
+	out := &ThreadNode{
+		Missing:     node.IsPlaceholder(),
+		Depth:       node.Depth,
+		Descendants: node.Descendants,
+		Score:       node.Score,
+		Children:    make([]*ThreadNode, 0, len(node.Children)),
+	}
 
-	filters := []nostr.Filter{
-		{
-			Kinds: []int{1},
-			Tags:  map[string][]string{"p": {myPubkey}},
-			Limit: limit,
-		},
+	if node.Event != nil {
+		out.ThreadEvent = d.parseThreadEvent(node.Event)
+	} else {
+		out.EventID = node.ID
+	}
+
+	for _, child := range node.Children {
+		out.Children = append(out.Children, d.toThreadNode(child))
 	}
+	return out
+}
 
-	eventChan, err := d.client.GetRelay().Subscribe(ctx, filters)
+// countReplies counts the posts below the root, placeholders excluded
+func countReplies(root *ThreadNode) int {
+	count := 0
+	for _, child := range root.Children {
+		if !child.Missing {
+			count++
+		}
+		count += countReplies(child)
+	}
+	return count
+}
+
+// AncestorEvent is a post in a reply's parent chain, with its author's profile
+// A parent that couldn't be found is kept as a placeholder (Missing, no content)
+type AncestorEvent struct {
+	ThreadEvent
+	AuthorName string `json:"authorName,omitempty"`
+	AvatarURL  string `json:"avatarUrl,omitempty"`
+	Missing    bool   `json:"missing,omitempty"`
+}
+
+// defaultAncestorDepth is used when the app passes no maxDepth
+const defaultAncestorDepth = 20
+
+// ancestorThreadLimit caps the thread replies fetched alongside the first parent
+const ancestorThreadLimit = 500
+
+// GetAncestors returns the posts a reply answers, root first ("show parent context")
+// The chain follows ReplyToID up to the root; the event itself isn't included.
+// Round trips are batched: the first one fetches the parent, the root and the
+// thread's replies (NIP-10 replies all tag the root, so intermediate parents
+// usually come with it); later ones only ask for parents still missing.
+// If a parent can't be found the chain starts with a placeholder for it
+// (after the root, when the root itself was found)
+// Timeout: 10 seconds
+// Parameters:
+//   - eventId: the reply to show the context of
+//   - maxDepth: maximum number of ancestors (0 = 20), the closest ones are kept
+//
+// Returns: JSON array of AncestorEvent ("[]" for a post that isn't a reply)
+func (d *DenDenClient) GetAncestors(eventId string, maxDepth int) (string, error) {
+	if maxDepth <= 0 {
+		maxDepth = defaultAncestorDepth
+	}
+
+	event, err := d.lookupEvent(eventId)
 	if err != nil {
-		return "", fmt.Errorf("failed to subscribe for notifications: %w", err)
+		return "", fmt.Errorf("failed to get event: %w", err)
 	}
 
-	var events []ThreadEvent
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	for {
-		select {
-		case <-ctx.Done():
-			return d.serializeEvents(events)
+	known := map[string]*nostr.Event{event.ID: event}
+	chain := []*nostr.Event{} // Closest parent first
+	seen := map[string]bool{event.ID: true}
+	missing, rootID := "", ""
+	fetchedThread := false
+	lookups := newDeletionLookups()
 
-		case event, ok := <-eventChan:
-			if !ok {
-				return d.serializeEvents(events)
+	current := event
+	for len(chain) < maxDepth {
+		refs := thread.Parse(current)
+		parentID := refs.Reply
+		if parentID == "" || seen[parentID] {
+			break
+		}
+
+		parent, ok := known[parentID]
+		if !ok {
+			filters := []nostr.Filter{{IDs: []string{parentID}}}
+			if refs.Root != "" && refs.Root != parentID && known[refs.Root] == nil {
+				filters[0].IDs = append(filters[0].IDs, refs.Root)
+			}
+			if refs.Root != "" && !fetchedThread {
+				fetchedThread = true
+				filters = append(filters, nostr.Filter{
+					Kinds: []int{1},
+					Tags:  nostr.TagMap{"e": {refs.Root}},
+					Limit: ancestorThreadLimit,
+				})
+			}
+
+			fetched, _ := d.queryAll(ctx, filters, 0)
+			for _, evt := range d.hideDeleted(ctx, fetched, lookups) {
+				known[evt.ID] = evt
 			}
 
-			if event.Kind == 1 {
-				te := d.parseThreadEvent(event)
-				events = append(events, te)
+			if parent, ok = known[parentID]; !ok {
+				missing, rootID = parentID, refs.Root
+				break
 			}
 		}
+
+		seen[parentID] = true
+		chain = append(chain, parent)
+		current = parent
+	}
+
+	// A broken chain still shows the thread's root, above the gap
+	var root *nostr.Event
+	if missing != "" && missing != rootID && !seen[rootID] {
+		root = known[rootID]
+	}
+
+	// Authors' profiles, in one query for the ones not cached yet
+	pubkeys := make([]string, 0, len(chain)+1)
+	for _, evt := range chain {
+		pubkeys = append(pubkeys, evt.PubKey)
+	}
+	if root != nil {
+		pubkeys = append(pubkeys, root.PubKey)
+	}
+	d.fetchProfiles(ctx, pubkeys)
+
+	ancestors := make([]AncestorEvent, 0, len(chain)+2)
+	if root != nil {
+		ancestors = append(ancestors, d.toAncestorEvent(root))
+	}
+	if missing != "" {
+		ancestors = append(ancestors, AncestorEvent{
+			ThreadEvent: ThreadEvent{EventID: missing},
+			Missing:     true,
+		})
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		ancestors = append(ancestors, d.toAncestorEvent(chain[i]))
+	}
+
+	jsonBytes, err := json.Marshal(ancestors)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize ancestors: %w", err)
+	}
+	return string(jsonBytes), nil
+}
+
+// toAncestorEvent converts an event to an AncestorEvent with its author's cached profile
+func (d *DenDenClient) toAncestorEvent(event *nostr.Event) AncestorEvent {
+	profile := d.getProfileFromCache(event.PubKey)
+	return AncestorEvent{
+		ThreadEvent: d.parseThreadEvent(event),
+		AuthorName:  profile.Name,
+		AvatarURL:   profile.Picture,
 	}
 }
 
@@ -135,59 +390,17 @@ func (d *DenDenClient) parseThreadEvent(event *nostr.Event) ThreadEvent {
 		Time:    event.CreatedAt.Time().Format(time.RFC3339),
 	}
 
-	// Parse tags
-	var eTags []string
+	// NIP-10 root and parent (marked tags, or the deprecated positional style)
+	refs := thread.Parse(event)
+	te.RootID = refs.Root
+	te.ReplyToID = refs.Reply
+
+	// Store all tags for reference
 	for _, tag := range event.Tags {
-		if len(tag) >= 2 && tag[0] == "e" {
-			eTags = append(eTags, tag[1])
-
-			// NIP-10: Check for explicit markers
-			if len(tag) >= 4 {
-				marker := tag[3]
-				if marker == "root" {
-					te.RootID = tag[1]
-				} else if marker == "reply" {
-					te.ReplyToID = tag[1]
-				}
-			}
-		}
-		// Store all tags for reference
 		if len(tag) >= 2 {
 			te.Tags = append(te.Tags, tag)
 		}
 	}
 
-	// Fallback: If no explicit markers, use positional (NIP-10 deprecated style)
-	// First e tag = root, last e tag = reply (if different)
-	if te.RootID == "" && len(eTags) > 0 {
-		te.RootID = eTags[0]
-	}
-	if te.ReplyToID == "" && len(eTags) > 1 {
-		te.ReplyToID = eTags[len(eTags)-1]
-	}
-
 	return te
 }
-
-// buildThreadResult creates a ThreadResult from collected events
-func (d *DenDenClient) buildThreadResult(rootEventId string, events []ThreadEvent) (*ThreadResult, error) {
-	jsonBytes, err := json.Marshal(events)
-	if err != nil {
-		return nil, fmt.Errorf("failed to serialize thread events: %w", err)
-	}
-
-	return &ThreadResult{
-		RootID: rootEventId,
-		Count:  len(events),
-		JSON:   string(jsonBytes),
-	}, nil
-}
-
-// serializeEvents converts events to JSON string
-func (d *DenDenClient) serializeEvents(events []ThreadEvent) (string, error) {
-	jsonBytes, err := json.Marshal(events)
-	if err != nil {
-		return "", fmt.Errorf("failed to serialize events: %w", err)
-	}
-	return string(jsonBytes), nil
-}
@@ -0,0 +1,93 @@
+// Package mobile provides GoMobile-compatible wrappers for the DenDen client.
+// This file contains the home timeline: posts from the accounts the user follows.
+package mobile
+
+import (
+	"fmt"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// homeAuthorsPerFilter is how many authors go in one filter
+// Relays reject filters that are too large, so big follow lists are split up
+const homeAuthorsPerFilter = 250
+
+// homeKinds are the kinds shown on the home timeline (text notes and reposts)
+var homeKinds = []int{1, 6}
+
+// GetHomeTimeline returns a page of posts and reposts from the accounts the user
+// follows (Kind 3) and the user's own, newest first: {"events":[...],"nextCursor":"..."}
+// cursor: nextCursor of the previous page ("" for the first page)
+func (d *DenDenClient) GetHomeTimeline(limit int, cursor string) (string, error) {
+	events, next, err := d.fetchPage(d.homeFilters(), cursor, limit, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to query home timeline: %w", err)
+	}
+	return d.pageToJson(events, next)
+}
+
+// GetCachedHomeTimeline is GetHomeTimeline from the local event store only
+// It returns at once, without waiting for relays, so the app can show the
+// cached timeline first and then load it from the network with GetHomeTimeline
+// The cursors of both work with either
+func (d *DenDenClient) GetCachedHomeTimeline(limit int, cursor string) (string, error) {
+	events, next, err := d.fetchLocalPage(d.localHomeFilters(), cursor, limit, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to query cached home timeline: %w", err)
+	}
+	return d.pageToJson(events, next)
+}
+
+// StartHomeTimeline streams new posts and reposts from followed accounts
+// Each post is sent to the callback as the same JSON object GetHomeTimeline
+// returns, with "feed":"home". The follow list is resolved when it starts;
+// Follow, Unfollow and SwitchAccount restart it with the new list.
+// The global stream of StartListening stays available as the Ocean feed
+func (d *DenDenClient) StartHomeTimeline(callback StringCallback) error {
+	return d.startLiveFeed(&d.homeFeed, "home", d.homeFilters(), callback, nil, d.StartHomeTimeline)
+}
+
+// StopHomeTimeline stops the stream started by StartHomeTimeline
+func (d *DenDenClient) StopHomeTimeline() {
+	d.homeFeed.stop()
+}
+
+// restartHomeTimeline resubscribes the home stream (if running), e.g. after the follow list changed
+func (d *DenDenClient) restartHomeTimeline() error {
+	return d.homeFeed.restart()
+}
+
+// homeFilters builds the home timeline filters: the user and everyone they
+// follow, homeAuthorsPerFilter authors per filter
+func (d *DenDenClient) homeFilters() []nostr.Filter {
+	myPubkey := d.client.GetPublicKey()
+	return homeFiltersFor(myPubkey, d.followingList(myPubkey))
+}
+
+// localHomeFilters is homeFilters with the follow list from the local store
+func (d *DenDenClient) localHomeFilters() []nostr.Filter {
+	myPubkey := d.client.GetPublicKey()
+	return homeFiltersFor(myPubkey, d.localFollowingList(myPubkey))
+}
+
+// homeFiltersFor builds the home timeline filters for a user and their follows
+func homeFiltersFor(myPubkey string, following []string) []nostr.Filter {
+	authors := []string{myPubkey}
+	seen := map[string]bool{myPubkey: true}
+	for _, pubkey := range following {
+		if !seen[pubkey] {
+			seen[pubkey] = true
+			authors = append(authors, pubkey)
+		}
+	}
+
+	var filters []nostr.Filter
+	for start := 0; start < len(authors); start += homeAuthorsPerFilter {
+		end := min(start+homeAuthorsPerFilter, len(authors))
+		filters = append(filters, nostr.Filter{
+			Kinds:   homeKinds,
+			Authors: authors[start:end],
+		})
+	}
+	return filters
+}
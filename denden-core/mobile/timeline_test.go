@@ -0,0 +1,56 @@
+package mobile
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestHomeFiltersFor(t *testing.T) {
+	me := fmt.Sprintf("%064x", 0)
+	pubkey := func(n int) string { return fmt.Sprintf("%064x", n) }
+
+	// 300 follows, one of them twice, and the user's own key
+	following := []string{pubkey(1), me}
+	for n := 2; n <= 300; n++ {
+		following = append(following, pubkey(n))
+	}
+	following = append(following, pubkey(42))
+
+	filters := homeFiltersFor(me, following)
+	if len(filters) != 2 {
+		t.Fatalf("len(filters) = %d, want 2", len(filters))
+	}
+
+	var authors []string
+	for i, filter := range filters {
+		if len(filter.Authors) > homeAuthorsPerFilter {
+			t.Errorf("filter %d has %d authors, want at most %d", i, len(filter.Authors), homeAuthorsPerFilter)
+		}
+		if !reflect.DeepEqual(filter.Kinds, homeKinds) {
+			t.Errorf("filter %d kinds = %v, want %v", i, filter.Kinds, homeKinds)
+		}
+		authors = append(authors, filter.Authors...)
+	}
+	if got := len(filters[0].Authors); got != homeAuthorsPerFilter {
+		t.Errorf("first filter has %d authors, want %d", got, homeAuthorsPerFilter)
+	}
+
+	// The user first, then each follow once, in order
+	want := []string{me}
+	for n := 1; n <= 300; n++ {
+		want = append(want, pubkey(n))
+	}
+	if !reflect.DeepEqual(authors, want) {
+		t.Errorf("authors = %d keys, want the user and 300 follows once each (%d keys)", len(authors), len(want))
+	}
+}
+
+func TestHomeFiltersForNoFollows(t *testing.T) {
+	me := fmt.Sprintf("%064x", 0)
+
+	filters := homeFiltersFor(me, nil)
+	if len(filters) != 1 || !reflect.DeepEqual(filters[0].Authors, []string{me}) {
+		t.Errorf("homeFiltersFor() = %v, want the user's own posts only", filters)
+	}
+}
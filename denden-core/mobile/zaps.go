@@ -0,0 +1,92 @@
+// Package mobile provides GoMobile-compatible wrappers for the DenDen client.
+// This file contains zap receipt verification: the keys allowed to sign each recipient's receipts.
+package mobile
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"denden-core/internal/zap"
+)
+
+// zapSignerTTL is how long a recipient's LNURL server key is used before it's fetched again
+const zapSignerTTL = time.Hour
+
+// maxZapSignerFetches bounds the LNURL servers asked at the same time
+const maxZapSignerFetches = 8
+
+// zapSigner is the key a recipient's LNURL server signs zap receipts with
+type zapSigner struct {
+	pubkey    string    // "" if the recipient can't receive zaps
+	fetchedAt time.Time // When it was looked up
+}
+
+// resolveZapSigners looks up the LNURL server key of each zap recipient: the
+// lightning address in their profile (lud16, or lud06), then the server's
+// nostrPubkey. Keys are cached for zapSignerTTL, and so are recipients without
+// zap support; network errors aren't, so the next load asks again
+// Recipients whose key can't be found are left out: their receipts are dropped
+func (d *DenDenClient) resolveZapSigners(ctx context.Context, recipients []string) zap.Signers {
+	var missing []string
+	d.zapMutex.Lock()
+	for _, recipient := range recipients {
+		if cached, ok := d.zapSigners[recipient]; !ok || time.Since(cached.fetchedAt) > zapSignerTTL {
+			missing = append(missing, recipient)
+		}
+	}
+	d.zapMutex.Unlock()
+
+	if len(missing) > 0 {
+		d.fetchProfiles(ctx, missing)
+
+		var wg sync.WaitGroup
+		slots := make(chan struct{}, maxZapSignerFetches)
+		for _, recipient := range missing {
+			wg.Add(1)
+			go func(recipient string) {
+				defer wg.Done()
+				slots <- struct{}{}
+				defer func() { <-slots }()
+
+				pubkey, err := d.fetchZapSigner(ctx, recipient)
+				if err != nil && !errors.Is(err, zap.ErrNoLightningAddress) && !errors.Is(err, zap.ErrZapsNotSupported) {
+					return
+				}
+
+				d.zapMutex.Lock()
+				d.zapSigners[recipient] = zapSigner{pubkey: pubkey, fetchedAt: time.Now()}
+				d.zapMutex.Unlock()
+			}(recipient)
+		}
+		wg.Wait()
+	}
+
+	return d.cachedZapSigners(recipients)
+}
+
+// fetchZapSigner asks a recipient's LNURL server for its key (the profile must be cached)
+func (d *DenDenClient) fetchZapSigner(ctx context.Context, recipient string) (string, error) {
+	profile := d.getProfileFromCache(recipient)
+	payURL, err := zap.PayURL(profile.Lud16, profile.Lud06)
+	if err != nil {
+		return "", err
+	}
+	return zap.FetchSigner(ctx, payURL)
+}
+
+// cachedZapSigners returns the LNURL server keys already known, without any
+// network request (expired ones too: they are better than dropping every zap offline)
+func (d *DenDenClient) cachedZapSigners(recipients []string) zap.Signers {
+	d.zapMutex.Lock()
+	defer d.zapMutex.Unlock()
+
+	signers := make(zap.Signers)
+	for _, recipient := range recipients {
+		if cached, ok := d.zapSigners[recipient]; ok && cached.pubkey != "" {
+			signers[recipient] = cached.pubkey
+		}
+	}
+	return signers
+}